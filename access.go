@@ -0,0 +1,42 @@
+package couchdb
+
+import (
+	"encoding/json"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// accessDoc encodes doc with its _access field set to access, as requested
+// by OptionDocAccess.
+type accessDoc struct {
+	doc    interface{}
+	access []string
+}
+
+var _ json.Marshaler = &accessDoc{}
+
+func (d *accessDoc) MarshalJSON() ([]byte, error) {
+	raw, err := json.Marshal(d.doc)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return nil, errors.Status(kivik.StatusBadRequest, "kivik: document must be a JSON object")
+	}
+	if fields["_access"], err = json.Marshal(d.access); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// withAccess returns doc with the users and roles set by OptionDocAccess in
+// opts, if any, which is removed from opts.
+func withAccess(doc interface{}, opts map[string]interface{}) (interface{}, error) {
+	access, err := docAccess(opts)
+	if err != nil || access == nil {
+		return doc, err
+	}
+	return &accessDoc{doc: doc, access: access}, nil
+}
@@ -0,0 +1,101 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestDocAccess(t *testing.T) {
+	type write func(*db, interface{}, map[string]interface{}) error
+	put := func(d *db, doc interface{}, opts map[string]interface{}) error {
+		_, err := d.Put(context.Background(), "foo", doc, opts)
+		return err
+	}
+	create := func(d *db, doc interface{}, opts map[string]interface{}) error {
+		_, _, err := d.CreateDoc(context.Background(), doc, opts)
+		return err
+	}
+	tests := []struct {
+		name     string
+		write    write
+		doc      interface{}
+		options  map[string]interface{}
+		expected string
+		rawQuery string
+		status   int
+		err      string
+	}{
+		{
+			name:     "put without access",
+			write:    put,
+			doc:      map[string]string{"foo": "bar"},
+			expected: `{"foo":"bar"}`,
+		},
+		{
+			name:     "put",
+			write:    put,
+			doc:      struct{ Foo string }{Foo: "bar"},
+			options:  map[string]interface{}{OptionDocAccess: []string{"bob", "staff"}},
+			expected: `{"Foo":"bar","_access":["bob","staff"]}`,
+		},
+		{
+			name:     "create doc",
+			write:    create,
+			doc:      map[string]string{"foo": "bar"},
+			options:  map[string]interface{}{OptionDocAccess: []string{"bob"}, "batch": "ok"},
+			expected: `{"foo":"bar","_access":["bob"]}`,
+			rawQuery: "batch=ok",
+		},
+		{
+			name:    "invalid option",
+			write:   put,
+			doc:     map[string]string{"foo": "bar"},
+			options: map[string]interface{}{OptionDocAccess: "bob"},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: option '_access' must be []string, not string",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body []byte
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				var err error
+				if body, err = ioutil.ReadAll(req.Body); err != nil {
+					return nil, err
+				}
+				if req.URL.RawQuery != test.rawQuery {
+					t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+				}
+				return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{"ok":true,"id":"foo","rev":"1-xxx"}`)}, nil
+			})
+			err := test.write(db, test.doc, test.options)
+			if test.expected != "" {
+				if d := diff.JSON([]byte(test.expected), body); d != nil {
+					t.Error(d)
+				}
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestCreateDBAccess(t *testing.T) {
+	var rawQuery string
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		rawQuery = req.URL.RawQuery
+		return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{"ok":true}`)}, nil
+	})
+	if err := c.CreateDB(context.Background(), "foo", map[string]interface{}{OptionAccess: true}); err != nil {
+		t.Fatal(err)
+	}
+	if rawQuery != "access=true" {
+		t.Errorf("Unexpected query: %s", rawQuery)
+	}
+}
@@ -0,0 +1,68 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// AdminParty returns true if the server is an unsecured "admin party", where
+// anonymous requests are granted admin privileges, as is the case for a new
+// CouchDB 1.x server, or a 2.x server started without an admin user. The
+// client should not be authenticated, or it will be reported as such.
+func (c *client) AdminParty(ctx context.Context) (bool, error) {
+	var s session
+	if _, err := c.DoJSON(ctx, kivik.MethodGet, "/_session", nil, &s); err != nil {
+		return false, err
+	}
+	if s.UserCtx.Name != "" {
+		return false, nil
+	}
+	for _, role := range s.UserCtx.Roles {
+		if role == "_admin" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateFirstAdmin ends an admin party by creating the server admin name with
+// password, through _config/admins on CouchDB 1.x, or
+// _node/{node}/_config/admins otherwise, where node defaults to LocalNode. In
+// a 2.x cluster, it must be repeated for each node. As a safeguard for
+// bootstrapping scripts, confirm must be true, and the server must be in an
+// admin party, as reported by AdminParty; otherwise an error is returned and
+// nothing is changed. Afterwards, the client must authenticate as the new
+// admin to retain admin privileges.
+func (c *client) CreateFirstAdmin(ctx context.Context, node, name, password string, confirm bool) error {
+	if name == "" {
+		return missingArg("name")
+	}
+	if password == "" {
+		return missingArg("password")
+	}
+	if !confirm {
+		return errors.Status(kivik.StatusBadRequest, "kivik: confirmation required to create the first admin")
+	}
+	party, err := c.AdminParty(ctx)
+	if err != nil {
+		return err
+	}
+	if !party {
+		return errors.Status(kivik.StatusPreconditionFailed, "kivik: server is not in admin party mode")
+	}
+	path := "/_config/admins/" + url.PathEscape(name)
+	if c.Compat != CompatCouch16 {
+		path = nodePath(node, "_config/admins/"+url.PathEscape(name))
+	}
+	body, err := json.Marshal(password)
+	if err != nil {
+		return errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	_, err = c.DoError(ctx, kivik.MethodPut, path, &chttp.Options{Body: chttp.EncodeBody(body)})
+	return err
+}
@@ -0,0 +1,112 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestAdminParty(t *testing.T) {
+	tests := []struct {
+		name     string
+		session  string
+		expected bool
+	}{
+		{
+			name:     "admin party",
+			session:  `{"ok":true,"userCtx":{"name":null,"roles":["_admin"]}}`,
+			expected: true,
+		},
+		{
+			name:    "secured",
+			session: `{"ok":true,"userCtx":{"name":null,"roles":[]}}`,
+		},
+		{
+			name:    "authenticated admin",
+			session: `{"ok":true,"userCtx":{"name":"bob","roles":["_admin"]}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.session)}, nil
+			})
+			party, err := c.AdminParty(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if party != test.expected {
+				t.Errorf("Expected %t, got %t", test.expected, party)
+			}
+		})
+	}
+}
+
+func TestCreateFirstAdmin(t *testing.T) {
+	tests := []struct {
+		name     string
+		compat   CompatMode
+		confirm  bool
+		session  string
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:   "not confirmed",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: confirmation required to create the first admin",
+		},
+		{
+			name:     "secured",
+			confirm:  true,
+			session:  `{"ok":true,"userCtx":{"name":null,"roles":[]}}`,
+			expected: []string{"GET /_session"},
+			status:   kivik.StatusPreconditionFailed,
+			err:      "kivik: server is not in admin party mode",
+		},
+		{
+			name:     "1.x",
+			compat:   CompatCouch16,
+			confirm:  true,
+			session:  `{"ok":true,"userCtx":{"name":null,"roles":["_admin"]}}`,
+			expected: []string{"GET /_session", `PUT /_config/admins/bob "pass\"word"`},
+		},
+		{
+			name:     "2.x",
+			compat:   CompatCouch20,
+			confirm:  true,
+			session:  `{"ok":true,"userCtx":{"name":null,"roles":["_admin"]}}`,
+			expected: []string{"GET /_session", `PUT /_node/_local/_config/admins/bob "pass\"word"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					requests = append(requests, req.Method+" "+req.URL.Path)
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.session)}, nil
+				}
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				requests = append(requests, req.Method+" "+req.URL.Path+" "+string(body))
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`""`)}, nil
+			})
+			c.Compat = test.compat
+			err := c.CreateFirstAdmin(context.Background(), "", "bob", `pass"word`, test.confirm)
+			if d := diff.Interface(test.expected, requests); d != nil {
+				t.Error(d)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
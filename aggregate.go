@@ -0,0 +1,127 @@
+package couchdb
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+// DBChange is a change to one of the databases followed by AggregateChanges.
+type DBChange struct {
+	DBName string
+	driver.Change
+}
+
+// AggregateChanges follows the changes feeds of every database whose name
+// matches pattern, calling fn for each change, one at a time, in the order in
+// which they arrive. _db_updates is followed too, so that matching databases
+// created while AggregateChanges runs are followed as they appear, and deleted
+// ones are dropped. It returns when ctx is cancelled, when fn returns an
+// error, or when the client is closed.
+func (c *client) AggregateChanges(ctx context.Context, pattern *regexp.Regexp, fn func(*DBChange) error) error {
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	events := make(chan *DBChange)
+	updates := make(chan *driver.DBUpdate)
+	errs := make(chan error, 1)
+
+	// Follow _db_updates before listing databases, so none are missed.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.followDBUpdates(ctx, updates, errs)
+	}()
+	dbNames, err := c.AllDBs(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	followers := make(map[string]context.CancelFunc)
+	follow := func(dbName string) {
+		if _, ok := followers[dbName]; ok || !pattern.MatchString(dbName) || !c.dbAllowed(dbName) {
+			return
+		}
+		fctx, fcancel := context.WithCancel(ctx)
+		followers[dbName] = fcancel
+		d := &db{client: c, dbName: dbName}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Errors here mean the database has gone away, or the context has
+			// been cancelled, so there is nothing to report.
+			_ = d.follow(fctx, "now", func(change *driver.Change) error {
+				select {
+				case events <- &DBChange{DBName: dbName, Change: *change}:
+					return nil
+				case <-fctx.Done():
+					return fctx.Err()
+				}
+			})
+		}()
+	}
+	for _, dbName := range dbNames {
+		follow(dbName)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case update := <-updates:
+			switch update.Type {
+			case "created":
+				follow(update.DBName)
+			case "deleted":
+				if fcancel, ok := followers[update.DBName]; ok {
+					fcancel()
+					delete(followers, update.DBName)
+				}
+			}
+		case event := <-events:
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// followDBUpdates sends each database event to updates, reconnecting to
+// _db_updates as necessary, until ctx is cancelled. Errors which reconnecting
+// cannot fix are sent to errs.
+func (c *client) followDBUpdates(ctx context.Context, updates chan<- *driver.DBUpdate, errs chan<- error) {
+	for {
+		feed, err := c.DBUpdates(ctx)
+		if err != nil {
+			if status := kivik.StatusCode(err); err == errClosed || status >= 400 && status < 500 {
+				errs <- err
+				return
+			}
+		} else {
+			for {
+				update := &driver.DBUpdate{}
+				if err := feed.Next(update); err != nil {
+					break
+				}
+				select {
+				case updates <- update:
+				case <-ctx.Done():
+				}
+			}
+			_ = feed.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock().After(tailRetryDelay):
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestAggregateChanges(t *testing.T) {
+	defer func(delay time.Duration) { tailRetryDelay = delay }(tailRetryDelay)
+	tailRetryDelay = time.Millisecond
+
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		body := ""
+		switch req.URL.Path {
+		case "/_all_dbs":
+			body = `["tenant_a","other"]`
+		case "/_db_updates":
+			body = `{"db_name":"tenant_b","type":"created","seq":"1-xxx"}
+{"db_name":"other2","type":"created","seq":"2-xxx"}
+`
+		case "/tenant_a/_changes", "/tenant_b/_changes":
+			if req.URL.Query().Get("since") == "now" {
+				body = `{"seq":"1-xxx","id":"doc","changes":[{"rev":"1-aaa"}]}
+`
+			}
+		case "/other/_changes", "/other2/_changes":
+			t.Errorf("Unexpected request for %s", req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body:       Body(body),
+		}, nil
+	})
+	stop := errors.New("stop")
+	var changes []string
+	err := c.AggregateChanges(context.Background(), regexp.MustCompile(`^tenant_`), func(change *DBChange) error {
+		changes = append(changes, change.DBName+"/"+change.ID)
+		if len(changes) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sort.Strings(changes)
+	if d := diff.Interface([]string{"tenant_a/doc", "tenant_b/doc"}, changes); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestAggregateChangesClientClosed(t *testing.T) {
+	c := newTestClient(&http.Response{
+		StatusCode: kivik.StatusOK,
+		Body:       Body(`["tenant_a"]`),
+	}, nil)
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := c.AggregateChanges(ctx, regexp.MustCompile(`^tenant_`), func(*DBChange) error { return nil })
+	testy.StatusError(t, "kivik: client closed", kivik.StatusUnknownError, err)
+}
@@ -0,0 +1,78 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// dbAllowed returns true if the client may access dbName.
+func (c *client) dbAllowed(dbName string) bool {
+	return c == nil || c.allowedDBs == nil || c.allowedDBs[dbName]
+}
+
+// checkDB returns a Forbidden error if the client may not access dbName.
+func (c *client) checkDB(dbName string) error {
+	if !c.dbAllowed(dbName) {
+		return errors.Statusf(kivik.StatusForbidden, "kivik: access to database '%s' is not permitted by this client", dbName)
+	}
+	return nil
+}
+
+// checkEndpoint returns a Forbidden error if endpoint, the source or target of
+// a replication, is a database on the client's server which the client may
+// not access. endpoint is a database name or URL, or an object with a url
+// field, as accepted by _replicate.
+func (c *client) checkEndpoint(endpoint interface{}) error {
+	if c == nil || c.allowedDBs == nil {
+		return nil
+	}
+	dsn, ok := endpoint.(string)
+	if !ok {
+		raw, _ := json.Marshal(endpoint)
+		var obj struct {
+			URL string `json:"url"`
+		}
+		_ = json.Unmarshal(raw, &obj)
+		dsn = obj.URL
+	}
+	if dbName, ok := c.localDBName(dsn); ok {
+		return c.checkDB(dbName)
+	}
+	return nil
+}
+
+// localDBName returns the name of the database to which dsn refers, and true,
+// if it is a bare database name, or the URL of a database on the client's
+// server.
+func (c *client) localDBName(dsn string) (string, bool) {
+	u, err := url.Parse(dsn)
+	if err != nil || dsn == "" {
+		return "", false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return dsn, true
+	}
+	server, err := url.Parse(c.DSN())
+	if err != nil || !strings.EqualFold(u.Host, server.Host) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(u.Path, strings.TrimSuffix(server.Path, "/")), "/"), true
+}
+
+// filterDBs returns those of dbNames which the client may access.
+func (c *client) filterDBs(dbNames []string) []string {
+	if c.allowedDBs == nil {
+		return dbNames
+	}
+	allowed := make([]string, 0, len(dbNames))
+	for _, dbName := range dbNames {
+		if c.allowedDBs[dbName] {
+			allowed = append(allowed, dbName)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,130 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestAllowedDBs(t *testing.T) {
+	c := newTestClient(&http.Response{
+		StatusCode: kivik.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       Body(`["_users","bar","foo"]`),
+	}, nil)
+	if err := c.Authenticate(context.Background(), SetAllowedDBs("foo")); err != nil {
+		t.Fatal(err)
+	}
+	const forbidden = "kivik: access to database 'bar' is not permitted by this client"
+	t.Run("DB", func(t *testing.T) {
+		if _, err := c.DB(context.Background(), "foo", nil); err != nil {
+			t.Fatal(err)
+		}
+		_, err := c.DB(context.Background(), "bar", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("CreateDB", func(t *testing.T) {
+		err := c.CreateDB(context.Background(), "bar", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("DestroyDB", func(t *testing.T) {
+		err := c.DestroyDB(context.Background(), "bar", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("DBExists", func(t *testing.T) {
+		_, err := c.DBExists(context.Background(), "bar", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("QueryMany", func(t *testing.T) {
+		_, err := c.QueryMany(context.Background(), []string{"foo", "bar"}, "ddoc", "view", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("AllDBs", func(t *testing.T) {
+		dbs, err := c.AllDBs(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := diff.Interface([]string{"foo"}, dbs); d != nil {
+			t.Error(d)
+		}
+	})
+	t.Run("Replicate", func(t *testing.T) {
+		_, err := c.Replicate(context.Background(), "foo", "bar", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("Replicate by URL", func(t *testing.T) {
+		_, err := c.Replicate(context.Background(), "http://example.com/bar", "foo", nil)
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("CreateReplication", func(t *testing.T) {
+		_, err := c.CreateReplication(context.Background(), &ReplicationDoc{
+			Source: ReplicationEndpoint{URL: "http://example.com/bar"},
+			Target: ReplicationEndpoint{URL: "http://backup.example.com/bar"},
+		})
+		testy.StatusError(t, forbidden, kivik.StatusForbidden, err)
+	})
+	t.Run("remote replication endpoint", func(t *testing.T) {
+		if err := c.checkEndpoint(map[string]interface{}{"url": "http://backup.example.com/bar"}); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("unrestricted", func(t *testing.T) {
+		if err := c.Authenticate(context.Background(), SetAllowedDBs()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.DB(context.Background(), "bar", nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestAllowedDBsFeeds(t *testing.T) {
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		body := `{"db_name":"bar","type":"created","seq":"1-xxx"}
+{"db_name":"foo","type":"created","seq":"2-xxx"}
+`
+		if req.URL.Path == "/_global_changes/_changes" {
+			body = `{"seq":"1-xxx","id":"created:bar","changes":[{"rev":"1-aaa"}]}
+{"seq":"2-xxx","id":"created:foo","changes":[{"rev":"1-aaa"}]}
+`
+		}
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(body)}, nil
+	})
+	if err := c.Authenticate(context.Background(), SetAllowedDBs("foo")); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("DBUpdates", func(t *testing.T) {
+		updates, err := c.DBUpdates(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer updates.Close() // nolint: errcheck
+		update := &driver.DBUpdate{}
+		if err := updates.Next(update); err != nil {
+			t.Fatal(err)
+		}
+		if update.DBName != "foo" {
+			t.Errorf("Unexpected update for %s", update.DBName)
+		}
+	})
+	t.Run("GlobalChanges", func(t *testing.T) {
+		feed, err := c.GlobalChanges(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer feed.Close() // nolint: errcheck
+		event := &GlobalChange{}
+		if err := feed.Next(event); err != nil {
+			t.Fatal(err)
+		}
+		if event.DBName != "foo" {
+			t.Errorf("Unexpected event for %s", event.DBName)
+		}
+	})
+}
@@ -1,8 +1,18 @@
 package couchdb
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
 	"net/http"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
@@ -10,9 +20,18 @@ import (
 	"github.com/go-kivik/kivik/errors"
 )
 
+// PutAttachment uploads att, streaming its content to the server as it is
+// read. The content is sent with a Content-Length of att.Size, if known. On
+// success, att.Digest is set to the MD5 digest of the content sent, in the
+// form CouchDB reports it.
 func (d *db) PutAttachment(ctx context.Context, docID, rev string, att *driver.Attachment, options map[string]interface{}) (newRev string, err error) {
-	if docID == "" {
-		return "", missingArg("docID")
+	if err := validateDocID(docID); err != nil {
+		return "", err
+	}
+	if rev != "" {
+		if err := validateRev(rev); err != nil {
+			return "", err
+		}
 	}
 	if att == nil {
 		return "", missingArg("att")
@@ -20,17 +39,33 @@ func (d *db) PutAttachment(ctx context.Context, docID, rev string, att *driver.A
 	if att.Filename == "" {
 		return "", missingArg("att.Filename")
 	}
-	if att.ContentType == "" {
-		return "", missingArg("att.ContentType")
-	}
 	if att.Content == nil {
 		return "", missingArg("att.Content")
 	}
+	if err := d.checkQuota(ctx); err != nil {
+		return "", err
+	}
+	contentType, content := att.ContentType, io.ReadCloser(att.Content)
+	if contentType == "" {
+		var err error
+		contentType, content, err = detectContentType(att.Filename, att.Content)
+		if err != nil {
+			return "", err
+		}
+	}
 
 	fullCommit, err := fullCommit(false, options)
 	if err != nil {
 		return "", err
 	}
+	progress, err := progressFunc(options)
+	if err != nil {
+		return "", err
+	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return "", err
+	}
 
 	query, err := optionsToParams(options)
 	if err != nil {
@@ -42,18 +77,88 @@ func (d *db) PutAttachment(ctx context.Context, docID, rev string, att *driver.A
 	var response struct {
 		Rev string `json:"rev"`
 	}
+	h := md5.New()
 	opts := &chttp.Options{
-		Body:        att.Content,
-		ContentType: att.ContentType,
+		Body: newProgressReader(ctx, &readCloser{
+			Reader: io.TeeReader(content, h),
+			Closer: content,
+		}, progress),
+		ContentType: contentType,
 		FullCommit:  fullCommit,
 	}
-	_, err = d.Client.DoJSON(ctx, kivik.MethodPut, d.path(chttp.EncodeDocID(docID)+"/"+att.Filename, query), opts, &response)
+	if att.Size > 0 {
+		opts.ContentLength = att.Size
+	}
+	resp, err := d.Client.DoJSON(ctx, kivik.MethodPut, d.path(chttp.EncodeDocID(docID)+"/"+att.Filename, query), opts, &response)
 	if err != nil {
 		return "", err
 	}
+	wr.record(resp.StatusCode)
+	att.Digest = "md5-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
 	return response.Rev, nil
 }
 
+// detectContentType guesses the content type of an attachment, first from the
+// filename's extension, then by sniffing the first 512 bytes of content. The
+// returned ReadCloser replays any bytes consumed while sniffing.
+func detectContentType(filename string, content io.ReadCloser) (string, io.ReadCloser, error) {
+	if ct := mime.TypeByExtension(path.Ext(filename)); ct != "" {
+		return ct, content, nil
+	}
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(content, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	buf = buf[:n]
+	return http.DetectContentType(buf), &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(buf), content),
+		Closer: content,
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ProgressFunc is called during an upload, with the total number of bytes
+// sent so far.
+type ProgressFunc func(sent int64)
+
+// progressReader wraps an upload body, reporting progress to fn, and aborting
+// the upload once ctx is cancelled.
+type progressReader struct {
+	io.ReadCloser
+	ctx  context.Context
+	fn   ProgressFunc
+	sent int64
+}
+
+var _ io.ReadCloser = &progressReader{}
+
+func newProgressReader(ctx context.Context, r io.ReadCloser, fn ProgressFunc) io.ReadCloser {
+	return &progressReader{
+		ReadCloser: r,
+		ctx:        ctx,
+		fn:         fn,
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		if r.fn != nil {
+			r.fn(r.sent)
+		}
+	}
+	return n, err
+}
+
 func (d *db) GetAttachmentMeta(ctx context.Context, docID, rev, filename string, options map[string]interface{}) (*driver.Attachment, error) {
 	resp, err := d.fetchAttachment(ctx, kivik.MethodHead, docID, rev, filename, options)
 	if err != nil {
@@ -75,17 +180,30 @@ func (d *db) fetchAttachment(ctx context.Context, method, docID, rev, filename s
 	if method == "" {
 		return nil, errors.New("method required")
 	}
-	if docID == "" {
-		return nil, missingArg("docID")
+	if err := validateDocID(docID); err != nil {
+		return nil, err
 	}
 	if filename == "" {
 		return nil, missingArg("filename")
 	}
+	if rev != "" {
+		if err := validateRev(rev); err != nil {
+			return nil, err
+		}
+	}
 
 	inm, err := ifNoneMatch(options)
 	if err != nil {
 		return nil, err
 	}
+	rng, err := rangeHeader(options)
+	if err != nil {
+		return nil, err
+	}
+	ir, err := ifRange(options)
+	if err != nil {
+		return nil, err
+	}
 
 	query, err := optionsToParams(options)
 	if err != nil {
@@ -96,6 +214,8 @@ func (d *db) fetchAttachment(ctx context.Context, method, docID, rev, filename s
 	}
 	opts := &chttp.Options{
 		IfNoneMatch: inm,
+		Range:       rng,
+		IfRange:     ir,
 	}
 	resp, err := d.Client.DoReq(ctx, method, d.path(chttp.EncodeDocID(docID)+"/"+filename, query), opts)
 	if err != nil {
@@ -104,6 +224,100 @@ func (d *db) fetchAttachment(ctx context.Context, method, docID, rev, filename s
 	return resp, chttp.ResponseError(resp)
 }
 
+// ResumeDownload continues an interrupted download of an attachment. dst must
+// hold the bytes fetched so far; they are re-read to seed the checksum, the
+// remainder is requested with a Range header and appended to dst, and the
+// MD5 digest of the complete content is verified against the one reported by
+// the server.
+//
+// digest is the digest of the attachment when the download began, as returned
+// by GetAttachment or an earlier ResumeDownload. It is sent with If-Range, so
+// that if the attachment has changed since, the server returns all of it
+// instead of a range. It may be empty if rev is given, as the attachments of a
+// revision never change. If the whole attachment is returned, the download
+// restarts from the beginning of dst, which is first truncated if it has a
+// Truncate method, as *os.File does. Otherwise, if the new content is shorter
+// than the bytes already in dst, the excess remains after it, and the
+// returned Size must be used to find its end.
+func (d *db) ResumeDownload(ctx context.Context, docID, rev, filename, digest string, dst io.ReadWriteSeeker, options map[string]interface{}) (*driver.Attachment, error) {
+	if dst == nil {
+		return nil, missingArg("dst")
+	}
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	h := md5.New()
+	offset, err := io.Copy(h, dst)
+	if err != nil {
+		return nil, err
+	}
+	opts := copyOptions(options)
+	if offset > 0 {
+		opts[OptionRange] = fmt.Sprintf("bytes=%d-", offset)
+		if digest != "" {
+			opts[OptionIfRange] = digest
+		}
+	}
+	resp, err := d.fetchAttachment(ctx, kivik.MethodGet, docID, rev, filename, opts)
+	if kivik.StatusCode(err) == kivik.StatusRequestedRangeNotSatisfiable {
+		// Nothing left to fetch; just verify what we have.
+		delete(opts, OptionRange)
+		delete(opts, OptionIfRange)
+		resp, err = d.fetchAttachment(ctx, kivik.MethodHead, docID, rev, filename, opts)
+		if err != nil {
+			return nil, err
+		}
+		_ = resp.Body.Close()
+		att, e := decodeAttachment(resp)
+		if e != nil {
+			return nil, e
+		}
+		att.Size = offset
+		att.Content = nil
+		return att, verifyDigest(att.Digest, h)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	att, err := decodeAttachment(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		// The range was ignored, or the attachment changed, so start over.
+		if _, err = dst.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if t, ok := dst.(interface {
+			Truncate(int64) error
+		}); ok {
+			if err = t.Truncate(0); err != nil {
+				return nil, err
+			}
+		}
+		h.Reset()
+		offset = 0
+	}
+	n, err := io.Copy(io.MultiWriter(dst, h), resp.Body)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusNetworkError, err)
+	}
+	att.Size = offset + n
+	att.Content = nil
+	return att, verifyDigest(att.Digest, h)
+}
+
+// verifyDigest compares the MD5 sum in h against a CouchDB attachment digest,
+// which may or may not carry the 'md5-' prefix.
+func verifyDigest(digest string, h hash.Hash) error {
+	sum := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if strings.TrimPrefix(digest, "md5-") != sum {
+		return errors.Statusf(kivik.StatusBadResponse, "kivik: attachment digest mismatch: expected %s, got md5-%s", digest, sum)
+	}
+	return nil
+}
+
 func decodeAttachment(resp *http.Response) (*driver.Attachment, error) {
 	cType, err := getContentType(resp)
 	if err != nil {
@@ -139,11 +353,11 @@ func getDigest(resp *http.Response) (string, error) {
 }
 
 func (d *db) DeleteAttachment(ctx context.Context, docID, rev, filename string, options map[string]interface{}) (newRev string, err error) {
-	if docID == "" {
-		return "", missingArg("docID")
+	if err := validateDocID(docID); err != nil {
+		return "", err
 	}
-	if rev == "" {
-		return "", missingArg("rev")
+	if err := validateRev(rev); err != nil {
+		return "", err
 	}
 	if filename == "" {
 		return "", missingArg("filename")
@@ -153,6 +367,10 @@ func (d *db) DeleteAttachment(ctx context.Context, docID, rev, filename string,
 	if err != nil {
 		return "", err
 	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return "", err
+	}
 
 	query, err := optionsToParams(options)
 	if err != nil {
@@ -166,9 +384,51 @@ func (d *db) DeleteAttachment(ctx context.Context, docID, rev, filename string,
 	opts := &chttp.Options{
 		FullCommit: fullCommit,
 	}
-	_, err = d.Client.DoJSON(ctx, kivik.MethodDelete, d.path(chttp.EncodeDocID(docID)+"/"+filename, query), opts, &response)
+	resp, err := d.Client.DoJSON(ctx, kivik.MethodDelete, d.path(chttp.EncodeDocID(docID)+"/"+filename, query), opts, &response)
 	if err != nil {
 		return "", err
 	}
+	wr.record(resp.StatusCode)
 	return response.Rev, nil
 }
+
+// AttachmentStub describes an attachment as listed in a document's
+// _attachments map.
+type AttachmentStub struct {
+	Filename    string `json:"-"`
+	ContentType string `json:"content_type"`
+	Length      int64  `json:"length"`
+	Digest      string `json:"digest"`
+	RevPos      int64  `json:"revpos"`
+}
+
+// ListAttachments returns the attachment stubs of the requested document,
+// sorted by filename.
+func (d *db) ListAttachments(ctx context.Context, docID string, options map[string]interface{}) ([]*AttachmentStub, error) {
+	resp, _, err := d.get(ctx, kivik.MethodGet, docID, options)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Attachments map[string]*AttachmentStub `json:"_attachments"`
+	}
+	if err := chttp.DecodeJSON(resp, &doc); err != nil {
+		return nil, err
+	}
+	return sortedStubs(doc.Attachments), nil
+}
+
+// sortedStubs converts an _attachments map to a slice sorted by filename.
+func sortedStubs(atts map[string]*AttachmentStub) []*AttachmentStub {
+	filenames := make([]string, 0, len(atts))
+	for filename := range atts {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	stubs := make([]*AttachmentStub, len(filenames))
+	for i, filename := range filenames {
+		stubs[i] = atts[filename]
+		stubs[i].Filename = filename
+	}
+	return stubs
+}
@@ -1,18 +1,22 @@
 package couchdb
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/flimzy/diff"
 	"github.com/flimzy/testy"
 
+	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
 	"github.com/go-kivik/kivik/driver"
 	"github.com/go-kivik/kivik/errors"
@@ -39,6 +43,7 @@ func TestPutAttachment(t *testing.T) {
 		options map[string]interface{}
 
 		newRev string
+		digest string
 		status int
 		err    string
 		final  func(*testing.T)
@@ -64,16 +69,6 @@ func TestPutAttachment(t *testing.T) {
 			status: kivik.StatusBadRequest,
 			err:    "kivik: att.Filename required",
 		},
-		{
-			name: "missing content type",
-			id:   "foo",
-			rev:  "1-xxx",
-			att: &driver.Attachment{
-				Filename: "x.jpg",
-			},
-			status: kivik.StatusBadRequest,
-			err:    "kivik: att.ContentType required",
-		},
 		{
 			name: "no body",
 			id:   "foo",
@@ -85,6 +80,26 @@ func TestPutAttachment(t *testing.T) {
 			status: kivik.StatusBadRequest,
 			err:    "kivik: att.Content required",
 		},
+		{
+			name: "detected content type",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if err := consume(req.Body); err != nil {
+					return nil, err
+				}
+				if ct := req.Header.Get("Content-Type"); ct != "image/jpeg" {
+					return nil, errors.Errorf("Unexpected Content-Type: %s", ct)
+				}
+				return nil, errors.New("success")
+			}),
+			id:  "foo",
+			rev: "1-xxx",
+			att: &driver.Attachment{
+				Filename: "x.jpg",
+				Content:  Body("x"),
+			},
+			status: kivik.StatusNetworkError,
+			err:    "success",
+		},
 		{
 			name: "network error",
 			db:   newTestDB(nil, errors.New("net error")),
@@ -139,6 +154,28 @@ func TestPutAttachment(t *testing.T) {
 				}, nil
 			}),
 			newRev: "2-8ee3381d24ee4ac3e9f8c1f6c7395641",
+			digest: "md5-vqglL/ToD0FxnqE83wBycw==",
+		},
+		{
+			name: "error reading content",
+			id:   "foo",
+			rev:  "1-xxx",
+			att: &driver.Attachment{
+				Filename:    "foo.txt",
+				ContentType: "text/plain",
+				Content:     ioutil.NopCloser(testy.ErrorReader("Hello", errors.New("read failed"))),
+			},
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				// Whatever was received would be stored.
+				_, _ = ioutil.ReadAll(req.Body)
+				return &http.Response{
+					StatusCode: 201,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body:       Body(`{"ok":true,"id":"foo","rev":"2-xxx"}`),
+				}, nil
+			}),
+			status: kivik.StatusNetworkError,
+			err:    "read failed",
 		},
 		{
 			name: "no rev",
@@ -221,6 +258,45 @@ func TestPutAttachment(t *testing.T) {
 			status:  kivik.StatusBadRequest,
 			err:     "kivik: option 'X-Couch-Full-Commit' must be bool, not int",
 		},
+		{
+			name: "invalid progress type",
+			db:   &db{},
+			id:   "foo",
+			rev:  "1-xxx",
+			att: &driver.Attachment{
+				Filename:    "foo.txt",
+				ContentType: "text/plain",
+				Content:     Body("x"),
+			},
+			options: map[string]interface{}{OptionProgress: 123},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: option 'progress' must be ProgressFunc, not int",
+		},
+		func() paoTest {
+			var sent int64
+			return paoTest{
+				name: "progress",
+				db: newTestDB(&http.Response{
+					StatusCode: 201,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body:       Body(`{"ok":true,"id":"foo","rev":"2-xxx"}`),
+				}, nil),
+				id:  "foo",
+				rev: "1-xxx",
+				att: &driver.Attachment{
+					Filename:    "foo.txt",
+					ContentType: "text/plain",
+					Content:     Body("Hello, World!"),
+				},
+				options: map[string]interface{}{OptionProgress: ProgressFunc(func(n int64) { sent = n })},
+				newRev:  "2-xxx",
+				final: func(t *testing.T) {
+					if sent != 14 {
+						t.Errorf("Unexpected bytes sent: %d", sent)
+					}
+				},
+			}
+		}(),
 		func() paoTest {
 			body := &closer{Reader: strings.NewReader("x")}
 			return paoTest{
@@ -259,6 +335,9 @@ func TestPutAttachment(t *testing.T) {
 			if newRev != test.newRev {
 				t.Errorf("Expected %s, got %s\n", test.newRev, newRev)
 			}
+			if test.digest != "" && test.att.Digest != test.digest {
+				t.Errorf("Expected digest %s, got %s", test.digest, test.att.Digest)
+			}
 			if test.final != nil {
 				test.final(t)
 			}
@@ -731,3 +810,380 @@ func TestDeleteAttachment(t *testing.T) {
 		})
 	}
 }
+
+// memFile is an in-memory io.ReadWriteSeeker.
+type memFile struct {
+	buf []byte
+	pos int64
+}
+
+var _ io.ReadWriteSeeker = &memFile{}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.pos >= int64(len(f.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.buf[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	end := f.pos + int64(len(p))
+	if end > int64(len(f.buf)) {
+		f.buf = append(f.buf[:f.pos], make([]byte, end-f.pos)...)
+	}
+	copy(f.buf[f.pos:], p)
+	f.pos = end
+	return len(p), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.buf = f.buf[:size]
+	return nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		f.pos = offset
+	case io.SeekCurrent:
+		f.pos += offset
+	case io.SeekEnd:
+		f.pos = int64(len(f.buf)) + offset
+	}
+	return f.pos, nil
+}
+
+// rangeDB returns a db which serves content as an attachment, honoring Range
+// headers, unless If-Range doesn't match digest, if ranges is true.
+func rangeDB(content, digest string, ranges bool) *db {
+	return newCustomDB(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{
+			"Content-Type": {"text/plain"},
+			"ETag":         {`"` + digest + `"`},
+		}
+		if req.Method == kivik.MethodHead {
+			return &http.Response{
+				StatusCode:    200,
+				Header:        header,
+				ContentLength: int64(len(content)),
+				Body:          ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+		var start int
+		ir := req.Header.Get("If-Range")
+		if rng := req.Header.Get("Range"); ranges && rng != "" && (ir == "" || ir == `"`+digest+`"`) {
+			if _, err := fmt.Sscanf(rng, "bytes=%d-", &start); err != nil {
+				return nil, err
+			}
+			if start >= len(content) {
+				return &http.Response{
+					StatusCode: kivik.StatusRequestedRangeNotSatisfiable,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+					Request:    req,
+				}, nil
+			}
+			return &http.Response{
+				StatusCode:    http.StatusPartialContent,
+				Header:        header,
+				ContentLength: int64(len(content) - start),
+				Body:          ioutil.NopCloser(strings.NewReader(content[start:])),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode:    200,
+			Header:        header,
+			ContentLength: int64(len(content)),
+			Body:          ioutil.NopCloser(strings.NewReader(content)),
+		}, nil
+	})
+}
+
+func TestResumeDownload(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       *db
+		partial  string
+		digest   string
+		dst      io.ReadWriteSeeker
+		expected *driver.Attachment
+		content  string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no dst",
+			db:     &db{},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: dst required",
+		},
+		{
+			name:    "fresh download",
+			db:      rangeDB("Hello, world!", "bNNVbesNpUvKBgtMOUeYOQ==", true),
+			partial: "",
+			expected: &driver.Attachment{
+				ContentType: "text/plain",
+				Digest:      "bNNVbesNpUvKBgtMOUeYOQ==",
+				Size:        13,
+			},
+			content: "Hello, world!",
+		},
+		{
+			name:    "resumed",
+			db:      rangeDB("Hello, world!", "bNNVbesNpUvKBgtMOUeYOQ==", true),
+			partial: "Hello",
+			expected: &driver.Attachment{
+				ContentType: "text/plain",
+				Digest:      "bNNVbesNpUvKBgtMOUeYOQ==",
+				Size:        13,
+			},
+			content: "Hello, world!",
+		},
+		{
+			name:    "range ignored",
+			db:      rangeDB("Hello, world!", "bNNVbesNpUvKBgtMOUeYOQ==", false),
+			partial: "Hello",
+			expected: &driver.Attachment{
+				ContentType: "text/plain",
+				Digest:      "bNNVbesNpUvKBgtMOUeYOQ==",
+				Size:        13,
+			},
+			content: "Hello, world!",
+		},
+		{
+			name:    "unchanged since",
+			db:      rangeDB("Hello, world!", "bNNVbesNpUvKBgtMOUeYOQ==", true),
+			partial: "Hello",
+			digest:  "bNNVbesNpUvKBgtMOUeYOQ==",
+			expected: &driver.Attachment{
+				ContentType: "text/plain",
+				Digest:      "bNNVbesNpUvKBgtMOUeYOQ==",
+				Size:        13,
+			},
+			content: "Hello, world!",
+		},
+		{
+			name:    "changed since",
+			db:      rangeDB("Hi!", "U2BwbIA6dZ46nyylSmUZUA==", true),
+			partial: "Hello",
+			digest:  "bNNVbesNpUvKBgtMOUeYOQ==",
+			expected: &driver.Attachment{
+				ContentType: "text/plain",
+				Digest:      "U2BwbIA6dZ46nyylSmUZUA==",
+				Size:        3,
+			},
+			content: "Hi!",
+		},
+		{
+			name:    "already complete",
+			db:      rangeDB("Hello, world!", "bNNVbesNpUvKBgtMOUeYOQ==", true),
+			partial: "Hello, world!",
+			expected: &driver.Attachment{
+				ContentType: "text/plain",
+				Digest:      "bNNVbesNpUvKBgtMOUeYOQ==",
+				Size:        13,
+			},
+			content: "Hello, world!",
+		},
+		{
+			name:    "corrupt partial",
+			db:      rangeDB("Hello, world!", "bNNVbesNpUvKBgtMOUeYOQ==", true),
+			partial: "Jello",
+			status:  kivik.StatusBadResponse,
+			err:     "kivik: attachment digest mismatch: expected bNNVbesNpUvKBgtMOUeYOQ==, got md5-.*",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := test.dst
+			if test.db.client != nil {
+				dst = &memFile{buf: []byte(test.partial)}
+			}
+			att, err := test.db.ResumeDownload(context.Background(), "foo", "", "foo.txt", test.digest, dst, nil)
+			testy.StatusErrorRE(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, att); d != nil {
+				t.Errorf("Unexpected attachment:\n%s", d)
+			}
+			if d := diff.Text(test.content, string(dst.(*memFile).buf)); d != nil {
+				t.Errorf("Unexpected content:\n%s", d)
+			}
+		})
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var sent []int64
+	r := newProgressReader(ctx, Body("Hello, World!"), func(n int64) { sent = append(sent, n) })
+	buf := make([]byte, 5)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	_, err := r.Read(buf)
+	if d := diff.Interface([]int64{5}, sent); d != nil {
+		t.Error(d)
+	}
+	testy.Error(t, "context canceled", err)
+}
+
+func TestPutAttachmentProgress(t *testing.T) {
+	// Large enough not to fit in the socket buffers, so that the server must
+	// start reading before the upload can complete.
+	const size = 32 << 20
+	var progress, atStart, received int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.StoreInt64(&atStart, atomic.LoadInt64(&progress))
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		atomic.StoreInt64(&received, n)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(kivik.StatusCreated)
+		_, _ = w.Write([]byte(`{"ok":true,"id":"foo","rev":"2-xxx"}`))
+	}))
+	defer s.Close()
+	c, err := chttp.New(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &db{client: &client{Client: c}, dbName: "testdb"}
+	att := &driver.Attachment{
+		Filename:    "foo.bin",
+		ContentType: "application/octet-stream",
+		Content:     ioutil.NopCloser(bytes.NewReader(make([]byte, size))),
+	}
+	options := map[string]interface{}{
+		OptionProgress: ProgressFunc(func(n int64) { atomic.StoreInt64(&progress, n) }),
+	}
+	if _, err := d.PutAttachment(context.Background(), "foo", "1-xxx", att, options); err != nil {
+		t.Fatal(err)
+	}
+	if atStart >= size {
+		t.Errorf("Progress reported %d bytes sent before the server read any", atStart)
+	}
+	if progress != received || received != size {
+		t.Errorf("Progress reported %d bytes sent, server received %d", progress, received)
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		expected string
+	}{
+		{
+			name:     "by extension",
+			filename: "foo.png",
+			content:  "not really a png",
+			expected: "image/png",
+		},
+		{
+			name:     "sniffed html",
+			filename: "index",
+			content:  "<html><body>Hello</body></html>",
+			expected: "text/html; charset=utf-8",
+		},
+		{
+			name:     "sniffed binary",
+			filename: "blob",
+			content:  "\x00\x01\x02",
+			expected: "application/octet-stream",
+		},
+		{
+			name:     "long content",
+			filename: "long",
+			content:  strings.Repeat("x", 1000),
+			expected: "text/plain; charset=utf-8",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ct, content, err := detectContentType(test.filename, ioutil.NopCloser(strings.NewReader(test.content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ct != test.expected {
+				t.Errorf("Unexpected content type: %s", ct)
+			}
+			body, err := ioutil.ReadAll(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.Text(test.content, string(body)); d != nil {
+				t.Errorf("Unexpected content:\n%s", d)
+			}
+		})
+	}
+}
+
+func TestListAttachments(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       *db
+		id       string
+		expected []*AttachmentStub
+		status   int
+		err      string
+	}{
+		{
+			name:   "missing doc ID",
+			db:     &db{},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: docID required",
+		},
+		{
+			name: "not found",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusNotFound,
+				Body:       Body(""),
+			}, nil),
+			id:     "foo",
+			status: kivik.StatusNotFound,
+			err:    "Not Found",
+		},
+		{
+			name: "no attachments",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Header: http.Header{
+					"Content-Type": {"application/json"},
+					"ETag":         {`"1-xxx"`},
+				},
+				Body: Body(`{"_id":"foo","_rev":"1-xxx"}`),
+			}, nil),
+			id:       "foo",
+			expected: []*AttachmentStub{},
+		},
+		{
+			name: "success",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Header: http.Header{
+					"Content-Type": {"application/json"},
+					"ETag":         {`"3-xxx"`},
+				},
+				Body: Body(`{"_id":"foo","_rev":"3-xxx","_attachments":{
+					"foo.txt":{"content_type":"text/plain","revpos":2,"digest":"md5-bNNVbesNpUvKBgtMOUeYOQ==","length":13,"stub":true},
+					"bar.jpg":{"content_type":"image/jpeg","revpos":3,"digest":"md5-gSr8dSmynwAoomH7V6RVYw==","length":1024,"stub":true}
+				}}`),
+			}, nil),
+			id: "foo",
+			expected: []*AttachmentStub{
+				{Filename: "bar.jpg", ContentType: "image/jpeg", Length: 1024, Digest: "md5-gSr8dSmynwAoomH7V6RVYw==", RevPos: 3},
+				{Filename: "foo.txt", ContentType: "text/plain", Length: 13, Digest: "md5-bNNVbesNpUvKBgtMOUeYOQ==", RevPos: 2},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stubs, err := test.db.ListAttachments(context.Background(), test.id, nil)
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, stubs); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// AuthHandler names an authentication handler enabled on the server, as
+// reported in the authentication_handlers of /_session.
+type AuthHandler string
+
+// Authentication handlers known to CouchDB.
+const (
+	// AuthHandlerJWT is JSON Web Token authentication (CouchDB 3.1 and later).
+	AuthHandlerJWT AuthHandler = "jwt"
+	// AuthHandlerCookie is cookie authentication, as provided by
+	// chttp.CookieAuth.
+	AuthHandlerCookie AuthHandler = "cookie"
+	// AuthHandlerProxy is proxy authentication, where a trusted proxy
+	// identifies the user with X-Auth-CouchDB-* headers.
+	AuthHandlerProxy AuthHandler = "proxy"
+	// AuthHandlerDefault is HTTP Basic authentication, as provided by
+	// chttp.BasicAuth.
+	AuthHandlerDefault AuthHandler = "default"
+	// AuthHandlerOAuth is OAuth 1.0 authentication (CouchDB 1.x).
+	AuthHandlerOAuth AuthHandler = "oauth"
+)
+
+// authStrength lists the handlers chosen by StrongestAuth, strongest first:
+// tokens, then credentials sent once, then a trusted proxy, then credentials
+// sent with every request.
+var authStrength = []AuthHandler{AuthHandlerJWT, AuthHandlerCookie, AuthHandlerProxy, AuthHandlerDefault}
+
+// AuthHandlers returns the authentication handlers enabled on the server.
+func (c *client) AuthHandlers(ctx context.Context) ([]AuthHandler, error) {
+	var s session
+	if _, err := c.DoJSON(ctx, kivik.MethodGet, "/_session", nil, &s); err != nil {
+		return nil, err
+	}
+	handlers := make([]AuthHandler, len(s.Info.AuthenticationHandlers))
+	for i, h := range s.Info.AuthenticationHandlers {
+		handlers[i] = AuthHandler(h)
+	}
+	return handlers, nil
+}
+
+type strongestAuth map[AuthHandler]interface{}
+
+var _ Authenticator = strongestAuth(nil)
+
+func (a strongestAuth) auth(ctx context.Context, c *client) error {
+	handlers, err := c.AuthHandlers(ctx)
+	if err != nil {
+		return err
+	}
+	enabled := make(map[AuthHandler]bool, len(handlers))
+	for _, h := range handlers {
+		enabled[h] = true
+	}
+	for _, h := range authStrength {
+		if auth, ok := a[h]; ok && enabled[h] {
+			return c.Authenticate(ctx, auth)
+		}
+	}
+	return errors.Status(kivik.StatusUnauthorized, "kivik: no configured authenticator matches the server's authentication handlers")
+}
+
+// StrongestAuth returns an authenticator which asks the server which
+// authentication handlers it has enabled, and authenticates with the
+// strongest of auths for which the server has a handler, in the order JWT,
+// cookie, proxy, then HTTP Basic. Each value of auths is an authenticator as
+// accepted by Authenticate, typically a chttp.Authenticator.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.StrongestAuth(map[couchdb.AuthHandler]interface{}{
+//         couchdb.AuthHandlerCookie:  &chttp.CookieAuth{Username: "bob", Password: "abc123"},
+//         couchdb.AuthHandlerDefault: &chttp.BasicAuth{Username: "bob", Password: "abc123"},
+//     }))
+func StrongestAuth(auths map[AuthHandler]interface{}) Authenticator {
+	return strongestAuth(auths)
+}
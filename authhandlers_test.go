@@ -0,0 +1,84 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+)
+
+const sessionHandlers = `{"ok":true,"userCtx":{"name":null,"roles":[]},"info":{"authentication_handlers":["cookie","default"]}}`
+
+func TestAuthHandlers(t *testing.T) {
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(sessionHandlers)}, nil
+	})
+	handlers, err := c.AuthHandlers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff.Interface([]AuthHandler{AuthHandlerCookie, AuthHandlerDefault}, handlers); d != nil {
+		t.Error(d)
+	}
+}
+
+type namedAuth struct {
+	name string
+	used *string
+}
+
+var _ chttp.Authenticator = &namedAuth{}
+
+func (a *namedAuth) Authenticate(_ context.Context, _ *chttp.Client) error {
+	*a.used = a.name
+	return nil
+}
+
+func TestStrongestAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		auths    []AuthHandler
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name:     "strongest supported",
+			auths:    []AuthHandler{AuthHandlerJWT, AuthHandlerCookie, AuthHandlerDefault},
+			expected: "cookie",
+		},
+		{
+			name:     "fallback",
+			auths:    []AuthHandler{AuthHandlerProxy, AuthHandlerDefault},
+			expected: "default",
+		},
+		{
+			name:   "none supported",
+			auths:  []AuthHandler{AuthHandlerJWT},
+			status: kivik.StatusUnauthorized,
+			err:    "kivik: no configured authenticator matches the server's authentication handlers",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(sessionHandlers)}, nil
+			})
+			var used string
+			auths := make(map[AuthHandler]interface{}, len(test.auths))
+			for _, h := range test.auths {
+				auths[h] = &namedAuth{name: string(h), used: &used}
+			}
+			err := c.Authenticate(context.Background(), StrongestAuth(auths))
+			if used != test.expected {
+				t.Errorf("Expected %q authenticator to be used, got %q", test.expected, used)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
@@ -15,6 +16,9 @@ import (
 type bulkResults struct {
 	body io.ReadCloser
 	dec  *json.Decoder
+	// rejected is set when an all_or_nothing update was rejected, so that no
+	// document was saved.
+	rejected bool
 }
 
 var _ driver.BulkResults = &bulkResults{}
@@ -55,10 +59,17 @@ func (r *bulkResults) Next(update *driver.BulkResult) error {
 		switch updateResult.Error {
 		case "conflict":
 			status = kivik.StatusConflict
+		case "forbidden":
+			status = kivik.StatusForbidden
+		case "unauthorized":
+			status = kivik.StatusUnauthorized
 		default:
 			status = 600 // Unknown error
 		}
 		update.Error = errors.Status(status, updateResult.Reason)
+	} else if r.rejected {
+		update.Rev = ""
+		update.Error = errors.Status(kivik.StatusExpectationFailed, "kivik: not saved, as another document was rejected")
 	}
 	return nil
 }
@@ -67,7 +78,123 @@ func (r *bulkResults) Close() error {
 	return r.body.Close()
 }
 
+var allOrNothingNotImplemented = errors.Status(kivik.StatusNotImplemented, "kivik: all_or_nothing not supported by CouchDB 2.0.0 and later")
+
 func (d *db) BulkDocs(ctx context.Context, docs []interface{}, options map[string]interface{}) (driver.BulkResults, error) {
+	if err := d.checkQuota(ctx, docs...); err != nil {
+		return nil, err
+	}
+	aon, err := allOrNothing(options)
+	if err != nil {
+		return nil, err
+	}
+	if aon && d.client != nil && d.client.Compat == CompatCouch20 {
+		return nil, allOrNothingNotImplemented
+	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return nil, err
+	}
+	if d.client != nil && d.Client.MaxRequestSize > 0 {
+		max := d.Client.MaxRequestSize
+		batches, err := splitBulkDocs(docs, options, max)
+		if err != nil {
+			return nil, err
+		}
+		if len(batches) > 1 {
+			if aon {
+				return nil, errors.Statusf(http.StatusRequestEntityTooLarge, "kivik: all_or_nothing update of %d documents exceeds maximum request size of %d bytes", len(docs), max)
+			}
+			return d.batchBulkDocs(ctx, batches, options, wr)
+		}
+	}
+	return d.bulkDocs(ctx, docs, options, wr)
+}
+
+// splitBulkDocs divides docs into batches which, once encoded along with
+// options, each fit within max bytes. Documents are returned pre-encoded.
+func splitBulkDocs(docs []interface{}, options map[string]interface{}, max int64) ([][]interface{}, error) {
+	envelope, err := json.Marshal(options)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	// Allow for `,"docs":[]` and a trailing newline
+	overhead := int64(len(envelope)) + 11
+	var batches [][]interface{}
+	var batch []interface{}
+	size := overhead
+	for i, doc := range docs {
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+		}
+		docSize := int64(len(encoded)) + 1 // Allow for the separating comma
+		if overhead+docSize > max {
+			return nil, errors.Statusf(http.StatusRequestEntityTooLarge, "kivik: document %d of %d bytes exceeds maximum request size of %d bytes", i, len(encoded), max)
+		}
+		if size+docSize > max {
+			batches = append(batches, batch)
+			batch, size = nil, overhead
+		}
+		batch = append(batch, json.RawMessage(encoded))
+		size += docSize
+	}
+	return append(batches, batch), nil
+}
+
+// batchBulkDocs sends each batch in turn, returning the combined results.
+func (d *db) batchBulkDocs(ctx context.Context, batches [][]interface{}, options map[string]interface{}, wr *WriteResult) (driver.BulkResults, error) {
+	results := &multiBulkResults{}
+	var rejected error
+	for _, batch := range batches {
+		opts := make(map[string]interface{}, len(options))
+		for k, v := range options {
+			opts[k] = v
+		}
+		r, err := d.bulkDocs(ctx, batch, opts, wr)
+		if r == nil {
+			_ = results.Close()
+			return nil, err
+		}
+		if err != nil {
+			rejected = err
+		}
+		results.results = append(results.results, r)
+	}
+	return results, rejected
+}
+
+// multiBulkResults combines the results of several _bulk_docs requests.
+type multiBulkResults struct {
+	results []driver.BulkResults
+}
+
+var _ driver.BulkResults = &multiBulkResults{}
+
+func (r *multiBulkResults) Next(update *driver.BulkResult) error {
+	for len(r.results) > 0 {
+		err := r.results[0].Next(update)
+		if err != io.EOF {
+			return err
+		}
+		_ = r.results[0].Close()
+		r.results = r.results[1:]
+	}
+	return io.EOF
+}
+
+func (r *multiBulkResults) Close() error {
+	var err error
+	for _, result := range r.results {
+		if e := result.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	r.results = nil
+	return err
+}
+
+func (d *db) bulkDocs(ctx context.Context, docs []interface{}, options map[string]interface{}, wr *WriteResult) (driver.BulkResults, error) {
 	if options == nil {
 		options = make(map[string]interface{})
 	}
@@ -75,6 +202,10 @@ func (d *db) BulkDocs(ctx context.Context, docs []interface{}, options map[strin
 	if err != nil {
 		return nil, err
 	}
+	aon, err := allOrNothing(options)
+	if err != nil {
+		return nil, err
+	}
 	options["docs"] = docs
 	opts := &chttp.Options{
 		Body:       chttp.EncodeBody(options),
@@ -85,12 +216,16 @@ func (d *db) BulkDocs(ctx context.Context, docs []interface{}, options map[strin
 		return nil, err
 	}
 	switch resp.StatusCode {
-	case kivik.StatusCreated:
-		// Nothing to do
+	case kivik.StatusCreated, kivik.StatusAccepted:
+		wr.record(resp.StatusCode)
 	case kivik.StatusExpectationFailed:
+		reason := "one or more document was rejected"
+		if aon {
+			reason = "one or more document was rejected, so none were saved"
+		}
 		err = &chttp.HTTPError{
 			Code:   kivik.StatusExpectationFailed,
-			Reason: "one or more document was rejected",
+			Reason: reason,
 		}
 	default:
 		if resp.StatusCode < 400 {
@@ -105,5 +240,6 @@ func (d *db) BulkDocs(ctx context.Context, docs []interface{}, options map[strin
 	if bulkErr != nil {
 		return nil, bulkErr
 	}
+	results.rejected = aon && resp.StatusCode == kivik.StatusExpectationFailed
 	return results, err
 }
@@ -128,6 +128,77 @@ func TestBulkDocs(t *testing.T) {
 			status:  kivik.StatusBadRequest,
 			err:     "kivik: option 'X-Couch-Full-Commit' must be bool, not int",
 		},
+		{
+			name: "document too large",
+			db: func() *db {
+				d := newTestDB(nil, errors.New("should not be sent"))
+				d.Client.MaxRequestSize = 20
+				return d
+			}(),
+			docs:   []interface{}{map[string]string{"_id": "foo", "value": "0123456789"}},
+			status: kivik.StatusRequestEntityTooLarge,
+			err:    "kivik: document 0 of 34 bytes exceeds maximum request size of 20 bytes",
+		},
+		{
+			name: "all or nothing",
+			db: func() *db {
+				d := newCustomDB(func(req *http.Request) (*http.Response, error) {
+					defer req.Body.Close() // nolint: errcheck
+					var body struct {
+						AllOrNothing bool `json:"all_or_nothing"`
+					}
+					if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+						return nil, err
+					}
+					if !body.AllOrNothing {
+						return nil, errors.New("`all_or_nothing` not set")
+					}
+					return &http.Response{
+						StatusCode: kivik.StatusExpectationFailed,
+						Body:       Body(`[{"id":"foo","error":"forbidden","reason":"invalid"}]`),
+					}, nil
+				})
+				d.client.Compat = CompatCouch16
+				return d
+			}(),
+			options: map[string]interface{}{OptionAllOrNothing: true},
+			docs:    []interface{}{map[string]string{"_id": "foo"}},
+			status:  kivik.StatusExpectationFailed,
+			err:     "Expectation Failed: one or more document was rejected, so none were saved",
+		},
+		{
+			name: "all or nothing 2.x",
+			db: func() *db {
+				d := newTestDB(nil, errors.New("should not be sent"))
+				d.client.Compat = CompatCouch20
+				return d
+			}(),
+			options: map[string]interface{}{OptionAllOrNothing: true},
+			status:  kivik.StatusNotImplemented,
+			err:     "kivik: all_or_nothing not supported by CouchDB 2.0.0 and later",
+		},
+		{
+			name:    "invalid all or nothing type",
+			db:      &db{},
+			options: map[string]interface{}{OptionAllOrNothing: "yes"},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: option 'all_or_nothing' must be bool, not string",
+		},
+		{
+			name: "all or nothing too large",
+			db: func() *db {
+				d := newTestDB(nil, errors.New("should not be sent"))
+				d.Client.MaxRequestSize = 100
+				return d
+			}(),
+			options: map[string]interface{}{OptionAllOrNothing: true},
+			docs: []interface{}{
+				map[string]string{"_id": "foo", "value": "0123456789"},
+				map[string]string{"_id": "bar", "value": "0123456789"},
+			},
+			status: kivik.StatusRequestEntityTooLarge,
+			err:    "kivik: all_or_nothing update of 2 documents exceeds maximum request size of 100 bytes",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -223,6 +294,35 @@ func TestBulkNext(t *testing.T) {
 				Error: errors.Status(kivik.StatusUnknownError, "foo is erroneous"),
 			},
 		},
+		{
+			name: "forbidden",
+			results: func() *bulkResults {
+				r, err := newBulkResults(Body(`[{"id":"foo","error":"forbidden","reason":"invalid"}]`))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return r
+			}(),
+			expected: &driver.BulkResult{
+				ID:    "foo",
+				Error: errors.Status(kivik.StatusForbidden, "invalid"),
+			},
+		},
+		{
+			name: "all or nothing rejected",
+			results: func() *bulkResults {
+				r, err := newBulkResults(Body(`[{"id":"foo","rev":"1-xxx"}]`))
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.rejected = true
+				return r
+			}(),
+			expected: &driver.BulkResult{
+				ID:    "foo",
+				Error: errors.Status(kivik.StatusExpectationFailed, "kivik: not saved, as another document was rejected"),
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -261,3 +361,59 @@ func TestBulkClose(t *testing.T) {
 		t.Errorf("Failed to close")
 	}
 }
+
+func TestBulkDocsBatches(t *testing.T) {
+	var requests int
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		defer req.Body.Close() // nolint: errcheck
+		var body struct {
+			Docs []struct {
+				ID string `json:"_id"`
+			} `json:"docs"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		requests++
+		results := make([]string, len(body.Docs))
+		for i, doc := range body.Docs {
+			results[i] = `{"ok":true,"id":"` + doc.ID + `","rev":"1-xxx"}`
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusCreated,
+			Body:       ioutil.NopCloser(strings.NewReader("[" + strings.Join(results, ",") + "]")),
+		}, nil
+	})
+	db.Client.MaxRequestSize = 50
+	docs := []interface{}{
+		map[string]string{"_id": "a"},
+		map[string]string{"_id": "b"},
+		map[string]string{"_id": "c"},
+		map[string]string{"_id": "d"},
+	}
+	results, err := db.BulkDocs(context.Background(), docs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for {
+		var result driver.BulkResult
+		err := results.Next(&result)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, result.ID)
+	}
+	if err := results.Close(); err != nil {
+		t.Error(err)
+	}
+	if d := diff.Interface([]string{"a", "b", "c", "d"}, ids); d != nil {
+		t.Error(d)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
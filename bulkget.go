@@ -0,0 +1,143 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+var _ driver.BulkGetter = &db{}
+
+// BulkGet fetches the documents referenced by docs with a single request to
+// _bulk_get, with options, such as revs and attachments, as its query
+// parameters. A row is returned for each revision of each document fetched,
+// as the results are read from the response. Documents which could not be
+// fetched are reported by the Error of their rows. BulkGet requires CouchDB
+// 2.0 or later; see GetMany for a fallback for older servers.
+func (d *db) BulkGet(ctx context.Context, docs []driver.BulkGetReference, options map[string]interface{}) (driver.Rows, error) {
+	if len(docs) == 0 {
+		return nil, missingArg("docs")
+	}
+	return d.postBulkGet(ctx, docs, options)
+}
+
+// postBulkGet sends a _bulk_get request for docs, returning an iterator over
+// the response.
+func (d *db) postBulkGet(ctx context.Context, docs []driver.BulkGetReference, options map[string]interface{}) (*bulkGetRows, error) {
+	query, err := optionsToParams(options)
+	if err != nil {
+		return nil, err
+	}
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(map[string]interface{}{"docs": docs}),
+	}
+	resp, err := d.Client.DoReq(ctx, kivik.MethodPost, d.path("_bulk_get", query), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		return nil, err
+	}
+	return &bulkGetRows{body: resp.Body}, nil
+}
+
+// bulkGetResult is the result for a single document in a _bulk_get response.
+type bulkGetResult struct {
+	ID   string `json:"id"`
+	Docs []struct {
+		OK    json.RawMessage `json:"ok"`
+		Error *struct {
+			Error  string `json:"error"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"docs"`
+}
+
+// bulkGetRows is an iterator over a _bulk_get response, which decodes the
+// results for one document at a time.
+type bulkGetRows struct {
+	body    io.ReadCloser
+	dec     *json.Decoder
+	current *bulkGetResult
+	closed  bool
+}
+
+var _ driver.Rows = &bulkGetRows{}
+
+func (r *bulkGetRows) Next(row *driver.Row) error {
+	for r.current == nil || len(r.current.Docs) == 0 {
+		result, err := r.nextResult()
+		if err != nil {
+			return err
+		}
+		r.current = result
+	}
+	doc := r.current.Docs[0]
+	r.current.Docs = r.current.Docs[1:]
+	*row = driver.Row{ID: r.current.ID, Doc: doc.OK}
+	if doc.Error != nil {
+		row.Error = rowError(doc.Error.Error, doc.Error.Reason)
+	}
+	return nil
+}
+
+// nextResult decodes the results for the next document in the response. It
+// returns io.EOF once all have been read.
+func (r *bulkGetRows) nextResult() (*bulkGetResult, error) {
+	if r.closed {
+		return nil, io.EOF
+	}
+	if r.dec == nil {
+		r.dec = json.NewDecoder(r.body)
+		if err := r.begin(); err != nil {
+			r.closed = true
+			return nil, err
+		}
+	}
+	if !r.dec.More() {
+		r.closed = true
+		if err := consumeDelim(r.dec, json.Delim(']')); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	result := &bulkGetResult{}
+	if err := r.dec.Decode(result); err != nil {
+		r.closed = true
+		return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+	}
+	return result, nil
+}
+
+// begin consumes the response up to the start of the results.
+func (r *bulkGetRows) begin() error {
+	if err := consumeDelim(r.dec, json.Delim('{')); err != nil {
+		return err
+	}
+	for {
+		t, err := r.dec.Token()
+		if err != nil {
+			return errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		if t == "results" {
+			return consumeDelim(r.dec, json.Delim('['))
+		}
+		var ignored json.RawMessage
+		if err := r.dec.Decode(&ignored); err != nil {
+			return errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+	}
+}
+
+func (r *bulkGetRows) Close() error {
+	return r.body.Close()
+}
+
+func (r *bulkGetRows) UpdateSeq() string { return "" }
+func (r *bulkGetRows) Offset() int64     { return 0 }
+func (r *bulkGetRows) TotalRows() int64  { return 0 }
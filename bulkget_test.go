@@ -0,0 +1,105 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestBulkGet(t *testing.T) {
+	type row struct {
+		ID  string
+		Doc string
+		Err string
+	}
+	tests := []struct {
+		name     string
+		docs     []driver.BulkGetReference
+		options  map[string]interface{}
+		response string
+		body     string
+		rawQuery string
+		expected []row
+		status   int
+		err      string
+	}{
+		{
+			name:   "no docs",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: docs required",
+		},
+		{
+			name:    "invalid options",
+			docs:    []driver.BulkGetReference{{ID: "foo"}},
+			options: map[string]interface{}{"foo": make(chan int)},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: invalid type chan int for options",
+		},
+		{
+			name:    "success",
+			docs:    []driver.BulkGetReference{{ID: "foo", Rev: "1-xxx"}, {ID: "bar"}, {ID: "baz"}},
+			options: map[string]interface{}{"revs": true},
+			response: `{"results":[
+				{"id":"foo","docs":[{"ok":{"_id":"foo","_rev":"1-xxx"}}]},
+				{"id":"bar","docs":[{"ok":{"_id":"bar","_rev":"2-aaa"}},{"ok":{"_id":"bar","_rev":"2-bbb"}}]},
+				{"id":"baz","docs":[{"error":{"id":"baz","rev":"undefined","error":"not_found","reason":"missing"}}]}
+			]}`,
+			body:     `{"docs":[{"id":"foo","rev":"1-xxx"},{"id":"bar"},{"id":"baz"}]}`,
+			rawQuery: "revs=true",
+			expected: []row{
+				{ID: "foo", Doc: `{"_id":"foo","_rev":"1-xxx"}`},
+				{ID: "bar", Doc: `{"_id":"bar","_rev":"2-aaa"}`},
+				{ID: "bar", Doc: `{"_id":"bar","_rev":"2-bbb"}`},
+				{ID: "baz", Err: "missing"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/testdb/_bulk_get" {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				if req.URL.RawQuery != test.rawQuery {
+					t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+				}
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				if d := diff.JSON([]byte(test.body), body); d != nil {
+					t.Error(d)
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.response)}, nil
+			})
+			rows, err := db.BulkGet(context.Background(), test.docs, test.options)
+			testy.StatusError(t, test.err, test.status, err)
+			defer rows.Close() // nolint: errcheck
+			var result []row
+			for {
+				var r driver.Row
+				if err := rows.Next(&r); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				res := row{ID: r.ID, Doc: string(r.Doc)}
+				if r.Error != nil {
+					res.Err = r.Error.Error()
+				}
+				result = append(result, res)
+			}
+			if d := diff.Interface(test.expected, result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
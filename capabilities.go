@@ -0,0 +1,76 @@
+package couchdb
+
+import (
+	"context"
+)
+
+// Capabilities describes the optional features supported by a server, so that
+// applications can adapt to it without parsing version strings.
+type Capabilities struct {
+	// Features lists the feature flags reported by the server's welcome
+	// message, including any not otherwise described here.
+	Features []string
+
+	// Find reports support for Mango queries with _find (CouchDB 2.0 and
+	// later).
+	Find bool
+	// Scheduler reports support for the _scheduler endpoints (CouchDB 2.1 and
+	// later).
+	Scheduler bool
+	// Partitioned reports support for partitioned databases (CouchDB 3.0 and
+	// later).
+	Partitioned bool
+	// Reshard reports support for shard splitting with _reshard (CouchDB 3.0
+	// and later).
+	Reshard bool
+	// PluggableStorageEngines reports support for choosing a database's
+	// storage engine.
+	PluggableStorageEngines bool
+	// Search reports that full-text search with Clouseau is available.
+	Search bool
+	// Nouveau reports that full-text search with Nouveau is available.
+	Nouveau bool
+	// AccessReady reports that the server is prepared for per-document
+	// access control.
+	AccessReady bool
+	// Access reports that the server was built with per-document access
+	// control, so that databases may be created with OptionAccess.
+	Access bool
+}
+
+// Has returns true if the server reported the named feature flag.
+func (c *Capabilities) Has(feature string) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the optional features supported by the server, as
+// reported in the features list of its welcome message, supplemented by
+// probing the server for features which older versions support without
+// reporting.
+func (c *client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	version, err := c.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	caps := &Capabilities{Features: version.Features}
+	caps.Partitioned = caps.Has("partitioned")
+	caps.Reshard = caps.Has("reshard")
+	caps.PluggableStorageEngines = caps.Has("pluggable-storage-engines")
+	caps.Search = caps.Has("search")
+	caps.Nouveau = caps.Has("nouveau")
+	caps.AccessReady = caps.Has("access-ready")
+	caps.Access = caps.Has("access")
+	caps.Find = !c.noFind && c.Compat != CompatCouch16
+	caps.Scheduler = caps.Has("scheduler")
+	if !caps.Scheduler {
+		if caps.Scheduler, err = c.schedulerSupported(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return caps, nil
+}
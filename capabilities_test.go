@@ -0,0 +1,92 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		welcome  string
+		compat   CompatMode
+		jobs     int
+		expected *Capabilities
+		status   int
+		err      string
+	}{
+		{
+			name:     "CouchDB 1.6",
+			welcome:  `{"couchdb":"Welcome","version":"1.6.1"}`,
+			compat:   CompatCouch16,
+			jobs:     kivik.StatusBadRequest,
+			expected: &Capabilities{},
+		},
+		{
+			name:     "CouchDB 2.1, probed",
+			welcome:  `{"couchdb":"Welcome","version":"2.1.1"}`,
+			compat:   CompatCouch20,
+			jobs:     kivik.StatusOK,
+			expected: &Capabilities{Find: true, Scheduler: true},
+		},
+		{
+			name:    "CouchDB 3.x, reported",
+			welcome: `{"couchdb":"Welcome","version":"3.3.2","features":["access-ready","partitioned","pluggable-storage-engines","reshard","scheduler","search","x-custom"]}`,
+			expected: &Capabilities{
+				Features:                []string{"access-ready", "partitioned", "pluggable-storage-engines", "reshard", "scheduler", "search", "x-custom"},
+				Find:                    true,
+				Scheduler:               true,
+				Partitioned:             true,
+				Reshard:                 true,
+				PluggableStorageEngines: true,
+				Search:                  true,
+				AccessReady:             true,
+			},
+		},
+		{
+			name:    "access build",
+			welcome: `{"couchdb":"Welcome","version":"3.4.0","features":["access","access-ready","scheduler"]}`,
+			expected: &Capabilities{
+				Features:    []string{"access", "access-ready", "scheduler"},
+				Find:        true,
+				Scheduler:   true,
+				AccessReady: true,
+				Access:      true,
+			},
+		},
+		{
+			name:   "error",
+			status: kivik.StatusInternalServerError,
+			err:    "Internal Server Error",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				switch {
+				case req.URL.Path == "/" && test.welcome != "":
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Header:     http.Header{"Content-Type": {"application/json"}},
+						Body:       Body(test.welcome),
+					}, nil
+				case req.URL.Path == "/_scheduler/jobs" && test.jobs != 0:
+					return &http.Response{StatusCode: test.jobs, Body: Body("")}, nil
+				}
+				return &http.Response{StatusCode: kivik.StatusInternalServerError, Request: req, Body: Body("")}, nil
+			})
+			c.Compat = test.compat
+			caps, err := c.Capabilities(context.Background())
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, caps); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
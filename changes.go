@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
@@ -11,30 +13,92 @@ import (
 	"github.com/go-kivik/kivik/errors"
 )
 
-// Changes returns the changes stream for the database.
+// Changes returns the changes stream for the database. By default, the feed
+// is continuous, with each change decoded as it arrives until the feed is
+// closed. With feed=longpoll, the server waits for at least one change before
+// responding, and with feed=normal it responds immediately, with the changes
+// since the start of the database unless since is given; in both cases the
+// changes are decoded incrementally from the results array, and the returned
+// driver.Changes also provides LastSeq and Pending once Next returns io.EOF.
 func (d *db) Changes(ctx context.Context, opts map[string]interface{}) (driver.Changes, error) {
 	overrideOpts := map[string]interface{}{
-		"feed":      "continuous",
-		"since":     "now",
-		"heartbeat": 6000,
+		"feed": "continuous",
+	}
+	// Defaults, which may be overridden by the caller
+	defaults := map[string]interface{}{"since": "now", "heartbeat": 6000}
+	var longpoll bool
+	if feed, ok := opts["feed"]; ok {
+		switch feed {
+		case "continuous":
+		case "normal":
+			// A normal feed reports only the changes made before the request,
+			// so starts from the beginning, as the server does by default.
+			delete(defaults, "since")
+			fallthrough
+		case "longpoll":
+			longpoll = true
+			delete(overrideOpts, "feed")
+		default:
+			return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: unsupported feed type: %v", feed)
+		}
+	}
+	for key, value := range defaults {
+		if _, ok := opts[key]; !ok {
+			overrideOpts[key] = value
+		}
+	}
+	// Validated and removed from opts by withTimeout.
+	timeout, _ := opts[OptionRequestTimeout].(time.Duration)
+	ctx, cancel, err := withTimeout(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := opts["timeout"]; timeout > 0 && !ok {
+		// Ask the server to end the feed cleanly, before the client gives up
+		overrideOpts["timeout"] = int(timeout / time.Millisecond)
 	}
 	options, err := optionsToParams(opts, overrideOpts)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	if d.client.feeds.isClosed() || d.feeds.isClosed() {
+		cancel()
+		return nil, errClosed
+	}
 	resp, err := d.Client.DoReq(ctx, kivik.MethodGet, d.path("_changes", options), nil)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	if err = chttp.ResponseError(resp); err != nil {
+		cancel()
 		return nil, err
 	}
-	return newChangesRows(resp.Body), nil
+	body, err := d.client.feeds.track(&cancelBody{ReadCloser: resp.Body, cancel: cancel})
+	if err != nil {
+		return nil, err
+	}
+	if body, err = d.feeds.track(body); err != nil {
+		return nil, err
+	}
+	rows := newChangesRows(body)
+	rows.longpoll = longpoll
+	return rows, nil
 }
 
 type changesRows struct {
 	body io.ReadCloser
 	dec  *json.Decoder
+	// longpoll is set for longpoll and normal feeds, whose response is a
+	// single object with a results array, rather than one change per line.
+	longpoll bool
+	// started is set once the results array has been entered, and done once
+	// it has been read to the end.
+	started bool
+	done    bool
+	lastSeq string
+	pending int64
 }
 
 func newChangesRows(r io.ReadCloser) *changesRows {
@@ -53,9 +117,93 @@ func (r *changesRows) Next(row *driver.Change) error {
 	if r.dec == nil {
 		r.dec = json.NewDecoder(r.body)
 	}
+	if r.longpoll {
+		return r.nextResult(row)
+	}
 	if !r.dec.More() {
 		return io.EOF
 	}
 
 	return errors.WrapStatus(kivik.StatusBadResponse, r.dec.Decode(row))
 }
+
+// LastSeq returns the last_seq reported at the end of a longpoll or normal
+// feed. It is valid once Next has returned io.EOF.
+func (r *changesRows) LastSeq() string {
+	return r.lastSeq
+}
+
+// Pending returns the number of changes remaining after those returned, as
+// reported at the end of a longpoll or normal feed by CouchDB 2.0 and later.
+// It is valid once Next has returned io.EOF.
+func (r *changesRows) Pending() int64 {
+	return r.pending
+}
+
+// nextResult returns the next change from the results array of a longpoll or
+// normal feed.
+func (r *changesRows) nextResult(row *driver.Change) error {
+	if r.done {
+		return io.EOF
+	}
+	if !r.started {
+		r.started = true
+		if err := consumeDelim(r.dec, json.Delim('{')); err != nil {
+			return err
+		}
+		found, err := r.readMeta()
+		if err != nil {
+			return err
+		}
+		if !found {
+			r.done = true
+			return io.EOF
+		}
+	}
+	if r.dec.More() {
+		return errors.WrapStatus(kivik.StatusBadResponse, r.dec.Decode(row))
+	}
+	r.done = true
+	if err := consumeDelim(r.dec, json.Delim(']')); err != nil {
+		return err
+	}
+	if _, err := r.readMeta(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// readMeta reads the fields of a longpoll or normal feed's response object
+// until the start of the results array, returning true, or the end of the
+// object, returning false.
+func (r *changesRows) readMeta() (bool, error) {
+	for {
+		t, err := r.dec.Token()
+		if err != nil {
+			return false, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		if t == json.Delim('}') {
+			return false, nil
+		}
+		key, ok := t.(string)
+		if !ok {
+			return false, errors.Statusf(kivik.StatusBadResponse, "Unexpected token: (%T) %v", t, t)
+		}
+		switch key {
+		case "results":
+			return true, consumeDelim(r.dec, json.Delim('['))
+		case "last_seq":
+			var seq json.RawMessage
+			err = r.dec.Decode(&seq)
+			r.lastSeq = strings.Trim(string(seq), `"`)
+		case "pending":
+			err = r.dec.Decode(&r.pending)
+		default:
+			var discard json.RawMessage
+			err = r.dec.Decode(&discard)
+		}
+		if err != nil {
+			return false, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package couchdb
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/flimzy/diff"
 	"github.com/flimzy/testy"
@@ -57,6 +60,54 @@ func TestChanges(t *testing.T) {
                     `),
 			}, nil),
 		},
+		{
+			name:    "request timeout",
+			options: map[string]interface{}{OptionRequestTimeout: 1500 * time.Millisecond},
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if timeout := req.URL.Query().Get("timeout"); timeout != "1500" {
+					return nil, fmt.Errorf("Unexpected timeout: %s", timeout)
+				}
+				if _, ok := req.Context().Deadline(); !ok {
+					return nil, errors.New("No deadline set")
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(""),
+				}, nil
+			}),
+		},
+		{
+			name:    "longpoll",
+			options: map[string]interface{}{"feed": "longpoll"},
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if feed := req.URL.Query().Get("feed"); feed != "longpoll" {
+					return nil, fmt.Errorf("Unexpected feed: %s", feed)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"results":[],"last_seq":"1-xxx","pending":0}`),
+				}, nil
+			}),
+		},
+		{
+			name:    "normal",
+			options: map[string]interface{}{"feed": "normal"},
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if query := req.URL.Query(); query.Get("feed") != "normal" || query["since"] != nil {
+					return nil, fmt.Errorf("Unexpected query: %s", req.URL.RawQuery)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"results":[],"last_seq":"1-xxx","pending":0}`),
+				}, nil
+			}),
+		},
+		{
+			name:    "unsupported feed",
+			options: map[string]interface{}{"feed": "eventsource"},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: unsupported feed type: eventsource",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -116,6 +167,75 @@ func TestChangesNext(t *testing.T) {
 	}
 }
 
+func TestChangesLongpoll(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+		lastSeq  string
+		pending  int64
+		status   int
+		err      string
+	}{
+		{
+			name: "2.x",
+			body: `{"results":[
+{"seq":"1-xxx","id":"foo","changes":[{"rev":"1-aaa"}]},
+{"seq":"2-xxx","id":"bar","changes":[{"rev":"2-bbb"}],"deleted":true}
+],
+"last_seq":"2-xxx","pending":5}`,
+			expected: []string{"foo 1-xxx", "bar 2-xxx"},
+			lastSeq:  "2-xxx",
+			pending:  5,
+		},
+		{
+			name:     "numeric last seq",
+			body:     `{"results":[{"seq":"7","id":"foo","changes":[{"rev":"1-aaa"}]}],"last_seq":7}`,
+			expected: []string{"foo 7"},
+			lastSeq:  "7",
+		},
+		{
+			name:    "no results",
+			body:    `{"last_seq":3}`,
+			lastSeq: "3",
+		},
+		{
+			name:     "invalid last seq",
+			body:     `{"results":[{"seq":"7","id":"foo","changes":[{"rev":"1-aaa"}]}],"last_seq":}`,
+			expected: []string{"foo 7"},
+			status:   kivik.StatusBadResponse,
+			err:      "invalid character '}' looking for beginning of value",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed := &changesRows{body: Body(test.body), longpoll: true}
+			var changes []string
+			var err error
+			for {
+				var change driver.Change
+				if err = feed.Next(&change); err != nil {
+					break
+				}
+				changes = append(changes, change.ID+" "+change.Seq)
+			}
+			if d := diff.Interface(test.expected, changes); d != nil {
+				t.Error(d)
+			}
+			if feed.LastSeq() != test.lastSeq {
+				t.Errorf("Unexpected last seq: %s", feed.LastSeq())
+			}
+			if feed.Pending() != test.pending {
+				t.Errorf("Unexpected pending: %d", feed.Pending())
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
 func TestChangesClose(t *testing.T) {
 	body := &closeTracker{ReadCloser: Body("foo")}
 	feed := &changesRows{body: body}
@@ -1,11 +1,14 @@
 package chttp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"strings"
+	"sync"
 
 	"golang.org/x/net/publicsuffix"
 
@@ -77,12 +80,19 @@ func (a *BasicAuth) Authenticate(ctx context.Context, c *Client) error {
 // CookieAuth provides CouchDB Cookie auth services as described at
 // http://docs.couchdb.org/en/2.0.0/api/server/authn.html#cookie-authentication
 //
+// When the server rejects a request with a 401 status, as when the session
+// has expired, CookieAuth logs in again, and retries the request once.
+//
 // CookieAuth stores authentication state after use, so should not be re-used.
 type CookieAuth struct {
 	Username string `json:"name"`
 	Password string `json:"password"`
 
+	// transport stores the original transport that is overridden by this auth
+	// mechanism
 	transport http.RoundTripper
+	// renewMU serializes session renewals.
+	renewMU sync.Mutex
 	// Set to true if the authenticator created the cookie jar; It will then
 	// also destroy it on logout.
 	setJar bool
@@ -105,7 +115,90 @@ func (a *CookieAuth) Authenticate(ctx context.Context, c *Client) error {
 	if _, err := c.DoError(ctx, kivik.MethodPost, "/_session", opts); err != nil {
 		return err
 	}
-	return ValidateAuth(ctx, a.Username, c)
+	if err := ValidateAuth(ctx, a.Username, c); err != nil {
+		return err
+	}
+	a.transport = c.Transport
+	c.Transport = a
+	return nil
+}
+
+// RoundTrip fulfills the http.RoundTripper interface. If the server responds
+// to a request with a 401 status, it renews the session, and retries the
+// request once with the new session cookie. Requests to /_session, and those
+// whose body cannot be replayed, are not retried.
+func (a *CookieAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := a.roundTripper()
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if strings.Trim(req.URL.Path, "/") == "_session" || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	if renewErr := a.renew(req); renewErr != nil {
+		return resp, nil
+	}
+	retry := req.WithContext(req.Context())
+	retry.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		retry.Header[k] = v
+	}
+	retry.Header.Del("Cookie")
+	if cookie, ok := a.Cookie(); ok {
+		retry.AddCookie(cookie)
+	}
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_ = resp.Body.Close()
+	return transport.RoundTrip(retry)
+}
+
+func (a *CookieAuth) roundTripper() http.RoundTripper {
+	if a.transport == nil {
+		return http.DefaultTransport
+	}
+	return a.transport
+}
+
+// renew logs in again, on behalf of req, storing the new session cookie in the
+// cookie jar.
+func (a *CookieAuth) renew(req *http.Request) error {
+	a.renewMU.Lock()
+	defer a.renewMU.Unlock()
+	// Another request may already have renewed the session.
+	if cookie, ok := a.Cookie(); ok {
+		if sent, err := req.Cookie(kivik.SessionCookieName); err == nil && sent.Value != cookie.Value {
+			return nil
+		}
+	}
+	u := *a.dsn
+	u.Path = "/_session"
+	u.RawQuery = ""
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	login, err := http.NewRequest(kivik.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	login = login.WithContext(req.Context())
+	login.Header.Set("Content-Type", typeJSON)
+	login.Header.Set("Accept", typeJSON)
+	resp, err := a.roundTripper().RoundTrip(login)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if err := ResponseError(resp); err != nil {
+		return err
+	}
+	a.jar.SetCookies(&u, resp.Cookies())
+	return nil
 }
 
 // Cookie returns the current session cookie and true, if found, or nil and
@@ -3,12 +3,14 @@ package chttp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/flimzy/diff"
@@ -376,3 +378,82 @@ func TestCookie(t *testing.T) {
 		})
 	}
 }
+
+// sessionServer is a CouchDB stand-in which accepts only the most recently
+// issued session cookie.
+type sessionServer struct {
+	mu       sync.Mutex
+	session  int
+	logins   int
+	rejectPW bool
+	bodies   []string
+}
+
+func (s *sessionServer) expire() {
+	s.mu.Lock()
+	s.session++
+	s.mu.Unlock()
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	valid := fmt.Sprintf("session-%d", s.session)
+	if r.URL.Path == "/_session" && r.Method == kivik.MethodPost {
+		s.logins++
+		if s.rejectPW {
+			w.WriteHeader(kivik.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: kivik.SessionCookieName, Value: valid, Path: "/"})
+		_, _ = w.Write([]byte(`{"ok":true}`))
+		return
+	}
+	if cookie, err := r.Cookie(kivik.SessionCookieName); err != nil || cookie.Value != valid {
+		w.Header().Set("Content-Type", typeJSON)
+		w.WriteHeader(kivik.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"unauthorized","reason":"You are not authorized to access this db."}`))
+		return
+	}
+	if r.URL.Path == "/_session" {
+		_, _ = w.Write([]byte(`{"userCtx":{"name":"foo"}}`))
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(body))
+	_, _ = w.Write([]byte(`{"ok":true}`))
+}
+
+func TestCookieAuthRenewal(t *testing.T) {
+	server := &sessionServer{}
+	s := httptest.NewServer(server)
+	defer s.Close()
+	c, err := New(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Auth(context.Background(), &CookieAuth{Username: "foo", Password: "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	server.expire()
+	opts := &Options{}
+	opts.SetBody([]byte(`{"foo":"bar"}`))
+	if _, err := c.DoError(context.Background(), kivik.MethodPut, "/db/doc", opts); err != nil {
+		t.Fatal(err)
+	}
+	if server.logins != 2 {
+		t.Errorf("Expected 2 logins, got %d", server.logins)
+	}
+	if d := diff.Interface([]string{`{"foo":"bar"}`}, server.bodies); d != nil {
+		t.Error(d)
+	}
+	t.Run("renewal fails", func(t *testing.T) {
+		server.expire()
+		server.rejectPW = true
+		_, err := c.DoError(context.Background(), kivik.MethodGet, "/db/doc", nil)
+		if server.logins != 3 {
+			t.Errorf("Expected 3 logins, got %d", server.logins)
+		}
+		testy.StatusError(t, "Unauthorized: You are not authorized to access this db.", kivik.StatusUnauthorized, err)
+	})
+}
@@ -0,0 +1,289 @@
+package chttp
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// Balancer is an http.RoundTripper which distributes requests across the
+// nodes of a cluster. Reads are sent to each node in turn. Writes are pinned
+// to a single node for each session, as set with WithSession, so that a
+// session reads its own writes despite internal replication lag between the
+// nodes, and so that its writes are less likely to conflict. Requests made
+// without a session belong to a single, default session.
+//
+// A node which cannot be connected to is avoided for DownTime, and the
+// request is sent to the next node instead. With a HedgeDelay, reads are also
+// sent to the next node when connecting to the first is slow.
+type Balancer struct {
+	// Transport is the underlying transport. It defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// DownTime is how long a node which could not be connected to is
+	// avoided. It defaults to 30 seconds.
+	DownTime time.Duration
+
+	// HedgeDelay, if greater than zero, is how long a read waits for a
+	// connection to its node before the read is also sent to the next node.
+	// The first successful response is used, and the other request is
+	// cancelled. As the request may reach both nodes, writes are never
+	// hedged.
+	HedgeDelay time.Duration
+
+	// Clock, if set, is the source of the time, to expire DownTime and
+	// HedgeDelay. It defaults to the SystemClock.
+	Clock Clock
+
+	nodes []*url.URL
+	next  uint32
+
+	mu        sync.Mutex
+	downUntil []time.Time
+}
+
+var _ http.RoundTripper = &Balancer{}
+
+// NewBalancer returns a Balancer which sends requests to nodes, the URLs of
+// the nodes of a cluster, of which only the scheme and host are used.
+func NewBalancer(transport http.RoundTripper, nodes ...string) (*Balancer, error) {
+	if len(nodes) == 0 {
+		return nil, errors.Status(kivik.StatusBadRequest, "chttp: no nodes to balance")
+	}
+	b := &Balancer{
+		Transport: transport,
+		nodes:     make([]*url.URL, len(nodes)),
+		downUntil: make([]time.Time, len(nodes)),
+	}
+	for i, node := range nodes {
+		u, err := parseDSN(node)
+		if err != nil {
+			return nil, err
+		}
+		b.nodes[i] = u
+	}
+	return b, nil
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context which causes writes made with it to be pinned
+// to the same node as other writes of session, when balanced by a Balancer.
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// Session returns the session attached to ctx by WithSession, or an empty
+// string.
+func Session(ctx context.Context) string {
+	session, _ := ctx.Value(sessionKey{}).(string)
+	return session
+}
+
+// RoundTrip fulfills the http.RoundTripper interface.
+func (b *Balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	write := mutating(req.Method, req.URL.Path)
+	var start int
+	if write {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(Session(req.Context())))
+		start = int(h.Sum32() % uint32(len(b.nodes)))
+	} else {
+		start = int((atomic.AddUint32(&b.next, 1) - 1) % uint32(len(b.nodes)))
+	}
+	order := b.order(start)
+	replayable := req.Body == nil || req.GetBody != nil
+	if !write && b.HedgeDelay > 0 && len(order) > 1 && replayable {
+		return b.hedge(req, order)
+	}
+	var err error
+	for i, node := range order {
+		if i > 0 && !replayable {
+			// The body cannot be sent again.
+			break
+		}
+		var r *http.Request
+		if r, err = b.request(req, node, i > 0); err != nil {
+			return nil, err
+		}
+		var resp *http.Response
+		resp, err = b.transport().RoundTrip(r)
+		if err == nil || !dialError(err) {
+			return resp, err
+		}
+		b.markDown(node)
+	}
+	return nil, err
+}
+
+// request returns a copy of req addressed to node, with a fresh copy of the
+// body if replay is true.
+func (b *Balancer) request(req *http.Request, node int, replay bool) (*http.Request, error) {
+	r := req.WithContext(req.Context())
+	u := *req.URL
+	u.Scheme, u.Host = b.nodes[node].Scheme, b.nodes[node].Host
+	r.URL = &u
+	r.Host = ""
+	if replay && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	attempt int
+	node    int
+}
+
+// hedge sends req to the nodes in order, starting the next attempt when the
+// current ones have not connected within HedgeDelay, or have all failed, and
+// returns the first successful response.
+func (b *Balancer) hedge(req *http.Request, order []int) (*http.Response, error) {
+	results := make(chan hedgeResult, len(order))
+	connected := make(chan struct{}, 1)
+	var cancels []context.CancelFunc
+	launch := func() error {
+		attempt, node := len(cancels), order[len(cancels)]
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GotConn: func(_ httptrace.GotConnInfo) {
+				select {
+				case connected <- struct{}{}:
+				default:
+				}
+			},
+		})
+		r, err := b.request(req.WithContext(ctx), node, attempt > 0)
+		if err != nil {
+			cancel()
+			return err
+		}
+		go func() {
+			resp, err := b.transport().RoundTrip(r)
+			results <- hedgeResult{resp: resp, err: err, attempt: attempt, node: node}
+		}()
+		return nil
+	}
+	if err := launch(); err != nil {
+		return nil, err
+	}
+	pending := 1
+	delay := b.clock().After(b.HedgeDelay)
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-connected:
+			delay = nil
+		case <-delay:
+			delay = nil
+			if len(cancels) < len(order) {
+				if err := launch(); err != nil {
+					lastErr = err
+				} else {
+					pending++
+				}
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				for i, cancel := range cancels {
+					if i != res.attempt {
+						cancel()
+					}
+				}
+				go func(n int) {
+					for ; n > 0; n-- {
+						if res := <-results; res.err == nil {
+							_ = res.resp.Body.Close()
+						}
+					}
+				}(pending)
+				res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, cancel: cancels[res.attempt]}
+				return res.resp, nil
+			}
+			cancels[res.attempt]()
+			if dialError(res.err) {
+				b.markDown(res.node)
+			}
+			lastErr = res.err
+			if pending == 0 && len(cancels) < len(order) {
+				if err := launch(); err != nil {
+					return nil, err
+				}
+				pending++
+			}
+		}
+	}
+	return nil, lastErr
+}
+
+func (b *Balancer) transport() http.RoundTripper {
+	if b.Transport == nil {
+		return http.DefaultTransport
+	}
+	return b.Transport
+}
+
+func (b *Balancer) clock() Clock {
+	if b.Clock == nil {
+		return SystemClock
+	}
+	return b.Clock
+}
+
+// order returns the indexes of the nodes, beginning at start, with the nodes
+// which are up before those which are down.
+func (b *Balancer) order(start int) []int {
+	now := b.clock().Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	up := make([]int, 0, len(b.nodes))
+	var down []int
+	for i := range b.nodes {
+		node := (start + i) % len(b.nodes)
+		if now.Before(b.downUntil[node]) {
+			down = append(down, node)
+			continue
+		}
+		up = append(up, node)
+	}
+	return append(up, down...)
+}
+
+func (b *Balancer) markDown(node int) {
+	downTime := b.DownTime
+	if downTime <= 0 {
+		downTime = 30 * time.Second
+	}
+	until := b.clock().Now().Add(downTime)
+	b.mu.Lock()
+	b.downUntil[node] = until
+	b.mu.Unlock()
+}
+
+// dialError returns true if err occurred connecting to the server, before any
+// of the request was sent.
+func dialError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
@@ -0,0 +1,227 @@
+package chttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestNewBalancer(t *testing.T) {
+	_, err := NewBalancer(nil)
+	testy.StatusError(t, "chttp: no nodes to balance", kivik.StatusBadRequest, err)
+	_, err = NewBalancer(nil, "http://foo.com/", "/no/host")
+	testy.StatusError(t, "chttp: no host in DSN", kivik.StatusBadRequest, err)
+}
+
+// closedAddr returns the address of a port on which nothing is listening.
+func closedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	return addr
+}
+
+func TestBalancer(t *testing.T) {
+	var hits []string
+	var bodies []string
+	var nodes []string
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits = append(hits, name+" "+r.Method+" "+r.URL.Path)
+			body, _ := ioutil.ReadAll(r.Body)
+			if len(body) > 0 {
+				bodies = append(bodies, name+" "+string(body))
+			}
+			w.WriteHeader(kivik.StatusOK)
+		}))
+		defer s.Close()
+		nodes = append(nodes, s.URL)
+	}
+	down := "http://" + closedAddr(t)
+	b, err := NewBalancer(nil, nodes[0], down, nodes[1], nodes[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &stepClock{now: time.Now()}
+	b.Clock = clock
+	c, err := New(context.Background(), "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Transport = b
+	do := func(ctx context.Context, method, path, body string) {
+		var opts *Options
+		if body != "" {
+			opts = &Options{}
+			opts.SetBody([]byte(body))
+		}
+		if _, err := c.DoError(ctx, method, path, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reads are distributed round-robin, skipping the node which is down.
+	for i := 0; i < 4; i++ {
+		do(context.Background(), kivik.MethodGet, "/db/doc", "")
+	}
+	// Writes of each session go to the same node, or, for erin, whose node
+	// is down, the next one.
+	for i := 0; i < 2; i++ {
+		do(WithSession(context.Background(), "alice"), kivik.MethodPut, "/db/alice", `{"n":1}`)
+		do(WithSession(context.Background(), "bob"), kivik.MethodPut, "/db/bob", `{"n":2}`)
+		do(WithSession(context.Background(), "erin"), kivik.MethodPut, "/db/erin", `{"n":3}`)
+	}
+	expected := []string{
+		"a GET /db/doc",
+		"b GET /db/doc",
+		"b GET /db/doc",
+		"c GET /db/doc",
+		"c PUT /db/alice",
+		"a PUT /db/bob",
+		"b PUT /db/erin",
+		"c PUT /db/alice",
+		"a PUT /db/bob",
+		"b PUT /db/erin",
+	}
+	if d := diff.Interface(expected, hits); d != nil {
+		t.Error(d)
+	}
+	if d := diff.Interface([]string{`c {"n":1}`, `a {"n":2}`, `b {"n":3}`, `c {"n":1}`, `a {"n":2}`, `b {"n":3}`}, bodies); d != nil {
+		t.Error(d)
+	}
+	t.Run("node recovers", func(t *testing.T) {
+		clock.now = clock.now.Add(time.Minute)
+		if order := b.order(1); order[0] != 1 {
+			t.Errorf("Expected the node to be retried after DownTime, got %v", order)
+		}
+	})
+}
+
+func TestBalancerHedge(t *testing.T) {
+	type attempt struct {
+		host      string
+		cancelled bool
+	}
+	tests := []struct {
+		name     string
+		method   string
+		hosts    map[string]string
+		expected []attempt
+		body     string
+		status   int
+		err      string
+	}{
+		{
+			name:   "slow connection",
+			method: kivik.MethodGet,
+			hosts:  map[string]string{"a.com": "hang", "b.com": "ok"},
+			expected: []attempt{
+				{host: "a.com", cancelled: true},
+				{host: "b.com"},
+			},
+			body: "b.com",
+		},
+		{
+			name:   "first fails",
+			method: kivik.MethodGet,
+			hosts:  map[string]string{"a.com": "fail", "b.com": "fail", "c.com": "ok"},
+			expected: []attempt{
+				{host: "a.com"},
+				{host: "b.com"},
+				{host: "c.com"},
+			},
+			body: "c.com",
+		},
+		{
+			name:   "all fail",
+			method: kivik.MethodGet,
+			hosts:  map[string]string{"a.com": "fail", "b.com": "fail", "c.com": "fail"},
+			expected: []attempt{
+				{host: "a.com"},
+				{host: "b.com"},
+				{host: "c.com"},
+			},
+			status: kivik.StatusNetworkError,
+			err:    "connection reset",
+		},
+		{
+			name:     "writes not hedged",
+			method:   kivik.MethodPut,
+			hosts:    map[string]string{"c.com": "ok"},
+			expected: []attempt{{host: "c.com"}},
+			body:     "c.com",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var attempts []attempt
+			done := make(chan struct{}, 3)
+			transport := customTransport(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				i := len(attempts)
+				attempts = append(attempts, attempt{host: req.URL.Host})
+				mu.Unlock()
+				switch test.hosts[req.URL.Host] {
+				case "hang":
+					<-req.Context().Done()
+					mu.Lock()
+					attempts[i].cancelled = true
+					mu.Unlock()
+					done <- struct{}{}
+					return nil, req.Context().Err()
+				case "fail":
+					return nil, errors.New("connection reset")
+				}
+				if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+					trace.GotConn(httptrace.GotConnInfo{})
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(req.URL.Host), Request: req}, nil
+			})
+			b, err := NewBalancer(transport, "http://a.com/", "http://b.com/", "http://c.com/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.HedgeDelay = time.Second
+			b.Clock = &stepClock{}
+			c := newCustomClient(nil)
+			c.Transport = b
+			resp, err := c.DoReq(WithSession(context.Background(), "bob"), test.method, "/db/doc", nil)
+			var body []byte
+			if err == nil {
+				body, _ = ioutil.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+			}
+			if test.hosts["a.com"] == "hang" {
+				<-done
+			}
+			if string(body) != test.body {
+				t.Errorf("Unexpected body: %s", body)
+			}
+			mu.Lock()
+			// Hedged attempts run concurrently.
+			sort.Slice(attempts, func(i, j int) bool { return attempts[i].host < attempts[j].host })
+			if d := diff.Interface(test.expected, attempts); d != nil {
+				t.Error(d)
+			}
+			mu.Unlock()
+			testy.StatusErrorRE(t, test.err, test.status, err)
+		})
+	}
+}
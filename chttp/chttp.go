@@ -1,5 +1,3 @@
-// Package chttp provides a minimal HTTP driver backend for communicating with
-// CouchDB servers.
 package chttp
 
 import (
@@ -8,10 +6,12 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kivik/kivik"
 	"github.com/go-kivik/kivik/errors"
@@ -25,6 +25,35 @@ const (
 type Client struct {
 	*http.Client
 
+	// MaxRequestSize, if greater than zero, is the maximum size, in bytes, of
+	// a request body. Larger requests fail with a 413 status: before being
+	// sent, if their ContentLength is known, or else as soon as the limit is
+	// passed while the body is sent.
+	MaxRequestSize int64
+
+	// TimingsFunc, if set, is called with a summary of the latency of each
+	// request.
+	TimingsFunc TimingsFunc
+
+	// ReadOnly, if true, causes requests which may modify data on the server,
+	// such as PUT, DELETE, COPY, and most POST requests, to fail locally with
+	// a *ReadOnlyError.
+	ReadOnly bool
+
+	// Clock, if set, is the source of the time for request timings. It
+	// defaults to the SystemClock.
+	Clock Clock
+
+	// RequestIDFunc, if set, generates the ID sent with each request which
+	// has none attached with WithRequestID. By default, random IDs are
+	// generated.
+	RequestIDFunc func() string
+
+	// Policies, if set, configures the timeout and retries of requests of
+	// each EndpointClass. Requests of classes without a Policy are sent once,
+	// without a timeout.
+	Policies map[EndpointClass]*Policy
+
 	rawDSN string
 	dsn    *url.URL
 	auth   Authenticator
@@ -36,9 +65,9 @@ type Client struct {
 // authentication mechanism, do not specify credentials in the URL, and instead
 // call the Auth() method later.
 func New(ctx context.Context, dsn string) (*Client, error) {
-	dsnURL, err := url.Parse(dsn)
+	dsnURL, err := parseDSN(dsn)
 	if err != nil {
-		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+		return nil, err
 	}
 	user := dsnURL.User
 	dsnURL.User = nil
@@ -56,6 +85,22 @@ func New(ctx context.Context, dsn string) (*Client, error) {
 	return c, nil
 }
 
+// parseDSN parses dsn, and ensures that it names a host. IPv6 literal hosts
+// must be enclosed in brackets, as in http://[::1]:5984/.
+func parseDSN(dsn string) (*url.URL, error) {
+	dsnURL, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	if dsnURL.Host == "" {
+		return nil, errors.Status(kivik.StatusBadRequest, "chttp: no host in DSN")
+	}
+	if strings.Count(dsnURL.Host, ":") > 1 && !strings.HasPrefix(dsnURL.Host, "[") {
+		return nil, errors.Statusf(kivik.StatusBadRequest, "chttp: IPv6 address in DSN must be enclosed in brackets, as in http://[::1]:5984/")
+	}
+	return dsnURL, nil
+}
+
 // DSN returns the unparsed DSN used to connect.
 func (c *Client) DSN() string {
 	return c.rawDSN
@@ -75,6 +120,21 @@ func (c *Client) defaultAuth(ctx context.Context, username, password string) err
 	})
 }
 
+// CloseIdleConnections closes any idle keep-alive connections held by the
+// client's transport, if the transport supports it.
+func (c *Client) CloseIdleConnections() {
+	type idleCloser interface {
+		CloseIdleConnections()
+	}
+	transport := c.Client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if t, ok := transport.(idleCloser); ok {
+		t.CloseIdleConnections()
+	}
+}
+
 // Auth authenticates using the provided Authenticator.
 func (c *Client) Auth(ctx context.Context, a Authenticator) error {
 	if c.auth != nil {
@@ -96,9 +156,19 @@ type Options struct {
 	// ContentType sets the requests's Content-Type header. Defaults to "application/json".
 	ContentType string
 
-	// Body sets the body of the request.
+	// Body sets the body of the request. It is sent as it is read, so an
+	// error reading it aborts the request, and is returned by DoReq.
 	Body io.ReadCloser
 
+	// ContentLength, if greater than zero, is the length of Body, which is
+	// otherwise sent with chunked encoding.
+	ContentLength int64
+
+	// GetBody, if set, returns a fresh copy of Body, with which the request
+	// may be sent again, such as when retried according to a Policy. Requests
+	// with a Body, but without GetBody, are sent only once.
+	GetBody func() (io.ReadCloser, error)
+
 	// JSON is an arbitrary data type which is marshaled to the request's body.
 	// It an error to set both Body and JSON on the same request. When this is
 	// set, ContentType is unconditionally set to 'application/json'. Note that
@@ -115,6 +185,25 @@ type Options struct {
 
 	// Destination is the target ID for COPY
 	Destination string
+
+	// Range adds the Range header, for partial content requests.
+	Range string
+
+	// IfRange adds the If-Range header, which makes Range conditional.
+	IfRange string
+
+	// Header adds arbitrary headers to the request.
+	Header http.Header
+}
+
+// SetBody sets Body to read b, along with ContentLength and GetBody, so that
+// the request may be sent again.
+func (o *Options) SetBody(b []byte) {
+	o.Body = ioutil.NopCloser(bytes.NewReader(b))
+	o.ContentLength = int64(len(b))
+	o.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
 }
 
 // Response represents a response from a CouchDB server.
@@ -148,6 +237,7 @@ func (c *Client) DoJSON(ctx context.Context, method, path string, opts *Options,
 
 // NewRequest returns a new *http.Request to the CouchDB server, and the
 // specified path. The host, schema, etc, of the specified path are ignored.
+// Query parameters are ordered with SortQuery.
 func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
 	reqPath, err := url.Parse(path)
 	if err != nil {
@@ -155,7 +245,7 @@ func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Re
 	}
 	url := *c.dsn // Make a copy
 	url.Path = reqPath.Path
-	url.RawQuery = reqPath.RawQuery
+	url.RawQuery = SortQuery(reqPath.RawQuery)
 	req, err := http.NewRequest(method, url.String(), body)
 	if err != nil {
 		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
@@ -170,40 +260,206 @@ func (c *Client) DoReq(ctx context.Context, method, path string, opts *Options)
 	if method == "" {
 		return nil, errors.Status(kivik.StatusBadRequest, "chttp: method required")
 	}
-	var body io.Reader
-	if opts != nil {
-		if opts.Body != nil {
-			body = opts.Body
-		}
+	if c.ReadOnly && mutating(method, path) {
+		return nil, &ReadOnlyError{Method: method, Path: path}
 	}
+	priority, err := requestPriority(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hasBody := opts != nil && opts.Body != nil
+	if hasBody && c.MaxRequestSize > 0 && opts.ContentLength > c.MaxRequestSize {
+		return nil, errors.Statusf(http.StatusRequestEntityTooLarge, "chttp: request body of %d bytes exceeds maximum of %d bytes", opts.ContentLength, c.MaxRequestSize)
+	}
+	// A request with a body may be sent again only if the body can be read
+	// again.
+	replayable := !hasBody || opts.GetBody != nil
 
-	// read the whole body into a string
-
-	var destBody io.Reader
-	destBody = body
-
-	if body != nil {
-
-		entireBody, err := ioutil.ReadAll(body)
+	requestID := RequestID(ctx)
+	if requestID == "" {
+		requestID = c.newRequestID()
+	}
+	var tracer *timingsTracer
+	if c.TimingsFunc != nil {
+		clock := c.clock()
+		tracer = &timingsTracer{clock: clock, timings: &RequestTimings{
+			Method:    method,
+			Path:      path,
+			RequestID: requestID,
+			Start:     clock.Now(),
+		}}
+		ctx = tracer.withContext(ctx)
+	}
+	var reused bool
+	if idempotent(method) {
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				reused = info.Reused
+			},
+		})
+	}
+	var body *requestBody
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		var reqBody io.Reader
+		if hasBody {
+			rc := opts.Body
+			if body != nil {
+				var err error
+				if rc, err = opts.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+			body = &requestBody{ReadCloser: rc, max: c.MaxRequestSize}
+			reqBody = body
+		}
+		req, err := c.NewRequest(ctx, method, path, reqBody)
 		if err != nil {
-			log.Printf("Error reading body: %v", err)
+			return nil, err
 		}
-		log.Printf("body size: %d bytes", len(entireBody))
-
-		body2 := bytes.NewBuffer(entireBody)
-		destBody = body2
+		if hasBody {
+			req.ContentLength = opts.ContentLength
+			req.GetBody = opts.GetBody
+		}
+		fixPath(req, path)
+		setHeaders(req, opts)
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		if priority != "" {
+			req.Header.Set(PriorityHeader, string(priority))
+		}
+		return req, nil
+	}
 
+	policy := c.policy(method, path)
+	maxRetries := policy.MaxRetries
+	if !repeatable(method, path) {
+		maxRetries = 0
+	}
+	delay := policy.RetryDelay
+	var response *http.Response
+	for attempt := 0; ; attempt++ {
+		response, err = c.attempt(ctx, policy.Timeout, newRequest, &reused, replayable)
+		if readErr := body.err(); readErr != nil {
+			// An error reading the body aborts the request, and takes
+			// precedence over the transport's report of it.
+			if response != nil {
+				_ = response.Body.Close()
+			}
+			response, err = nil, readErr
+		}
+		if !replayable || attempt >= maxRetries || ctx.Err() != nil || !retryable(response, err) {
+			break
+		}
+		if response != nil {
+			_ = response.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, netError(ctx.Err())
+		case <-c.clock().After(delay):
+		}
+		delay *= 2
+	}
+	if tracer != nil {
+		c.TimingsFunc(tracer.snapshot())
 	}
+	if err == nil {
+		reportHeaders(ctx, method, path, response)
+	}
+	return response, netError(err)
+}
 
-	req, err := c.NewRequest(ctx, method, path, destBody)
+// attempt sends a single request, built by newRequest, within timeout, if it
+// is greater than zero. reused reports whether the connection used had been
+// idle.
+func (c *Client) attempt(ctx context.Context, timeout time.Duration, newRequest func(context.Context) (*http.Request, error), reused *bool, replayable bool) (*http.Response, error) {
+	if timeout <= 0 {
+		return c.send(ctx, newRequest, reused, replayable)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	response, err := c.send(ctx, newRequest, reused, replayable)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	fixPath(req, path)
-	setHeaders(req, opts)
+	response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
 
+// send sends the request built by newRequest, retrying once on a fresh
+// connection, if the request is replayable, if an idle one turns out to have
+// been closed.
+func (c *Client) send(ctx context.Context, newRequest func(context.Context) (*http.Request, error), reused *bool, replayable bool) (*http.Response, error) {
+	req, err := newRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
 	response, err := c.Do(req)
-	return response, netError(err)
+	if err != nil && replayable && *reused && staleConnError(err) {
+		// The server closed the keep-alive connection while it was idle, so
+		// try once more on a fresh connection.
+		c.CloseIdleConnections()
+		if req, err = newRequest(ctx); err != nil {
+			return nil, err
+		}
+		response, err = c.Do(req)
+	}
+	return response, err
+}
+
+// requestBody is the body of a request, as read by the transport. It enforces
+// the Client's MaxRequestSize, and records any error reading the body, which
+// the transport may not report as such.
+type requestBody struct {
+	io.ReadCloser
+	max int64
+
+	mu      sync.Mutex
+	n       int64
+	readErr error
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.n += int64(n)
+	if b.max > 0 && b.n > b.max {
+		n, err = 0, errors.Statusf(http.StatusRequestEntityTooLarge, "chttp: request body exceeds maximum of %d bytes", b.max)
+	}
+	if err != nil && err != io.EOF && b.readErr == nil {
+		b.readErr = err
+	}
+	return n, err
+}
+
+// err returns the error, if any, encountered reading the body.
+func (b *requestBody) err() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.readErr
+}
+
+// idempotent returns true if requests with method may safely be retried.
+func idempotent(method string) bool {
+	return method == kivik.MethodGet || method == kivik.MethodHead
+}
+
+// staleConnError returns true if err indicates that the connection was closed
+// by the server before a response was received.
+func staleConnError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset by peer") || strings.Contains(msg, "broken pipe")
 }
 
 func netError(err error) error {
@@ -278,6 +534,17 @@ func setHeaders(req *http.Request, opts *Options) {
 		if opts.IfNoneMatch != "" {
 			req.Header.Set("If-None-Match", opts.IfNoneMatch)
 		}
+		if opts.Range != "" {
+			req.Header.Set("Range", opts.Range)
+		}
+		if opts.IfRange != "" {
+			req.Header.Set("If-Range", opts.IfRange)
+		}
+		for key, values := range opts.Header {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
 	}
 	req.Header.Add("Accept", accept)
 	req.Header.Add("Content-Type", contentType)
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"testing"
@@ -36,6 +37,31 @@ func TestNew(t *testing.T) {
 			status: kivik.StatusBadRequest,
 			err:    `parse http://foo.com/%xx: invalid URL escape "%xx"`,
 		},
+		{
+			name:   "no host",
+			dsn:    "http:///foo",
+			status: kivik.StatusBadRequest,
+			err:    "chttp: no host in DSN",
+		},
+		{
+			name:   "unbracketed IPv6",
+			dsn:    "http://fe80::1/",
+			status: kivik.StatusBadRequest,
+			err:    "chttp: IPv6 address in DSN must be enclosed in brackets, as in http://[::1]:5984/",
+		},
+		{
+			name: "IPv6 with port",
+			dsn:  "http://[::1]:5984/",
+			expected: &Client{
+				Client: &http.Client{},
+				rawDSN: "http://[::1]:5984/",
+				dsn: &url.URL{
+					Scheme: "http",
+					Host:   "[::1]:5984",
+					Path:   "/",
+				},
+			},
+		},
 		{
 			name: "no auth",
 			dsn:  "http://foo.com/",
@@ -73,20 +99,21 @@ func TestNew(t *testing.T) {
 			dsn, _ := url.Parse(s.URL)
 			authDSN.User = url.UserPassword("user", "password")
 			jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+			auth := &CookieAuth{
+				Username: "user",
+				Password: "password",
+				dsn:      dsn,
+				setJar:   true,
+				jar:      jar,
+			}
 			return newTest{
 				name: "auth success",
 				dsn:  authDSN.String(),
 				expected: &Client{
-					Client: &http.Client{Jar: jar},
+					Client: &http.Client{Jar: jar, Transport: auth},
 					rawDSN: authDSN.String(),
 					dsn:    dsn,
-					auth: &CookieAuth{
-						Username: "user",
-						Password: "password",
-						dsn:      dsn,
-						setJar:   true,
-						jar:      jar,
-					},
+					auth:   auth,
 				},
 			}
 		}(),
@@ -111,6 +138,21 @@ func TestDSN(t *testing.T) {
 	}
 }
 
+func TestNewRequestIPv6(t *testing.T) {
+	c, err := New(context.Background(), "http://[fe80::1%25en0]:5984/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := c.NewRequest(context.Background(), kivik.MethodGet, "/db/doc/att.txt?rev=1-xxx", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "http://[fe80::1%25en0]:5984/db/doc/att.txt?rev=1-xxx"
+	if req.URL.String() != expected {
+		t.Errorf("Unexpected URL: %s", req.URL)
+	}
+}
+
 func TestFixPath(t *testing.T) {
 	tests := []struct {
 		Input    string
@@ -248,6 +290,24 @@ func TestSetHeaders(t *testing.T) {
 				"If-None-Match": {`"foo"`},
 			},
 		},
+		{
+			Name:    "Range",
+			Options: &Options{Range: "bytes=10-"},
+			Expected: http.Header{
+				"Accept":       {"application/json"},
+				"Content-Type": {"application/json"},
+				"Range":        {"bytes=10-"},
+			},
+		},
+		{
+			Name:    "Header",
+			Options: &Options{Header: http.Header{"X-Foo": {"a", "b"}}},
+			Expected: http.Header{
+				"Accept":       {"application/json"},
+				"Content-Type": {"application/json"},
+				"X-Foo":        {"a", "b"},
+			},
+		},
 	}
 	for _, test := range tests {
 		func(test shTest) {
@@ -509,6 +569,26 @@ func TestNewRequest(t *testing.T) {
 				Host:       "example.com",
 			},
 		},
+		{
+			name:   "sorted query",
+			method: "GET",
+			path:   "foo?skip=2&limit=10&include_docs=true",
+			client: newTestClient(nil, nil),
+			expected: &http.Request{
+				Method: "GET",
+				URL: func() *url.URL {
+					url := newTestClient(nil, nil).dsn
+					url.Path = "/foo"
+					url.RawQuery = "include_docs=true&limit=10&skip=2"
+					return url
+				}(),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{},
+				Host:       "example.com",
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -580,6 +660,50 @@ func TestDoReq(t *testing.T) {
 			status: kivik.StatusBadRequest,
 			err:    "Put http://example.com/foo: bad request",
 		},
+		{
+			name:   "request too large",
+			method: "PUT",
+			path:   "foo",
+			opts:   &Options{Body: Body("0123456789"), ContentLength: 10},
+			client: func() *Client {
+				c := newTestClient(nil, errors.New("should not be sent"))
+				c.MaxRequestSize = 5
+				return c
+			}(),
+			status: kivik.StatusRequestEntityTooLarge,
+			err:    "chttp: request body of 10 bytes exceeds maximum of 5 bytes",
+		},
+		{
+			name:   "request of unknown length too large",
+			method: "PUT",
+			path:   "foo",
+			opts:   &Options{Body: Body("0123456789")},
+			client: func() *Client {
+				c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+					if _, err := ioutil.ReadAll(req.Body); err != nil {
+						return nil, err
+					}
+					return &http.Response{StatusCode: 201, Body: Body("")}, nil
+				})
+				c.MaxRequestSize = 5
+				return c
+			}(),
+			status: kivik.StatusRequestEntityTooLarge,
+			err:    "chttp: request body exceeds maximum of 5 bytes",
+		},
+		{
+			name:   "error reading body",
+			method: "PUT",
+			path:   "foo",
+			opts:   &Options{Body: ioutil.NopCloser(testy.ErrorReader("0123456789", errors.New("read failed")))},
+			client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+				// The server stores whatever it receives.
+				_, _ = ioutil.ReadAll(req.Body)
+				return &http.Response{StatusCode: 201, Body: Body("")}, nil
+			}),
+			status: kivik.StatusNetworkError,
+			err:    "read failed",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -688,3 +812,70 @@ func TestNetError(t *testing.T) {
 		})
 	}
 }
+
+func TestStaleConnRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		reused   bool
+		err      error
+		attempts int
+	}{
+		{
+			name:     "reused GET",
+			method:   kivik.MethodGet,
+			reused:   true,
+			err:      io.EOF,
+			attempts: 2,
+		},
+		{
+			name:     "reused HEAD, connection reset",
+			method:   kivik.MethodHead,
+			reused:   true,
+			err:      errors.New("read tcp 127.0.0.1:5984: connection reset by peer"),
+			attempts: 2,
+		},
+		{
+			name:     "new connection",
+			method:   kivik.MethodGet,
+			err:      io.EOF,
+			attempts: 1,
+		},
+		{
+			name:     "not idempotent",
+			method:   kivik.MethodPost,
+			reused:   true,
+			err:      io.EOF,
+			attempts: 1,
+		},
+		{
+			name:     "other error",
+			method:   kivik.MethodGet,
+			reused:   true,
+			err:      errors.New("no such host"),
+			attempts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+					trace.GotConn(httptrace.GotConnInfo{Reused: test.reused})
+				}
+				if attempts == 1 {
+					return nil, test.err
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(""),
+				}, nil
+			})
+			_, _ = c.DoReq(context.Background(), test.method, "/foo", nil)
+			if attempts != test.attempts {
+				t.Errorf("Expected %d attempts, got %d", test.attempts, attempts)
+			}
+		})
+	}
+}
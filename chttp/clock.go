@@ -0,0 +1,38 @@
+package chttp
+
+import "time"
+
+// Clock is a source of the current time, and of timers. Replacing the
+// SystemClock allows tests to control the passage of time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel which receives the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clock returns the client's Clock, or the SystemClock.
+func (c *Client) clock() Clock {
+	if c.Clock != nil {
+		return c.Clock
+	}
+	return SystemClock
+}
+
+// newRequestID returns a request ID from the client's RequestIDFunc, or a
+// randomly generated one.
+func (c *Client) newRequestID() string {
+	if c.RequestIDFunc != nil {
+		return c.RequestIDFunc()
+	}
+	return newRequestID()
+}
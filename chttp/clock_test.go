@@ -0,0 +1,61 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kivik/kivik"
+)
+
+// fixedClock is a Clock which is stopped at a single instant.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+func (c fixedClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Time(c).Add(d)
+	return ch
+}
+
+func TestClientClock(t *testing.T) {
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	var sent []string
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Header.Get(RequestIDHeader))
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body("{}"), Request: req}, nil
+	})
+	var n int
+	c.RequestIDFunc = func() string {
+		n++
+		return "req-" + string(rune('0'+n))
+	}
+	c.Clock = fixedClock(now)
+	var timings []*RequestTimings
+	c.TimingsFunc = func(t *RequestTimings) {
+		timings = append(timings, t)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.DoError(context.Background(), kivik.MethodGet, "/foo", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.DoError(WithRequestID(context.Background(), "mine"), kivik.MethodGet, "/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 3 || sent[0] != "req-1" || sent[1] != "req-2" || sent[2] != "mine" {
+		t.Errorf("Unexpected request IDs: %v", sent)
+	}
+	if len(timings) != 3 {
+		t.Fatalf("Expected 3 timings, got %d", len(timings))
+	}
+	for _, timing := range timings {
+		if !timing.Start.Equal(now) {
+			t.Errorf("Unexpected start time: %s", timing.Start)
+		}
+		if timing.FirstByte != 0 {
+			t.Errorf("Unexpected time to first byte: %s", timing.FirstByte)
+		}
+	}
+}
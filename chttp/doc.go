@@ -0,0 +1,25 @@
+// Package chttp provides a minimal HTTP driver backend for communicating with
+// CouchDB servers. It is the layer on which the couchdb driver is built, and
+// may be used directly by tools which need to reach CouchDB endpoints that
+// Kivik does not model.
+//
+// The package covers:
+//
+//   - Connecting and authenticating: New, Client, and the Authenticator
+//     implementations, BasicAuth and CookieAuth.
+//   - Building and sending requests: Client.NewRequest, Client.DoReq,
+//     Client.DoJSON, Client.DoError and Options, with EncodeDocID and
+//     EncodeParams applying CouchDB's path and query-string encoding rules,
+//     and EncodeBody streaming JSON request bodies.
+//   - Decoding responses: ResponseError and HTTPError, which carry the status
+//     code used by kivik.StatusCode, and DecodeJSON, ETag and GetRev.
+//   - Instrumentation: request IDs, with WithRequestID and RequestID, and
+//     latency tracing, with Client.TimingsFunc.
+//
+// The exported API of this package is versioned along with the couchdb driver,
+// following semantic versioning: within a major version, exported
+// identifiers are not removed, and their signatures and documented behavior
+// do not change incompatibly. New fields may be added to structs, such as
+// Options, so they should be initialized with named fields. Unexported
+// identifiers, and the exact text of error messages, are not covered.
+package chttp
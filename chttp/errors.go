@@ -9,8 +9,16 @@ import (
 
 // HTTPError is an error that represents an HTTP transport error.
 type HTTPError struct {
-	Code   int
+	Code int
+	// Name is the error name reported by the server, such as "not_found".
+	Name   string `json:"error"`
 	Reason string `json:"reason"`
+
+	// RequestID is the ID the client sent with the failed request, and
+	// ServerRequestID the ID reported by the server, if any. They can be used
+	// to find the request in client and server logs.
+	RequestID       string `json:"-"`
+	ServerRequestID string `json:"-"`
 }
 
 func (e *HTTPError) Error() string {
@@ -38,5 +46,7 @@ func ResponseError(resp *http.Response) error {
 		}
 	}
 	httpErr.Code = resp.StatusCode
+	httpErr.RequestID = resp.Request.Header.Get(RequestIDHeader)
+	httpErr.ServerRequestID = resp.Header.Get(ServerRequestIDHeader)
 	return httpErr
 }
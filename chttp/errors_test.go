@@ -50,6 +50,7 @@ func TestResponseError(t *testing.T) {
 			},
 			expected: &HTTPError{
 				Code:   400,
+				Name:   "illegal_database_name",
 				Reason: "Name: '_foo'. Only lowercase characters (a-z), digits (0-9), and any of the characters _, $, (, ), +, -, and / are allowed. Must begin with a letter.",
 			},
 		},
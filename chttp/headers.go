@@ -0,0 +1,56 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// HeaderFunc receives the selected headers of the response to each request
+// made with a context returned by WithResponseHeaders. A single operation may
+// make several requests.
+type HeaderFunc func(method, path string, header http.Header)
+
+type responseHeadersKey struct{}
+
+type responseHeaders struct {
+	fn    HeaderFunc
+	names []string
+}
+
+// WithResponseHeaders returns a context which causes fn to be called with the
+// named headers, such as "ETag", "X-Couch-Request-ID" and "Content-Length", of
+// the response to each request made with it, including error responses. If no
+// names are given, fn receives all headers. fn must not modify the headers.
+//
+// Example:
+//
+//     ctx = chttp.WithResponseHeaders(ctx, func(method, path string, h http.Header) {
+//         log.Printf("%s %s: ETag %s", method, path, h.Get("ETag"))
+//     }, "ETag")
+func WithResponseHeaders(ctx context.Context, fn HeaderFunc, names ...string) context.Context {
+	canonical := make([]string, len(names))
+	for i, name := range names {
+		canonical[i] = http.CanonicalHeaderKey(name)
+	}
+	return context.WithValue(ctx, responseHeadersKey{}, &responseHeaders{fn: fn, names: canonical})
+}
+
+// reportHeaders passes the headers of resp to the HeaderFunc attached to ctx,
+// if any.
+func reportHeaders(ctx context.Context, method, path string, resp *http.Response) {
+	rh, ok := ctx.Value(responseHeadersKey{}).(*responseHeaders)
+	if !ok || rh.fn == nil {
+		return
+	}
+	if len(rh.names) == 0 {
+		rh.fn(method, path, resp.Header)
+		return
+	}
+	header := make(http.Header, len(rh.names))
+	for _, name := range rh.names {
+		if values, ok := resp.Header[name]; ok {
+			header[name] = values
+		}
+	}
+	rh.fn(method, path, header)
+}
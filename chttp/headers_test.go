@@ -0,0 +1,67 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestWithResponseHeaders(t *testing.T) {
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Header: http.Header{
+				"Etag":               {`"1-xxx"`},
+				"X-Couch-Request-Id": {"abc123"},
+				"Server":             {"CouchDB/2.1.0"},
+			},
+			Body:    Body("{}"),
+			Request: req,
+		}, nil
+	})
+	tests := []struct {
+		name     string
+		names    []string
+		expected http.Header
+	}{
+		{
+			name:  "selected",
+			names: []string{"etag", "X-Couch-Request-ID", "Content-Length"},
+			expected: http.Header{
+				"Etag":               {`"1-xxx"`},
+				"X-Couch-Request-Id": {"abc123"},
+			},
+		},
+		{
+			name: "all",
+			expected: http.Header{
+				"Etag":               {`"1-xxx"`},
+				"X-Couch-Request-Id": {"abc123"},
+				"Server":             {"CouchDB/2.1.0"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+			var headers http.Header
+			ctx := WithResponseHeaders(context.Background(), func(method, path string, h http.Header) {
+				requests = append(requests, method+" "+path)
+				headers = h
+			}, test.names...)
+			if _, err := c.DoError(ctx, kivik.MethodGet, "/db/doc", nil); err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.Interface([]string{"GET /db/doc"}, requests); d != nil {
+				t.Error(d)
+			}
+			if d := diff.Interface(test.expected, headers); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
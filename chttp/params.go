@@ -0,0 +1,212 @@
+package chttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// paramEncoder converts an option value to its query-string representation.
+type paramEncoder func(key string, value interface{}) ([]string, error)
+
+// paramRules maps known query parameters to their encoding rules. Parameters
+// not listed here are converted by encodeGeneric.
+var paramRules = map[string]paramEncoder{
+	// JSON-encoded keys
+	"key":        encodeJSON,
+	"keys":       encodeJSON,
+	"startkey":   encodeJSON,
+	"start_key":  encodeJSON,
+	"endkey":     encodeJSON,
+	"end_key":    encodeJSON,
+	"atts_since": encodeJSON,
+	"doc_ids":    encodeJSON,
+
+	// Plain-string document IDs and revisions
+	"startkey_docid":   encodeString,
+	"start_key_doc_id": encodeString,
+	"endkey_docid":     encodeString,
+	"end_key_doc_id":   encodeString,
+	"rev":              encodeString,
+
+	// Booleans
+	"attachments":       encodeBool,
+	"att_encoding_info": encodeBool,
+	"conflicts":         encodeBool,
+	"deleted_conflicts": encodeBool,
+	"descending":        encodeBool,
+	"group":             encodeBool,
+	"include_docs":      encodeBool,
+	"inclusive_end":     encodeBool,
+	"latest":            encodeBool,
+	"local_seq":         encodeBool,
+	"meta":              encodeBool,
+	"reduce":            encodeBool,
+	"revs":              encodeBool,
+	"revs_info":         encodeBool,
+	"sorted":            encodeBool,
+	"stable":            encodeBool,
+	"update_seq":        encodeBool,
+
+	// Integers
+	"group_level": encodeInt,
+	"limit":       encodeInt,
+	"skip":        encodeInt,
+	"timeout":     encodeInt,
+
+	// Integers, or true for the server's default
+	"heartbeat": encodeIntOrBool,
+}
+
+// EncodeParams converts options to query parameters, according to the
+// encoding rules for CouchDB's query parameters. Values for keys which take
+// JSON, such as startkey, are JSON-encoded, unless given as a string, []byte
+// or json.RawMessage, in which case they are validated as JSON. Booleans and
+// integers must have the appropriate type, or be a string representation of
+// one. Options which CouchDB does not define are passed as given, and must be
+// a string, []string, bool or integer.
+func EncodeParams(opts ...map[string]interface{}) (url.Values, error) {
+	params := url.Values{}
+	for _, optsSet := range opts {
+		for key, i := range optsSet {
+			values, err := EncodeParam(key, i)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range values {
+				params.Add(key, value)
+			}
+		}
+	}
+	return params, nil
+}
+
+// SortQuery returns the raw query string with its parameters ordered by key,
+// so that the same options always produce the same URL. Parameters which
+// share a key keep their relative order, and are not otherwise re-encoded.
+func SortQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	sort.SliceStable(params, func(i, j int) bool {
+		return queryKey(params[i]) < queryKey(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// queryKey returns the key of a single raw query parameter.
+func queryKey(param string) string {
+	if i := strings.IndexByte(param, '='); i >= 0 {
+		return param[:i]
+	}
+	return param
+}
+
+// EncodeParam encodes a single option, as EncodeParams does, returning its
+// query-string values.
+func EncodeParam(key string, value interface{}) ([]string, error) {
+	if rule, ok := paramRules[key]; ok {
+		return rule(key, value)
+	}
+	return encodeGeneric(key, value)
+}
+
+// encodeGeneric converts strings, string slices, booleans, and integers with
+// no further validation.
+func encodeGeneric(_ string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case bool:
+		return []string{fmt.Sprintf("%t", v)}, nil
+	case int, uint, uint8, uint16, uint32, uint64, int8, int16, int32, int64:
+		return []string{fmt.Sprintf("%d", v)}, nil
+	}
+	return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: invalid type %T for options", value)
+}
+
+// encodeJSON JSON-encodes the value. Strings, byte slices, and
+// json.RawMessages are assumed to be already encoded, and are validated only.
+func encodeJSON(key string, value interface{}) ([]string, error) {
+	var raw []byte
+	switch v := value.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case json.RawMessage:
+		raw = v
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+		}
+		return []string{string(encoded)}, nil
+	}
+	var x interface{}
+	if err := json.Unmarshal(raw, &x); err != nil {
+		return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be valid JSON: %s", key, err)
+	}
+	return []string{string(raw)}, nil
+}
+
+func encodeString(key string, value interface{}) ([]string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be string, not %T", key, value)
+	}
+	return []string{s}, nil
+}
+
+func encodeBool(key string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case string:
+		if v == "true" || v == "false" {
+			return []string{v}, nil
+		}
+		return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: invalid value '%s' for boolean option '%s'", v, key)
+	}
+	return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be bool, not %T", key, value)
+}
+
+func encodeInt(key string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case int, uint, uint8, uint16, uint32, uint64, int8, int16, int32, int64:
+		return []string{fmt.Sprintf("%d", v)}, nil
+	case float64:
+		if v == math.Trunc(v) {
+			return []string{strconv.FormatInt(int64(v), 10)}, nil
+		}
+	case string:
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return []string{v}, nil
+		}
+	}
+	return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: invalid value '%v' for integer option '%s'", value, key)
+}
+
+// encodeIntOrBool encodes booleans as encodeBool does, and anything else as
+// encodeInt does.
+func encodeIntOrBool(key string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case bool:
+		return encodeBool(key, v)
+	case string:
+		if v == "true" || v == "false" {
+			return []string{v}, nil
+		}
+	}
+	return encodeInt(key, value)
+}
@@ -0,0 +1,196 @@
+package chttp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestEncodeParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		value    interface{}
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:     "unknown string",
+			key:      "foo",
+			value:    "bar",
+			expected: []string{"bar"},
+		},
+		{
+			name:     "unknown string slice",
+			key:      "foo",
+			value:    []string{"bar", "baz"},
+			expected: []string{"bar", "baz"},
+		},
+		{
+			name:   "unknown invalid type",
+			key:    "foo",
+			value:  1.5,
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid type float64 for options",
+		},
+		{
+			name:     "key, pre-encoded string",
+			key:      "startkey",
+			value:    `"foo"`,
+			expected: []string{`"foo"`},
+		},
+		{
+			name:     "key, raw message",
+			key:      "key",
+			value:    json.RawMessage(`["foo",1]`),
+			expected: []string{`["foo",1]`},
+		},
+		{
+			name:   "key, invalid JSON string",
+			key:    "endkey",
+			value:  "foo",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'endkey' must be valid JSON: invalid character 'o' in literal false \\(expecting 'a'\\)",
+		},
+		{
+			name:     "key, slice",
+			key:      "keys",
+			value:    []string{"foo", "bar"},
+			expected: []string{`["foo","bar"]`},
+		},
+		{
+			name:     "key, number",
+			key:      "key",
+			value:    123,
+			expected: []string{"123"},
+		},
+		{
+			name:     "doc ID",
+			key:      "startkey_docid",
+			value:    "foo",
+			expected: []string{"foo"},
+		},
+		{
+			name:   "doc ID, wrong type",
+			key:    "endkey_docid",
+			value:  123,
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'endkey_docid' must be string, not int",
+		},
+		{
+			name:     "bool",
+			key:      "include_docs",
+			value:    true,
+			expected: []string{"true"},
+		},
+		{
+			name:     "bool, string",
+			key:      "include_docs",
+			value:    "false",
+			expected: []string{"false"},
+		},
+		{
+			name:   "bool, invalid string",
+			key:    "include_docs",
+			value:  "yes",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid value 'yes' for boolean option 'include_docs'",
+		},
+		{
+			name:   "bool, wrong type",
+			key:    "descending",
+			value:  1,
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'descending' must be bool, not int",
+		},
+		{
+			name:     "int",
+			key:      "limit",
+			value:    int64(10),
+			expected: []string{"10"},
+		},
+		{
+			name:     "int, integral float",
+			key:      "limit",
+			value:    float64(10),
+			expected: []string{"10"},
+		},
+		{
+			name:     "int, string",
+			key:      "skip",
+			value:    "10",
+			expected: []string{"10"},
+		},
+		{
+			name:   "int, invalid string",
+			key:    "skip",
+			value:  "ten",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid value 'ten' for integer option 'skip'",
+		},
+		{
+			name:     "heartbeat, int",
+			key:      "heartbeat",
+			value:    10000,
+			expected: []string{"10000"},
+		},
+		{
+			name:     "heartbeat, bool",
+			key:      "heartbeat",
+			value:    true,
+			expected: []string{"true"},
+		},
+		{
+			name:     "heartbeat, bool string",
+			key:      "heartbeat",
+			value:    "true",
+			expected: []string{"true"},
+		},
+		{
+			name:   "heartbeat, invalid string",
+			key:    "heartbeat",
+			value:  "often",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid value 'often' for integer option 'heartbeat'",
+		},
+		{
+			name:   "int, fractional float",
+			key:    "limit",
+			value:  1.5,
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid value '1.5' for integer option 'limit'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := EncodeParam(test.key, test.value)
+			testy.StatusErrorRE(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestSortQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "", expected: ""},
+		{input: "rev=1-xxx", expected: "rev=1-xxx"},
+		{input: "skip=2&include_docs=true&limit=10", expected: "include_docs=true&limit=10&skip=2"},
+		{input: "b=2&a=1&b=1&flag", expected: "a=1&b=2&b=1&flag"},
+		{input: "startkey=%22foo%22&descending=true", expected: "descending=true&startkey=%22foo%22"},
+	}
+	for _, test := range tests {
+		if result := SortQuery(test.input); result != test.expected {
+			t.Errorf("SortQuery(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package chttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/kivik"
+)
+
+// EndpointClass is a class of requests which share a Policy.
+type EndpointClass string
+
+// The endpoint classes to which requests belong.
+const (
+	// EndpointRead includes GET and HEAD requests, and queries sent by POST,
+	// other than those of the other classes.
+	EndpointRead EndpointClass = "read"
+	// EndpointWrite includes requests which may modify data, other than
+	// attachment uploads.
+	EndpointWrite EndpointClass = "write"
+	// EndpointChanges includes requests to _changes and _db_updates feeds.
+	EndpointChanges EndpointClass = "changes"
+	// EndpointAttachments includes requests for individual attachments.
+	EndpointAttachments EndpointClass = "attachments"
+)
+
+// Policy configures the timeout and retries of a class of requests.
+type Policy struct {
+	// Timeout, if greater than zero, limits the duration of each attempt,
+	// including reading the response body.
+	Timeout time.Duration
+
+	// MaxRetries is the number of times a request is retried after a
+	// network error, a timeout, or a 502, 503 or 504 response. POST requests
+	// which may modify data, such as those creating a document without an ID
+	// or to _bulk_docs, are never retried, as the failed attempt may have
+	// been applied, and repeating it would create duplicate documents.
+	MaxRetries int
+
+	// RetryDelay is the delay before the first retry. It doubles for each
+	// subsequent retry.
+	RetryDelay time.Duration
+}
+
+// defaultPolicy sends each request once, without a timeout.
+var defaultPolicy = &Policy{}
+
+// policy returns the Policy for a request with method to path.
+func (c *Client) policy(method, path string) *Policy {
+	if p, ok := c.Policies[classify(method, path)]; ok && p != nil {
+		return p
+	}
+	return defaultPolicy
+}
+
+// classify returns the EndpointClass of a request with method to path.
+func classify(method, path string) EndpointClass {
+	path = strings.Trim(strings.SplitN(path, "?", 2)[0], "/")
+	parts := strings.Split(path, "/")
+	switch parts[len(parts)-1] {
+	case "_changes", "_db_updates":
+		return EndpointChanges
+	}
+	if attachmentPath(parts) {
+		return EndpointAttachments
+	}
+	if mutating(method, path) {
+		return EndpointWrite
+	}
+	return EndpointRead
+}
+
+// attachmentPath returns true if the path segments address an attachment, as
+// /{db}/{docid}/{attname} or /{db}/_design/{ddoc}/{attname}.
+func attachmentPath(parts []string) bool {
+	if len(parts) < 3 {
+		return false
+	}
+	// Other than the system databases, paths beginning with an underscore
+	// are server endpoints.
+	if strings.HasPrefix(parts[0], "_") && parts[0] != "_users" && parts[0] != "_replicator" {
+		return false
+	}
+	rest := parts[2:]
+	switch {
+	case parts[1] == "_design" || parts[1] == "_local":
+		rest = parts[3:]
+	case strings.HasPrefix(parts[1], "_"):
+		return false
+	}
+	return len(rest) > 0 && !strings.HasPrefix(rest[0], "_")
+}
+
+// repeatable returns true if a request with method to path may be sent again
+// without risk of applying it twice. Requests other than POST either don't
+// modify data, or fail with a conflict if repeated.
+func repeatable(method, path string) bool {
+	return method != kivik.MethodPost || !mutating(method, path)
+}
+
+// retryable returns true if the result of an attempt warrants a retry.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return kivik.StatusCode(netError(err)) == kivik.StatusNetworkError
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// cancelBody releases the context of a request with a timeout when its
+// response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,228 @@
+package chttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected EndpointClass
+	}{
+		{kivik.MethodGet, "/db/doc", EndpointRead},
+		{kivik.MethodHead, "/db/doc", EndpointRead},
+		{kivik.MethodPut, "/db/doc", EndpointWrite},
+		{kivik.MethodDelete, "/db/doc?rev=1-xxx", EndpointWrite},
+		{kivik.MethodPost, "/db/_bulk_docs", EndpointWrite},
+		{kivik.MethodPost, "/db/_find", EndpointRead},
+		{kivik.MethodPost, "/db/_design/foo/_view/bar", EndpointRead},
+		{kivik.MethodGet, "/db/_design/foo", EndpointRead},
+		{kivik.MethodGet, "/db/_changes?feed=continuous", EndpointChanges},
+		{kivik.MethodPost, "/db/_changes", EndpointChanges},
+		{kivik.MethodGet, "/_db_updates", EndpointChanges},
+		{kivik.MethodGet, "/db/doc/att.txt", EndpointAttachments},
+		{kivik.MethodPut, "/db/doc/att.txt?rev=1-xxx", EndpointAttachments},
+		{kivik.MethodGet, "/db/_design/foo/att.txt", EndpointAttachments},
+		{kivik.MethodGet, "/_users/org.couchdb.user:bob/avatar.png", EndpointAttachments},
+		{kivik.MethodGet, "/db/_design/foo/_info", EndpointRead},
+		{kivik.MethodGet, "/db/_index/foo/bar", EndpointRead},
+		{kivik.MethodGet, "/_node/_local/_config/couchdb/uuid", EndpointRead},
+		{kivik.MethodPut, "/_config/admins/bob", EndpointWrite},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			if class := classify(test.method, test.path); class != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, class)
+			}
+		})
+	}
+}
+
+// stepClock is a Clock which advances only when waited on, recording each
+// wait.
+type stepClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *stepClock) Now() time.Time { return c.now }
+func (c *stepClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestPolicyRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		responses []int
+		attempts  int
+		waits     []time.Duration
+		status    int
+		err       string
+	}{
+		{
+			name:      "no policy",
+			method:    kivik.MethodGet,
+			path:      "/db/_changes",
+			responses: []int{0, kivik.StatusOK},
+			attempts:  1,
+			status:    kivik.StatusNetworkError,
+			err:       "connection refused",
+		},
+		{
+			name:      "PUT retried",
+			method:    kivik.MethodPut,
+			path:      "/db/doc",
+			responses: []int{0, kivik.StatusCreated},
+			attempts:  2,
+			waits:     []time.Duration{time.Second},
+		},
+		{
+			name:      "POST not retried",
+			method:    kivik.MethodPost,
+			path:      "/db",
+			responses: []int{0, kivik.StatusCreated},
+			attempts:  1,
+			status:    kivik.StatusNetworkError,
+			err:       "connection refused",
+		},
+		{
+			name:      "bulk docs not retried",
+			method:    kivik.MethodPost,
+			path:      "/db/_bulk_docs",
+			responses: []int{http.StatusServiceUnavailable, kivik.StatusCreated},
+			attempts:  1,
+		},
+		{
+			name:      "query by POST retried",
+			method:    kivik.MethodPost,
+			path:      "/db/_find",
+			responses: []int{0, kivik.StatusOK},
+			attempts:  2,
+			waits:     []time.Duration{time.Second},
+		},
+		{
+			name:      "retried until success",
+			method:    kivik.MethodGet,
+			path:      "/db/doc",
+			responses: []int{0, http.StatusServiceUnavailable, kivik.StatusOK},
+			attempts:  3,
+			waits:     []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:      "retries exhausted",
+			method:    kivik.MethodGet,
+			path:      "/db/doc",
+			responses: []int{0, 0, 0, 0, kivik.StatusOK},
+			attempts:  4,
+			waits:     []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			status:    kivik.StatusNetworkError,
+			err:       "connection refused",
+		},
+		{
+			name:      "client error not retried",
+			method:    kivik.MethodGet,
+			path:      "/db/doc",
+			responses: []int{kivik.StatusNotFound, kivik.StatusOK},
+			attempts:  1,
+		},
+		{
+			name:      "attachment policy",
+			method:    kivik.MethodGet,
+			path:      "/db/doc/att.txt",
+			responses: []int{0, kivik.StatusOK},
+			attempts:  2,
+			waits:     []time.Duration{time.Minute},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				status := test.responses[attempts]
+				attempts++
+				if status == 0 {
+					return nil, errors.New("connection refused")
+				}
+				return &http.Response{StatusCode: status, Body: Body(""), Request: req}, nil
+			})
+			clock := &stepClock{}
+			c.Clock = clock
+			c.Policies = map[EndpointClass]*Policy{
+				EndpointRead:        {MaxRetries: 3, RetryDelay: time.Second},
+				EndpointWrite:       {MaxRetries: 3, RetryDelay: time.Second},
+				EndpointAttachments: {MaxRetries: 1, RetryDelay: time.Minute},
+			}
+			_, err := c.DoReq(context.Background(), test.method, test.path, nil)
+			if attempts != test.attempts {
+				t.Errorf("Expected %d attempts, got %d", test.attempts, attempts)
+			}
+			if len(clock.waits) != len(test.waits) {
+				t.Errorf("Unexpected waits: %v", clock.waits)
+			}
+			for i := range test.waits {
+				if i < len(clock.waits) && clock.waits[i] != test.waits[i] {
+					t.Errorf("Unexpected waits: %v", clock.waits)
+				}
+			}
+			testy.StatusErrorRE(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	var attempts int
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"ok":true}`), Request: req}, nil
+	})
+	c.Policies = map[EndpointClass]*Policy{
+		EndpointRead: {Timeout: 10 * time.Millisecond, MaxRetries: 1},
+	}
+	var result struct {
+		OK bool `json:"ok"`
+	}
+	if _, err := c.DoJSON(context.Background(), kivik.MethodGet, "/db/doc", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	if !result.OK {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	t.Run("body closed", func(t *testing.T) {
+		c.Policies[EndpointRead].MaxRetries = 0
+		resp, err := c.DoReq(context.Background(), kivik.MethodGet, "/db/doc", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := resp.Body.(*cancelBody); !ok {
+			t.Errorf("Expected the body to release the timeout when closed")
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Error(err)
+		}
+		if resp.Request.Context().Err() != context.Canceled {
+			t.Errorf("Expected the request context to be cancelled on Close")
+		}
+	})
+}
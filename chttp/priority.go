@@ -0,0 +1,41 @@
+package chttp
+
+import (
+	"context"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// PriorityHeader is the header which tells Cloudant's IO scheduler the
+// priority of a request.
+const PriorityHeader = "X-Cloudant-IO-Priority"
+
+// Priority is the IO priority of a request to Cloudant.
+type Priority string
+
+// The priorities recognized by Cloudant.
+const (
+	PriorityLow  Priority = "low"
+	PriorityHigh Priority = "high"
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context which causes requests made with it to be
+// sent with the priority p, so that, for instance, background batch jobs may
+// identify themselves as low priority. Servers other than Cloudant ignore it.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// requestPriority returns the priority attached to ctx by WithPriority, or an
+// error if it is not recognized.
+func requestPriority(ctx context.Context) (Priority, error) {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	switch p {
+	case "", PriorityLow, PriorityHigh:
+		return p, nil
+	}
+	return "", errors.Statusf(kivik.StatusBadRequest, "chttp: invalid priority: %q", p)
+}
@@ -0,0 +1,56 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestWithPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name: "no priority",
+			ctx:  context.Background(),
+		},
+		{
+			name:     "low",
+			ctx:      WithPriority(context.Background(), PriorityLow),
+			expected: "low",
+		},
+		{
+			name:     "high",
+			ctx:      WithPriority(context.Background(), PriorityHigh),
+			expected: "high",
+		},
+		{
+			name:   "invalid",
+			ctx:    WithPriority(context.Background(), "urgent"),
+			status: kivik.StatusBadRequest,
+			err:    `chttp: invalid priority: "urgent"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent string
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				sent = req.Header.Get(PriorityHeader)
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body("{}"), Request: req}, nil
+			})
+			_, err := c.DoError(test.ctx, kivik.MethodGet, "/db/_all_docs", nil)
+			if sent != test.expected {
+				t.Errorf("Unexpected priority header: %q", sent)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package chttp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+)
+
+// ReadOnlyError is returned by a ReadOnly client for requests which may
+// modify data on the server.
+type ReadOnlyError struct {
+	Method string
+	Path   string
+}
+
+var _ error = &ReadOnlyError{}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("chttp: %s %s refused by read-only client", e.Method, e.Path)
+}
+
+// StatusCode returns kivik.StatusForbidden.
+func (e *ReadOnlyError) StatusCode() int {
+	return kivik.StatusForbidden
+}
+
+// readOnlyPosts are the endpoints which accept POST requests, but only read
+// data, identified by the last segment of their path.
+var readOnlyPosts = map[string]bool{
+	"_all_docs":     true,
+	"_bulk_get":     true,
+	"_changes":      true,
+	"_dbs_info":     true,
+	"_design_docs":  true,
+	"_explain":      true,
+	"_find":         true,
+	"_local_docs":   true,
+	"_missing_revs": true,
+	"_revs_diff":    true,
+	"_temp_view":    true,
+}
+
+// mutating returns true if a request with method to path may modify data on
+// the server. Requests to /_session, which manage only the client's own
+// authentication, are always permitted.
+func mutating(method, path string) bool {
+	path = strings.Trim(strings.SplitN(path, "?", 2)[0], "/")
+	if path == "_session" {
+		return false
+	}
+	switch method {
+	case kivik.MethodGet, kivik.MethodHead, http.MethodOptions:
+		return false
+	case kivik.MethodPost:
+		parts := strings.Split(path, "/")
+		if readOnlyPosts[parts[len(parts)-1]] {
+			return false
+		}
+		// Multiple queries: /{db}/_all_docs/queries and
+		// /{db}/_design/{ddoc}/_view/{view}/queries
+		if len(parts) > 1 && parts[len(parts)-1] == "queries" {
+			parts = parts[:len(parts)-1]
+			if readOnlyPosts[parts[len(parts)-1]] {
+				return false
+			}
+		}
+		// View queries: /{db}/_design/{ddoc}/_view/{view}
+		return len(parts) < 2 || parts[len(parts)-2] != "_view"
+	}
+	return true
+}
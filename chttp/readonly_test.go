@@ -0,0 +1,58 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestMutating(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{method: "GET", path: "/db/doc"},
+		{method: "HEAD", path: "/db/doc"},
+		{method: "OPTIONS", path: "/"},
+		{method: "PUT", path: "/db/doc", expected: true},
+		{method: "DELETE", path: "/db/doc?rev=1-xxx", expected: true},
+		{method: "COPY", path: "/db/doc", expected: true},
+		{method: "POST", path: "/db", expected: true},
+		{method: "POST", path: "/db/_bulk_docs", expected: true},
+		{method: "POST", path: "/db/_purge", expected: true},
+		{method: "POST", path: "/_replicate", expected: true},
+		{method: "POST", path: "/db/_find"},
+		{method: "POST", path: "/db/_all_docs?include_docs=true"},
+		{method: "POST", path: "/db/_design/foo/_view/bar"},
+		{method: "POST", path: "/db/_temp_view"},
+		{method: "POST", path: "/db/_all_docs/queries"},
+		{method: "POST", path: "/db/_design/foo/_view/bar/queries"},
+		{method: "POST", path: "/db/queries", expected: true},
+		{method: "POST", path: "/queries", expected: true},
+		{method: "POST", path: "/_session"},
+		{method: "DELETE", path: "/_session"},
+	}
+	for _, test := range tests {
+		if result := mutating(test.method, test.path); result != test.expected {
+			t.Errorf("mutating(%s, %s) = %t, expected %t", test.method, test.path, result, test.expected)
+		}
+	}
+}
+
+func TestDoReqReadOnly(t *testing.T) {
+	c := newCustomClient(func(_ *http.Request) (*http.Response, error) {
+		t.Fatal("Request should not be sent")
+		return nil, nil
+	})
+	c.ReadOnly = true
+	_, err := c.DoReq(context.Background(), kivik.MethodPut, "/db/doc", nil)
+	if _, ok := err.(*ReadOnlyError); !ok {
+		t.Errorf("Unexpected error type: %T", err)
+	}
+	testy.StatusError(t, "chttp: PUT /db/doc refused by read-only client", kivik.StatusForbidden, err)
+}
@@ -0,0 +1,41 @@
+package chttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// RequestIDHeader is the header used to send the client's ID for each
+	// request.
+	RequestIDHeader = "X-Request-ID"
+
+	// ServerRequestIDHeader is the header in which CouchDB reports its own ID
+	// for a request, as used in the server's logs.
+	ServerRequestIDHeader = "X-Couch-Request-ID"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context which causes requests made with it to be
+// sent with the request ID id, rather than a newly generated one.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID attached to ctx by WithRequestID, or an
+// empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random 16-character hex request ID.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
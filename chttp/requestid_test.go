@@ -0,0 +1,52 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	var sent string
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		sent = req.Header.Get(RequestIDHeader)
+		header := http.Header{}
+		header.Set(ServerRequestIDHeader, "abc123")
+		return &http.Response{
+			StatusCode: kivik.StatusNotFound,
+			Header:     header,
+			Body:       Body(""),
+			Request:    req,
+		}, nil
+	})
+	t.Run("generated", func(t *testing.T) {
+		resp, err := c.DoReq(context.Background(), kivik.MethodGet, "/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sent) != 16 {
+			t.Errorf("Unexpected generated request ID: %q", sent)
+		}
+		httpErr, ok := ResponseError(resp).(*HTTPError)
+		if !ok {
+			t.Fatalf("Expected *HTTPError")
+		}
+		if httpErr.RequestID != sent {
+			t.Errorf("Unexpected RequestID in error: %s", httpErr.RequestID)
+		}
+		if httpErr.ServerRequestID != "abc123" {
+			t.Errorf("Unexpected ServerRequestID in error: %s", httpErr.ServerRequestID)
+		}
+	})
+	t.Run("from context", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "my-request")
+		if _, err := c.DoReq(ctx, kivik.MethodGet, "/foo", nil); err != nil {
+			t.Fatal(err)
+		}
+		if sent != "my-request" {
+			t.Errorf("Unexpected request ID: %s", sent)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package chttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTimings summarizes the latency of a single request. Phases which did
+// not occur, such as DNS resolution on a reused connection, are zero.
+type RequestTimings struct {
+	Method    string
+	Path      string
+	RequestID string
+	Start     time.Time
+
+	// ConnReused is true if the request was sent over a previously idle
+	// connection.
+	ConnReused bool
+
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+
+	// FirstByte is the time from Start until the first byte of the response
+	// was received.
+	FirstByte time.Duration
+}
+
+// TimingsFunc receives the timings of each request made by a Client, once the
+// response headers have been read, or the request has failed.
+type TimingsFunc func(*RequestTimings)
+
+// timingsTracer collects RequestTimings from httptrace events. The hooks may be
+// called concurrently, when dialing several addresses at once.
+type timingsTracer struct {
+	mu                            sync.Mutex
+	clock                         Clock
+	timings                       *RequestTimings
+	dnsStart, connStart, tlsStart time.Time
+}
+
+// snapshot returns a copy of the timings collected so far.
+func (tr *timingsTracer) snapshot() *RequestTimings {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	t := *tr.timings
+	return &t
+}
+
+// since returns the time elapsed since t.
+func (tr *timingsTracer) since(t time.Time) time.Duration {
+	return tr.clock.Now().Sub(t)
+}
+
+// withContext returns a context which records the request's timings. Any
+// httptrace.ClientTrace already attached to ctx continues to receive events.
+func (tr *timingsTracer) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.mu.Lock()
+			tr.timings.ConnReused = info.Reused
+			tr.mu.Unlock()
+		},
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			tr.mu.Lock()
+			tr.dnsStart = tr.clock.Now()
+			tr.mu.Unlock()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			tr.mu.Lock()
+			tr.timings.DNS = tr.since(tr.dnsStart)
+			tr.mu.Unlock()
+		},
+		ConnectStart: func(_, _ string) {
+			tr.mu.Lock()
+			if tr.connStart.IsZero() {
+				tr.connStart = tr.clock.Now()
+			}
+			tr.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			tr.mu.Lock()
+			if err == nil && tr.timings.Connect == 0 {
+				tr.timings.Connect = tr.since(tr.connStart)
+			}
+			tr.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			tr.mu.Lock()
+			tr.tlsStart = tr.clock.Now()
+			tr.mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			tr.mu.Lock()
+			tr.timings.TLSHandshake = tr.since(tr.tlsStart)
+			tr.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tr.mu.Lock()
+			tr.timings.FirstByte = tr.since(tr.timings.Start)
+			tr.mu.Unlock()
+		},
+	})
+}
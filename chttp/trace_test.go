@@ -0,0 +1,56 @@
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestTimingsFunc(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(kivik.StatusOK)
+	}))
+	defer s.Close()
+	c, err := New(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timings []*RequestTimings
+	c.TimingsFunc = func(t *RequestTimings) {
+		timings = append(timings, t)
+	}
+	var gotFirstByte bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { gotFirstByte = true },
+	})
+	for i := 0; i < 2; i++ {
+		resp, err := c.DoReq(ctx, kivik.MethodGet, "/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	if !gotFirstByte {
+		t.Errorf("Caller's ClientTrace was not called")
+	}
+	if len(timings) != 2 {
+		t.Fatalf("Expected 2 timings, got %d", len(timings))
+	}
+	first, second := timings[0], timings[1]
+	if first.Method != kivik.MethodGet || first.Path != "/foo" {
+		t.Errorf("Unexpected request: %s %s", first.Method, first.Path)
+	}
+	if first.ConnReused || first.Connect == 0 {
+		t.Errorf("Expected a new connection for the first request: %+v", first)
+	}
+	if !second.ConnReused || second.Connect != 0 {
+		t.Errorf("Expected a reused connection for the second request: %+v", second)
+	}
+	if first.FirstByte == 0 || second.FirstByte == 0 {
+		t.Errorf("Expected time to first byte to be recorded")
+	}
+}
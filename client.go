@@ -13,13 +13,16 @@ import (
 func (c *client) AllDBs(ctx context.Context, _ map[string]interface{}) ([]string, error) {
 	var allDBs []string
 	_, err := c.DoJSON(ctx, kivik.MethodGet, "/_all_dbs", nil, &allDBs)
-	return allDBs, err
+	return c.filterDBs(allDBs), err
 }
 
 func (c *client) DBExists(ctx context.Context, dbName string, _ map[string]interface{}) (bool, error) {
 	if dbName == "" {
 		return false, missingArg("dbName")
 	}
+	if err := c.checkDB(dbName); err != nil {
+		return false, err
+	}
 	_, err := c.DoError(ctx, kivik.MethodHead, dbName, nil)
 	if kivik.StatusCode(err) == kivik.StatusNotFound {
 		return false, nil
@@ -27,11 +30,22 @@ func (c *client) DBExists(ctx context.Context, dbName string, _ map[string]inter
 	return err == nil, err
 }
 
-func (c *client) CreateDB(ctx context.Context, dbName string, _ map[string]interface{}) error {
+func (c *client) CreateDB(ctx context.Context, dbName string, options map[string]interface{}) error {
 	if dbName == "" {
 		return missingArg("dbName")
 	}
-	_, err := c.DoError(ctx, kivik.MethodPut, dbName, nil)
+	if err := c.checkDB(dbName); err != nil {
+		return err
+	}
+	path := dbName
+	if len(options) > 0 {
+		params, err := optionsToParams(options)
+		if err != nil {
+			return err
+		}
+		path += "?" + params.Encode()
+	}
+	_, err := c.DoError(ctx, kivik.MethodPut, path, nil)
 	return err
 }
 
@@ -39,24 +53,44 @@ func (c *client) DestroyDB(ctx context.Context, dbName string, _ map[string]inte
 	if dbName == "" {
 		return missingArg("dbName")
 	}
+	if err := c.checkDB(dbName); err != nil {
+		return err
+	}
 	_, err := c.DoError(ctx, kivik.MethodDelete, dbName, nil)
 	return err
 }
 
-func (c *client) DBUpdates() (updates driver.DBUpdates, err error) {
-	resp, err := c.DoReq(context.Background(), kivik.MethodGet, "/_db_updates?feed=continuous&since=now", nil)
+var _ driver.DBUpdater = &client{}
+
+// DBUpdates follows the continuous /_db_updates feed, from now, reporting the
+// creation, update and deletion of databases. Cancelling ctx, or closing the
+// feed, stops it.
+func (c *client) DBUpdates(ctx context.Context) (updates driver.DBUpdates, err error) {
+	if c.feeds.isClosed() {
+		return nil, errClosed
+	}
+	resp, err := c.DoReq(ctx, kivik.MethodGet, "/_db_updates?feed=continuous&since=now", nil)
 	if err != nil {
 		return nil, err
 	}
 	if err := chttp.ResponseError(resp); err != nil {
 		return nil, err
 	}
-	return newUpdates(resp.Body), nil
+	body, err := c.feeds.track(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	feed := newUpdates(body)
+	feed.allowed = c.dbAllowed
+	return feed, nil
 }
 
 type couchUpdates struct {
 	body io.ReadCloser
 	dec  *json.Decoder
+	// allowed, if set, reports whether updates to a database are to be
+	// reported.
+	allowed func(dbName string) bool
 }
 
 var _ driver.DBUpdates = &couchUpdates{}
@@ -69,7 +103,14 @@ func newUpdates(body io.ReadCloser) *couchUpdates {
 }
 
 func (u *couchUpdates) Next(update *driver.DBUpdate) error {
-	return u.dec.Decode(update)
+	for {
+		if err := u.dec.Decode(update); err != nil {
+			return err
+		}
+		if u.allowed == nil || u.allowed(update.DBName) {
+			return nil
+		}
+	}
 }
 
 func (u *couchUpdates) Close() error {
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/flimzy/diff"
 	"github.com/flimzy/testy"
 
+	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
 	"github.com/go-kivik/kivik/driver"
 )
@@ -251,7 +253,7 @@ func TestDBUpdates(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			result, err := test.client.DBUpdates()
+			result, err := test.client.DBUpdates(context.Background())
 			testy.StatusError(t, test.err, test.status, err)
 			if _, ok := result.(*couchUpdates); !ok {
 				t.Errorf("Unexpected type returned: %t", result)
@@ -260,6 +262,38 @@ func TestDBUpdates(t *testing.T) {
 	}
 }
 
+func TestDBUpdatesCancel(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(kivik.StatusOK)
+		_, _ = w.Write([]byte(`{"db_name":"tenant1","type":"created","seq":"1-xxx"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+	c, err := chttp.New(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	feed, err := (&client{Client: c}).DBUpdates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close() // nolint: errcheck
+	update := &driver.DBUpdate{}
+	if err := feed.Next(update); err != nil {
+		t.Fatal(err)
+	}
+	if d := diff.Interface(&driver.DBUpdate{DBName: "tenant1", Type: "created", Seq: "1-xxx"}, update); d != nil {
+		t.Error(d)
+	}
+	cancel()
+	if err := feed.Next(update); err == nil {
+		t.Error("Expected an error after cancellation")
+	}
+}
+
 func TestUpdatesNext(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,101 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// errClosed is returned when a feed is opened with a client, or database
+// handle, which has been closed.
+var errClosed = errors.Status(kivik.StatusUnknownError, "kivik: client closed")
+
+// feedSet tracks the open bodies of streaming responses, such as changes
+// feeds, so that they can be closed en masse. The zero value is ready to use.
+type feedSet struct {
+	mu     sync.Mutex
+	open   map[*feed]struct{}
+	closed bool
+}
+
+// feed is a tracked response body, which removes itself from its set when
+// closed.
+type feed struct {
+	io.ReadCloser
+	set  *feedSet
+	once sync.Once
+	err  error
+}
+
+func (f *feed) Close() error {
+	f.once.Do(func() {
+		f.set.remove(f)
+		f.err = f.ReadCloser.Close()
+	})
+	return f.err
+}
+
+// track registers body with the set. If the set has already been closed, body
+// is closed, and an error is returned.
+func (s *feedSet) track(body io.ReadCloser) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		_ = body.Close()
+		return nil, errClosed
+	}
+	if s.open == nil {
+		s.open = make(map[*feed]struct{})
+	}
+	f := &feed{ReadCloser: body, set: s}
+	s.open[f] = struct{}{}
+	return f, nil
+}
+
+// isClosed returns true if the set has been closed.
+func (s *feedSet) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *feedSet) remove(f *feed) {
+	s.mu.Lock()
+	delete(s.open, f)
+	s.mu.Unlock()
+}
+
+// close closes all open feeds, and prevents new ones from being tracked.
+func (s *feedSet) close() error {
+	s.mu.Lock()
+	s.closed = true
+	open := make([]*feed, 0, len(s.open))
+	for f := range s.open {
+		open = append(open, f)
+	}
+	s.mu.Unlock()
+	var err error
+	for _, f := range open {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close cancels any outstanding changes or DB updates feeds, and closes idle
+// connections. The client must not be used after it has been closed.
+func (c *client) Close(_ context.Context) error {
+	err := c.feeds.close()
+	c.Client.CloseIdleConnections()
+	return err
+}
+
+// Close cancels any outstanding changes feeds opened on the database. The
+// parent client, and other database handles, remain usable.
+func (d *db) Close(_ context.Context) error {
+	return d.feeds.close()
+}
@@ -0,0 +1,66 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestFeedSet(t *testing.T) {
+	var s feedSet
+	a, b := &closeTracker{ReadCloser: Body("")}, &closeTracker{ReadCloser: Body("")}
+	fa, err := s.track(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.track(b); err != nil {
+		t.Fatal(err)
+	}
+	if err = fa.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.open) != 1 {
+		t.Errorf("Expected 1 open feed, got %d", len(s.open))
+	}
+	if err = s.close(); err != nil {
+		t.Fatal(err)
+	}
+	if !a.closed || !b.closed {
+		t.Errorf("Expected all feeds to be closed")
+	}
+	c := &closeTracker{ReadCloser: Body("")}
+	_, err = s.track(c)
+	if !c.closed {
+		t.Errorf("Expected feed to be closed after client closed")
+	}
+	testy.StatusError(t, "kivik: client closed", kivik.StatusUnknownError, err)
+}
+
+func TestClientClose(t *testing.T) {
+	body := &closeTracker{ReadCloser: Body("")}
+	client := newTestClient(&http.Response{
+		StatusCode: 200,
+		Body:       body,
+	}, nil)
+	d := &db{client: client, dbName: "testdb"}
+	changes, err := d.Changes(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !body.closed {
+		t.Errorf("Expected changes feed to be closed")
+	}
+	if err = changes.Next(&driver.Change{}); err == nil {
+		t.Errorf("Expected an error reading from a closed feed")
+	}
+	_, err = d.Changes(context.Background(), nil)
+	testy.StatusError(t, "kivik: client closed", kivik.StatusUnknownError, err)
+}
@@ -0,0 +1,229 @@
+package couchdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// CollateKeys compares two JSON-encoded view keys, such as the Key of a
+// driver.Row, in the order in which CouchDB sorts them, returning -1, 0 or 1
+// as a sorts before, equal to, or after b. It is intended for merging the
+// results of several view queries on the client.
+//
+// Values of different types sort as null, false, true, numbers, strings,
+// arrays, then objects. Arrays are compared element by element, and objects
+// member by member, in the order in which their members appear, with the
+// shorter sorting first when one is a prefix of the other. Strings are
+// compared with CollateStrings.
+func CollateKeys(a, b []byte) (int, error) {
+	av, err := decodeKey(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := decodeKey(b)
+	if err != nil {
+		return 0, err
+	}
+	return collate(av, bv), nil
+}
+
+// CollateStrings compares two strings approximately as the ICU collation used
+// by CouchDB does: whitespace and punctuation sort before digits, which sort
+// before letters; letters are compared case-insensitively first, with lower
+// case sorting before upper case only where the strings are otherwise equal.
+// Exact ICU ordering, including that of accented characters, is not
+// reproduced.
+func CollateStrings(a, b string) int {
+	// Primary strength: character class and case-folded character.
+	if c := collateRunes(a, b, func(r rune) rune { return unicode.ToLower(r) }); c != 0 {
+		return c
+	}
+	// Tertiary strength: lower case before upper case.
+	if c := collateRunes(a, b, func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return 1
+		}
+		return 0
+	}); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// collateRunes compares a and b rune by rune, after mapping each rune with
+// weight.
+func collateRunes(a, b string, weight func(rune) rune) int {
+	for a != "" && b != "" {
+		ar, an := utf8.DecodeRuneInString(a)
+		br, bn := utf8.DecodeRuneInString(b)
+		if c := compareInts(runeClass(ar), runeClass(br)); c != 0 {
+			return c
+		}
+		if c := compareInts(int(weight(ar)), int(weight(br))); c != 0 {
+			return c
+		}
+		a, b = a[an:], b[bn:]
+	}
+	return compareInts(len(a), len(b))
+}
+
+// runeClass orders whitespace and punctuation before digits before letters.
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r):
+		return 2
+	case unicode.IsDigit(r):
+		return 1
+	}
+	return 0
+}
+
+// collation type ranks, in sort order.
+const (
+	collateNull = iota
+	collateFalse
+	collateTrue
+	collateNumber
+	collateString
+	collateArray
+	collateObject
+)
+
+// keyMember is a single member of a JSON object, with its members kept in
+// their original order.
+type keyMember struct {
+	Name  string
+	Value interface{}
+}
+
+// decodeKey decodes a JSON value into nil, bool, float64, string,
+// []interface{}, or []keyMember for objects.
+func decodeKey(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	value, err := decodeKeyValue(dec)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.Status(kivik.StatusBadRequest, "kivik: invalid key: trailing data")
+	}
+	return value, nil
+}
+
+func decodeKeyValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Number:
+		return t.Float64()
+	case json.Delim:
+		switch t {
+		case '[':
+			array := []interface{}{}
+			for dec.More() {
+				value, err := decodeKeyValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+			_, err := dec.Token()
+			return array, err
+		case '{':
+			object := []keyMember{}
+			for dec.More() {
+				name, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeKeyValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				object = append(object, keyMember{Name: name.(string), Value: value})
+			}
+			_, err := dec.Token()
+			return object, err
+		}
+	}
+	return tok, nil
+}
+
+func collationRank(v interface{}) int {
+	switch t := v.(type) {
+	case nil:
+		return collateNull
+	case bool:
+		if t {
+			return collateTrue
+		}
+		return collateFalse
+	case float64:
+		return collateNumber
+	case string:
+		return collateString
+	case []interface{}:
+		return collateArray
+	}
+	return collateObject
+}
+
+func collate(a, b interface{}) int {
+	ar, br := collationRank(a), collationRank(b)
+	if ar != br {
+		return compareInts(ar, br)
+	}
+	switch ar {
+	case collateNumber:
+		af, bf := a.(float64), b.(float64)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case collateString:
+		return CollateStrings(a.(string), b.(string))
+	case collateArray:
+		aa, ba := a.([]interface{}), b.([]interface{})
+		for i := 0; i < len(aa) && i < len(ba); i++ {
+			if c := collate(aa[i], ba[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(aa), len(ba))
+	case collateObject:
+		ao, bo := a.([]keyMember), b.([]keyMember)
+		for i := 0; i < len(ao) && i < len(bo); i++ {
+			if c := CollateStrings(ao[i].Name, bo[i].Name); c != 0 {
+				return c
+			}
+			if c := collate(ao[i].Value, bo[i].Value); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(ao), len(bo))
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
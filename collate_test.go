@@ -0,0 +1,81 @@
+package couchdb
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+// collationOrder is the example ordering from the CouchDB documentation on
+// view collation, with some additions.
+var collationOrder = []string{
+	`null`,
+	`false`,
+	`true`,
+	`1`,
+	`2`,
+	`3.0`,
+	`4`,
+	`" "`,
+	`"-"`,
+	`"1"`,
+	`"a"`,
+	`"A"`,
+	`"aa"`,
+	`"b"`,
+	`"B"`,
+	`"ba"`,
+	`"bb"`,
+	`["a"]`,
+	`["b"]`,
+	`["b","c"]`,
+	`["b","c", "a"]`,
+	`["b","d"]`,
+	`["b","d", "e"]`,
+	`{"a":1}`,
+	`{"a":2}`,
+	`{"b":1}`,
+	`{"b":2}`,
+	`{"b":2, "a":1}`,
+	`{"b":2, "c":2}`,
+}
+
+func TestCollateKeys(t *testing.T) {
+	for i := range collationOrder {
+		for j := range collationOrder {
+			expected := compareInts(i, j)
+			result, err := CollateKeys([]byte(collationOrder[i]), []byte(collationOrder[j]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != expected {
+				t.Errorf("CollateKeys(%s, %s) = %d, expected %d", collationOrder[i], collationOrder[j], result, expected)
+			}
+		}
+	}
+}
+
+func TestCollateKeysEquivalent(t *testing.T) {
+	if c, _ := CollateKeys([]byte(`[1, {"a": 3.0}]`), []byte(`[1.0,{"a":3}]`)); c != 0 {
+		t.Errorf("Expected equal keys, got %d", c)
+	}
+}
+
+func TestCollateKeysInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		err  string
+	}{
+		{name: "empty a", a: ``, b: `1`, err: "EOF"},
+		{name: "invalid b", a: `1`, b: `1 2`, err: "kivik: invalid key: trailing data"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := CollateKeys([]byte(test.a), []byte(test.b))
+			testy.StatusError(t, test.err, kivik.StatusBadRequest, err)
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/tleyden/couchdb/chttp"
+)
+
+// maxRequestSize is an Authenticator which limits the size of request bodies.
+type maxRequestSize int64
+
+var _ Authenticator = maxRequestSize(0)
+
+func (s maxRequestSize) auth(_ context.Context, c *client) error {
+	c.Client.MaxRequestSize = int64(s)
+	return nil
+}
+
+// SetMaxRequestSize returns an authenticator which limits request bodies to
+// max bytes, so that oversized documents fail locally with a descriptive
+// error, rather than with an opaque 413 response from the server. BulkDocs
+// requests which would exceed the limit are split into several requests.
+// A value of 0 removes the limit.
+//
+// Example:
+//
+//     client, _ := kivik.New( ... )
+//     client.Authenticate(couchdb.SetMaxRequestSize(4 << 20))
+func SetMaxRequestSize(max int64) Authenticator {
+	return maxRequestSize(max)
+}
+
+// timingsAuth is an Authenticator which sets a client's TimingsFunc.
+type timingsAuth chttp.TimingsFunc
+
+var _ Authenticator = timingsAuth(nil)
+
+func (fn timingsAuth) auth(_ context.Context, c *client) error {
+	c.Client.TimingsFunc = chttp.TimingsFunc(fn)
+	return nil
+}
+
+// SetTimingsFunc returns an authenticator which causes fn to be called with a
+// summary of the DNS, connect, TLS and time-to-first-byte latency of every
+// request made by the client. To trace individual calls in more detail, attach
+// an httptrace.ClientTrace to the context passed to that call instead.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetTimingsFunc(func(t *chttp.RequestTimings) {
+//         log.Printf("%s %s: first byte after %s", t.Method, t.Path, t.FirstByte)
+//     }))
+func SetTimingsFunc(fn chttp.TimingsFunc) Authenticator {
+	return timingsAuth(fn)
+}
+
+// quotaAuth is an Authenticator which sets the Quota for a database.
+type quotaAuth struct {
+	dbName string
+	quota  *Quota
+}
+
+var _ Authenticator = &quotaAuth{}
+
+func (a *quotaAuth) auth(_ context.Context, c *client) error {
+	c.quotas.set(a.dbName, a.quota)
+	return nil
+}
+
+// SetQuota returns an authenticator which limits writes to dbName according
+// to q. Passing a nil q removes any quota.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetQuota("tenant1", &couchdb.Quota{MaxDocs: 10000}))
+func SetQuota(dbName string, q *Quota) Authenticator {
+	return &quotaAuth{dbName: dbName, quota: q}
+}
+
+// queryCacheAuth is an Authenticator which enables query result caching.
+type queryCacheAuth struct {
+	config *QueryCache
+}
+
+var _ Authenticator = &queryCacheAuth{}
+
+func (a *queryCacheAuth) auth(_ context.Context, c *client) error {
+	if a.config == nil {
+		c.queryCache = nil
+		return nil
+	}
+	c.queryCache = newQueryCache(*a.config)
+	return nil
+}
+
+// SetQueryCache returns an authenticator which enables the caching of query
+// results, as configured by config. Passing nil disables caching, and discards
+// any cached results.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetQueryCache(&couchdb.QueryCache{TTL: 5 * time.Minute}))
+func SetQueryCache(config *QueryCache) Authenticator {
+	return &queryCacheAuth{config: config}
+}
+
+// readOnlyAuth is an Authenticator which sets a client's read-only mode.
+type readOnlyAuth bool
+
+var _ Authenticator = readOnlyAuth(false)
+
+func (ro readOnlyAuth) auth(_ context.Context, c *client) error {
+	c.Client.ReadOnly = bool(ro)
+	return nil
+}
+
+// SetReadOnly returns an authenticator which, if readOnly is true, causes the
+// client to refuse any request which may modify data on the server, returning
+// a *chttp.ReadOnlyError without contacting the server. Queries sent by POST,
+// such as Find and view queries with keys, and authentication requests, are
+// still permitted.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetReadOnly(true))
+func SetReadOnly(readOnly bool) Authenticator {
+	return readOnlyAuth(readOnly)
+}
+
+// allowedDBsAuth is an Authenticator which restricts the databases a client
+// may access.
+type allowedDBsAuth []string
+
+var _ Authenticator = allowedDBsAuth(nil)
+
+func (a allowedDBsAuth) auth(_ context.Context, c *client) error {
+	if len(a) == 0 {
+		c.allowedDBs = nil
+		return nil
+	}
+	allowed := make(map[string]bool, len(a))
+	for _, dbName := range a {
+		allowed[dbName] = true
+	}
+	c.allowedDBs = allowed
+	return nil
+}
+
+// SetAllowedDBs returns an authenticator which restricts the client to the
+// databases named in dbNames. Opening, creating, destroying, querying or
+// following any other database, or replicating to or from one on the same
+// server, fails with a Forbidden error. AllDBs lists only the allowed
+// databases, and DBUpdates and GlobalChanges report only their events.
+// Databases used internally by the driver, such as _replicator, and requests
+// made with DoRequest, are not restricted. Calling it with no names removes
+// the restriction.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetAllowedDBs("tenant1", "tenant1-archive"))
+func SetAllowedDBs(dbNames ...string) Authenticator {
+	return allowedDBsAuth(dbNames)
+}
+
+// clockAuth is an Authenticator which sets a client's Clock.
+type clockAuth struct {
+	clock chttp.Clock
+}
+
+var _ Authenticator = &clockAuth{}
+
+func (a *clockAuth) auth(_ context.Context, c *client) error {
+	c.Client.Clock = a.clock
+	return nil
+}
+
+// SetClock returns an authenticator which causes the client to take the
+// current time, and to wait between retries and polls, using clock rather
+// than the time package, so that tests may control time. Passing nil restores
+// the chttp.SystemClock.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetClock(fakeClock))
+func SetClock(clock chttp.Clock) Authenticator {
+	return &clockAuth{clock: clock}
+}
+
+// clock returns the client's Clock, or the chttp.SystemClock.
+func (c *client) clock() chttp.Clock {
+	if c == nil || c.Client == nil || c.Client.Clock == nil {
+		return chttp.SystemClock
+	}
+	return c.Client.Clock
+}
+
+// requestIDAuth is an Authenticator which sets a client's RequestIDFunc.
+type requestIDAuth func() string
+
+var _ Authenticator = requestIDAuth(nil)
+
+func (fn requestIDAuth) auth(_ context.Context, c *client) error {
+	c.Client.RequestIDFunc = fn
+	return nil
+}
+
+// SetRequestIDFunc returns an authenticator which causes fn to generate the
+// request ID sent with each request, in place of a random one, for instance to
+// produce predictable IDs in tests. IDs attached with chttp.WithRequestID take
+// precedence. Passing nil restores random IDs.
+//
+// Example:
+//
+//     var n int
+//     client.Authenticate(couchdb.SetRequestIDFunc(func() string {
+//         n++
+//         return fmt.Sprintf("test-%d", n)
+//     }))
+func SetRequestIDFunc(fn func() string) Authenticator {
+	return requestIDAuth(fn)
+}
+
+// policyAuth is an Authenticator which sets the Policy for a class of
+// requests.
+type policyAuth struct {
+	class  chttp.EndpointClass
+	policy *chttp.Policy
+}
+
+var _ Authenticator = &policyAuth{}
+
+func (a *policyAuth) auth(_ context.Context, c *client) error {
+	if a.policy == nil {
+		delete(c.Client.Policies, a.class)
+		return nil
+	}
+	if c.Client.Policies == nil {
+		c.Client.Policies = make(map[chttp.EndpointClass]*chttp.Policy)
+	}
+	c.Client.Policies[a.class] = a.policy
+	return nil
+}
+
+// SetPolicy returns an authenticator which configures the timeout and
+// retries of requests of class, as suitable values differ greatly between,
+// say, document reads and changes feeds. Passing a nil policy restores the
+// default, of a single attempt without a timeout.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetPolicy(chttp.EndpointRead, &chttp.Policy{
+//         Timeout:    5 * time.Second,
+//         MaxRetries: 3,
+//         RetryDelay: 100 * time.Millisecond,
+//     }))
+//     client.Authenticate(couchdb.SetPolicy(chttp.EndpointAttachments, &chttp.Policy{
+//         Timeout: 5 * time.Minute,
+//     }))
+func SetPolicy(class chttp.EndpointClass, policy *chttp.Policy) Authenticator {
+	return &policyAuth{class: class, policy: policy}
+}
+
+// nodesAuth is an Authenticator which balances requests across the nodes of a
+// cluster.
+type nodesAuth []string
+
+var _ Authenticator = nodesAuth(nil)
+
+func (nodes nodesAuth) auth(_ context.Context, c *client) error {
+	b, err := chttp.NewBalancer(c.Client.Client.Transport, nodes...)
+	if err != nil {
+		return err
+	}
+	b.Clock = c.Client.Clock
+	c.Client.Client.Transport = b
+	return nil
+}
+
+// SetNodes returns an authenticator which distributes the client's requests
+// across the nodes of a cluster, given by their URLs, in place of the host of
+// the DSN. Reads are sent to each node in turn, while writes are pinned to one
+// node for each session set with chttp.WithSession, failing over to the next
+// node if it cannot be reached. See chttp.Balancer for details. As it wraps the
+// client's transport, SetTransport must be called first, if at all.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetNodes(
+//         "http://couch1.example.com:5984/",
+//         "http://couch2.example.com:5984/",
+//         "http://couch3.example.com:5984/",
+//     ))
+//     ctx := chttp.WithSession(context.Background(), userID)
+//     db.Put(ctx, docID, doc)
+func SetNodes(nodes ...string) Authenticator {
+	return nodesAuth(nodes)
+}
+
+// passwordHashingAuth is an Authenticator which enables client-side password
+// hashing.
+type passwordHashingAuth int
+
+var _ Authenticator = passwordHashingAuth(0)
+
+func (i passwordHashingAuth) auth(_ context.Context, c *client) error {
+	c.passwordIterations = int(i)
+	return nil
+}
+
+// SetPasswordHashing returns an authenticator which causes PutUser to hash
+// passwords before they are sent, so that the plaintext password never
+// reaches the server. User documents are written in the pbkdf2 password
+// scheme, using iterations rounds, or, for servers older than CouchDB 1.3,
+// which do not support it, in the password_sha scheme. Passing 0 restores
+// sending passwords to be hashed by the server, where it supports doing so.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetPasswordHashing(10))
+func SetPasswordHashing(iterations int) Authenticator {
+	return passwordHashingAuth(iterations)
+}
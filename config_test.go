@@ -0,0 +1,109 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+)
+
+func TestSetMaxRequestSize(t *testing.T) {
+	c := &client{Client: &chttp.Client{}}
+	if err := c.Authenticate(context.Background(), SetMaxRequestSize(1024)); err != nil {
+		t.Fatal(err)
+	}
+	if c.Client.MaxRequestSize != 1024 {
+		t.Errorf("Unexpected MaxRequestSize: %d", c.Client.MaxRequestSize)
+	}
+}
+
+func TestSetTimingsFunc(t *testing.T) {
+	c := &client{Client: &chttp.Client{}}
+	var called bool
+	fn := func(_ *chttp.RequestTimings) { called = true }
+	if err := c.Authenticate(context.Background(), SetTimingsFunc(fn)); err != nil {
+		t.Fatal(err)
+	}
+	if c.Client.TimingsFunc == nil {
+		t.Fatal("TimingsFunc not set")
+	}
+	c.Client.TimingsFunc(&chttp.RequestTimings{})
+	if !called {
+		t.Errorf("Expected TimingsFunc to be called")
+	}
+}
+
+func TestSetReadOnly(t *testing.T) {
+	c := &client{Client: &chttp.Client{}}
+	if err := c.Authenticate(context.Background(), SetReadOnly(true)); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Client.ReadOnly {
+		t.Errorf("Expected ReadOnly to be set")
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	c := &client{Client: &chttp.Client{}}
+	if c.clock() != chttp.SystemClock {
+		t.Errorf("Expected the SystemClock by default")
+	}
+	clock := &fakeClock{}
+	if err := c.Authenticate(context.Background(), SetClock(clock)); err != nil {
+		t.Fatal(err)
+	}
+	if c.clock() != clock {
+		t.Errorf("Expected the configured clock")
+	}
+	if err := c.Authenticate(context.Background(), SetClock(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if c.clock() != chttp.SystemClock {
+		t.Errorf("Expected the SystemClock to be restored")
+	}
+}
+
+func TestSetRequestIDFunc(t *testing.T) {
+	c := &client{Client: &chttp.Client{}}
+	if err := c.Authenticate(context.Background(), SetRequestIDFunc(func() string { return "test-1" })); err != nil {
+		t.Fatal(err)
+	}
+	if c.Client.RequestIDFunc == nil {
+		t.Fatal("RequestIDFunc not set")
+	}
+	if id := c.Client.RequestIDFunc(); id != "test-1" {
+		t.Errorf("Unexpected request ID: %s", id)
+	}
+}
+
+func TestSetPolicy(t *testing.T) {
+	c := &client{Client: &chttp.Client{}}
+	policy := &chttp.Policy{MaxRetries: 3}
+	if err := c.Authenticate(context.Background(), SetPolicy(chttp.EndpointRead, policy)); err != nil {
+		t.Fatal(err)
+	}
+	if c.Client.Policies[chttp.EndpointRead] != policy {
+		t.Errorf("Expected the read policy to be set")
+	}
+	if err := c.Authenticate(context.Background(), SetPolicy(chttp.EndpointRead, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Client.Policies[chttp.EndpointRead]; ok {
+		t.Errorf("Expected the read policy to be removed")
+	}
+}
+
+func TestSetNodes(t *testing.T) {
+	c := &client{Client: &chttp.Client{Client: &http.Client{}}}
+	if err := c.Authenticate(context.Background(), SetNodes()); kivik.StatusCode(err) != kivik.StatusBadRequest {
+		t.Errorf("Expected a bad request error, got %v", err)
+	}
+	if err := c.Authenticate(context.Background(), SetNodes("http://a.example.com/", "http://b.example.com/")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Client.Client.Transport.(*chttp.Balancer); !ok {
+		t.Errorf("Expected a *chttp.Balancer transport, got %T", c.Client.Client.Transport)
+	}
+}
@@ -15,6 +15,128 @@ const (
 	//
 	//    row, err := db.Get(ctx, "doc_id", kivik.Options(couchdb.OptionIfNoneMatch: "1-xxx"))
 	OptionIfNoneMatch = "If-None-Match"
+
+	// OptionRange sets the Range header on attachment requests, to fetch only
+	// part of the attachment content.
+	//
+	// Example:
+	//
+	//    att, err := db.GetAttachment(ctx, "doc_id", "", "foo.txt", kivik.Options{couchdb.OptionRange: "bytes=100-"})
+	OptionRange = "Range"
+
+	// OptionIfRange sets the If-Range header on attachment requests, so that
+	// the range requested by OptionRange is returned only if the attachment
+	// still has the given digest; otherwise the whole attachment is returned.
+	//
+	// Example:
+	//
+	//    att, err := db.GetAttachment(ctx, "doc_id", "", "foo.txt", kivik.Options{couchdb.OptionRange: "bytes=100-", couchdb.OptionIfRange: digest})
+	OptionIfRange = "If-Range"
+
+	// OptionProgress sets a ProgressFunc to be called as attachment content
+	// is uploaded.
+	//
+	// Example:
+	//
+	//    rev, err := db.PutAttachment(ctx, "doc_id", rev, att, kivik.Options{
+	//        couchdb.OptionProgress: couchdb.ProgressFunc(func(sent int64) {
+	//            fmt.Printf("%d bytes sent\n", sent)
+	//        }),
+	//    })
+	OptionProgress = "progress"
+
+	// OptionRequestTimeout sets a time.Duration after which an individual
+	// request is abandoned, independent of any deadline on the context. Where
+	// the server supports it, such as for changes feeds, the equivalent
+	// server-side timeout is also requested.
+	//
+	// Example:
+	//
+	//    rows, err := db.Query(ctx, "ddoc", "view", kivik.Options{couchdb.OptionRequestTimeout: 30 * time.Second})
+	OptionRequestTimeout = "request_timeout"
+
+	// OptionTombstone, when true, causes Get to return the tombstone of a
+	// deleted document, including any fields preserved by DeletePreserving,
+	// rather than a 404 error.
+	//
+	// Example:
+	//
+	//    row, err := db.Get(ctx, "doc_id", kivik.Options{couchdb.OptionTombstone: true})
+	OptionTombstone = "tombstone"
+
+	// OptionDeletedRev, when true, causes Get of a deleted document to look
+	// up the revision of its tombstone, and report it in the returned
+	// *DeletedError.
+	//
+	// Example:
+	//
+	//    row, err := db.Get(ctx, "doc_id", kivik.Options{couchdb.OptionDeletedRev: true})
+	OptionDeletedRev = "deleted_rev"
+
+	// OptionRetryTimeouts, when true, causes Query and AllDocs to respond to
+	// a server-side timeout by fetching the results in successively smaller
+	// pages, each continuing from the last row received, and stitching them
+	// into a single result set. It is intended for scans of large ranges,
+	// and is not suitable for reduced queries.
+	//
+	// Example:
+	//
+	//    rows, err := db.AllDocs(ctx, kivik.Options{couchdb.OptionRetryTimeouts: true})
+	OptionRetryTimeouts = "retry_timeouts"
+
+	// OptionAllOrNothing, when true, causes BulkDocs to save either all of the
+	// documents or, if any is rejected by a validation function, none of
+	// them. It is supported only by CouchDB 1.x. Conflicts are not checked in
+	// this mode: a document whose _rev is not current is saved anyway, as a
+	// conflicting revision, and reported as successful. When the update is
+	// rejected, results which report no validation error carry status 417
+	// (Expectation Failed) instead, as those documents were not saved either.
+	//
+	// Example:
+	//
+	//    results, err := db.BulkDocs(ctx, docs, kivik.Options{couchdb.OptionAllOrNothing: true})
+	OptionAllOrNothing = "all_or_nothing"
+
+	// OptionWriteResult sets a *WriteResult to be filled in by Put,
+	// CreateDoc, Delete, Copy, BulkDocs, PutAttachment and DeleteAttachment,
+	// to distinguish writes committed by a quorum of copies from those only
+	// accepted by fewer.
+	//
+	// Example:
+	//
+	//    result := new(couchdb.WriteResult)
+	//    rev, err := db.Put(ctx, "doc_id", doc, kivik.Options{couchdb.OptionWriteResult: result})
+	//    if err == nil && result.Accepted {
+	//        log.Printf("write of doc_id not yet durable")
+	//    }
+	OptionWriteResult = "write_result"
+
+	// OptionAccess, when true, causes CreateDB to create a database with
+	// per-document access control, on servers built with the access feature.
+	//
+	// Example:
+	//
+	//    err := client.CreateDB(ctx, "dbname", kivik.Options{couchdb.OptionAccess: true})
+	OptionAccess = "access"
+
+	// OptionDocAccess sets the users and roles, as a []string, which may access
+	// a document written with Put or CreateDoc, in a database with
+	// per-document access control. It is stored in the document's _access
+	// field.
+	//
+	// Example:
+	//
+	//    rev, err := db.Put(ctx, "doc_id", doc, kivik.Options{couchdb.OptionDocAccess: []string{"bob", "staff"}})
+	OptionDocAccess = "_access"
+
+	// OptionPartitioned, when true, causes CreateDB to create a partitioned
+	// database (CouchDB 3.0 and later), in which document IDs take the form
+	// {partition}:{id}, and which may be queried one partition at a time.
+	//
+	// Example:
+	//
+	//    err := client.CreateDB(ctx, "dbname", kivik.Options{couchdb.OptionPartitioned: true})
+	OptionPartitioned = "partitioned"
 )
 
 // optionForceCommit is an unfortunately mispelled version of "full-commit",
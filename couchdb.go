@@ -49,6 +49,27 @@ type client struct {
 	// noFind will be set to true if the Mango _find support is found not to be
 	// supported.
 	noFind bool
+
+	// feeds holds the streaming feeds which are closed by Close().
+	feeds feedSet
+
+	// quotas holds the database quotas set with SetQuota.
+	quotas quotaGuard
+
+	// queryCache, if set by SetQueryCache, caches query results.
+	queryCache *queryCache
+
+	// warningFunc, if set by SetWarningFunc, receives deprecation warnings.
+	warningFunc WarningFunc
+
+	// allowedDBs, if set by SetAllowedDBs, holds the only databases which the
+	// client may access.
+	allowedDBs map[string]bool
+
+	// passwordIterations, if set by SetPasswordHashing, is the number of
+	// PBKDF2 iterations with which PutUser hashes passwords for servers which
+	// support the pbkdf2 scheme.
+	passwordIterations int
 }
 
 var _ driver.Client = &client{}
@@ -104,6 +125,9 @@ func (c *client) DB(_ context.Context, dbName string, _ map[string]interface{})
 	if dbName == "" {
 		return nil, missingArg("dbName")
 	}
+	if err := c.checkDB(dbName); err != nil {
+		return nil, err
+	}
 	return &db{
 		client: c,
 		dbName: dbName,
@@ -0,0 +1,78 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/go-kivik/kivik/driver"
+)
+
+// NamedQuery is a Mango query, as passed to Find, labelled for reporting.
+type NamedQuery struct {
+	Name  string
+	Query interface{}
+}
+
+// QueryCoverage reports the index chosen for a single query.
+type QueryCoverage struct {
+	Name string
+	// DesignDoc and Index identify the index used. For full scans, Index is
+	// "_all_docs", and DesignDoc is empty.
+	DesignDoc string
+	Index     string
+	// FullScan is true when no index covers the query, so every document in
+	// the database is examined.
+	FullScan bool
+}
+
+// CoverageReport is the result of AnalyzeIndexCoverage.
+type CoverageReport struct {
+	Queries []QueryCoverage
+	// Unused lists the indexes not chosen for any of the queries. The
+	// _all_docs index is not included.
+	Unused []driver.Index
+}
+
+// FullScans returns the coverage of those queries which fall back to a full
+// scan.
+func (r *CoverageReport) FullScans() []QueryCoverage {
+	var scans []QueryCoverage
+	for _, q := range r.Queries {
+		if q.FullScan {
+			scans = append(scans, q)
+		}
+	}
+	return scans
+}
+
+// AnalyzeIndexCoverage explains each of queries against the database's
+// indexes, and reports which index each would use, which fall back to full
+// scans, and which indexes none of them use. It is intended for reviewing an
+// application's queries, for example in a CI pipeline.
+func (d *db) AnalyzeIndexCoverage(ctx context.Context, queries []NamedQuery) (*CoverageReport, error) {
+	indexes, err := d.GetIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &CoverageReport{Queries: make([]QueryCoverage, len(queries))}
+	used := make(map[string]bool)
+	for i, q := range queries {
+		plan, err := d.Explain(ctx, q.Query)
+		if err != nil {
+			return nil, err
+		}
+		coverage := QueryCoverage{Name: q.Name}
+		coverage.DesignDoc, _ = plan.Index["ddoc"].(string)
+		coverage.Index, _ = plan.Index["name"].(string)
+		if indexType, _ := plan.Index["type"].(string); indexType == "special" {
+			coverage.FullScan = true
+		}
+		used[coverage.DesignDoc+"/"+coverage.Index] = true
+		report.Queries[i] = coverage
+	}
+	for _, index := range indexes {
+		if index.Type != "special" && !used[index.DesignDoc+"/"+index.Name] {
+			report.Unused = append(report.Unused, index)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,103 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestAnalyzeIndexCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       *db
+		queries  []NamedQuery
+		expected *CoverageReport
+		status   int
+		err      string
+	}{
+		{
+			name: "CouchDB 1.6",
+			db: &db{
+				client: &client{Compat: CompatCouch16},
+			},
+			status: kivik.StatusNotImplemented,
+			err:    "kivik: Find interface not implemented prior to CouchDB 2.0.0",
+		},
+		{
+			name: "coverage",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Body: Body(`{"total_rows":3,"indexes":[
+{"ddoc":null,"name":"_all_docs","type":"special","def":{"fields":[{"_id":"asc"}]}},
+{"ddoc":"_design/by-type","name":"by-type","type":"json","def":{"fields":[{"type":"asc"}]}},
+{"ddoc":"_design/by-date","name":"by-date","type":"json","def":{"fields":[{"date":"asc"}]}}
+]}`),
+					}, nil
+				}
+				var query struct {
+					Selector map[string]interface{} `json:"selector"`
+				}
+				if err := json.NewDecoder(req.Body).Decode(&query); err != nil {
+					return nil, err
+				}
+				if _, ok := query.Selector["type"]; ok {
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Body:       Body(`{"dbname":"testdb","index":{"ddoc":"_design/by-type","name":"by-type","type":"json","def":{"fields":[{"type":"asc"}]}},"selector":{"type":{"$eq":"foo"}}}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"dbname":"testdb","index":{"ddoc":null,"name":"_all_docs","type":"special","def":{"fields":[{"_id":"asc"}]}},"selector":{"name":{"$eq":"bob"}}}`),
+				}, nil
+			}),
+			queries: []NamedQuery{
+				{Name: "by type", Query: map[string]interface{}{"selector": map[string]string{"type": "foo"}}},
+				{Name: "by name", Query: map[string]interface{}{"selector": map[string]string{"name": "bob"}}},
+			},
+			expected: &CoverageReport{
+				Queries: []QueryCoverage{
+					{Name: "by type", DesignDoc: "_design/by-type", Index: "by-type"},
+					{Name: "by name", Index: "_all_docs", FullScan: true},
+				},
+				Unused: []driver.Index{
+					{DesignDoc: "_design/by-date", Name: "by-date", Type: "json", Definition: map[string]interface{}{"fields": []interface{}{map[string]interface{}{"date": "asc"}}}},
+				},
+			},
+		},
+		{
+			name: "explain error",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"indexes":[]}`)}, nil
+				}
+				return nil, errors.New("explain failed")
+			}),
+			queries: []NamedQuery{{Name: "q", Query: map[string]interface{}{}}},
+			status:  kivik.StatusNetworkError,
+			err:     "explain failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := test.db.AnalyzeIndexCoverage(context.Background(), test.queries)
+			testy.StatusErrorRE(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, report); d != nil {
+				t.Error(d)
+			}
+			if scans := report.FullScans(); len(scans) != 1 || scans[0].Name != "by name" {
+				t.Errorf("Unexpected full scans: %v", scans)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
@@ -23,6 +24,15 @@ import (
 type db struct {
 	*client
 	dbName string
+
+	// feeds holds the changes feeds opened on this database, which are closed
+	// by Close(). They are tracked by the parent client, too.
+	feeds feedSet
+
+	// props caches the database properties, once fetched. It should only be
+	// accessed through the Props() and setProps() methods.
+	props   *DBProps
+	propsMU sync.Mutex
 }
 
 var _ driver.DB = &db{}
@@ -37,45 +47,84 @@ func (d *db) path(path string, query url.Values) string {
 	return url.String()
 }
 
+// optionsToParams converts options to query parameters, according to the
+// encoding rules of chttp.EncodeParams.
 func optionsToParams(opts ...map[string]interface{}) (url.Values, error) {
-	params := url.Values{}
-	for _, optsSet := range opts {
-		for key, i := range optsSet {
-			var values []string
-			switch v := i.(type) {
-			case string:
-				values = []string{v}
-			case []string:
-				values = v
-			case bool:
-				values = []string{fmt.Sprintf("%t", v)}
-			case int, uint, uint8, uint16, uint32, uint64, int8, int16, int32, int64:
-				values = []string{fmt.Sprintf("%d", v)}
-			default:
-				return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: invalid type %T for options", i)
-			}
-			for _, value := range values {
-				params.Add(key, value)
-			}
-		}
-	}
-	return params, nil
+	return chttp.EncodeParams(opts...)
 }
 
 // rowsQuery performs a query that returns a rows iterator.
 func (d *db) rowsQuery(ctx context.Context, path string, opts map[string]interface{}) (driver.Rows, error) {
+	retry, err := retryTimeouts(opts)
+	if err != nil {
+		return nil, err
+	}
+	if retry {
+		return d.retryingQuery(ctx, path, opts)
+	}
+	ctx, cancel, err := withTimeout(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateQueryOptions(opts); err != nil {
+		cancel()
+		return nil, err
+	}
+	method, body, err := keysBody(opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 	options, err := optionsToParams(opts)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	resp, err := d.Client.DoReq(ctx, kivik.MethodGet, d.path(path, options), nil)
+	d.client.warnQueryOptions(opts)
+	withAttachments := options.Get("include_docs") == "true" && options.Get("attachments") == "true"
+	if d.client != nil && d.queryCache != nil {
+		defer cancel()
+		body, e := d.cachedQuery(ctx, method, d.path(path, options), body)
+		if e != nil {
+			return nil, e
+		}
+		rows := newRows(body)
+		rows.withAttachments = withAttachments
+		return rows, nil
+	}
+	var chttpOpts *chttp.Options
+	if body != nil {
+		chttpOpts = &chttp.Options{}
+		chttpOpts.SetBody(body)
+	}
+	resp, err := d.Client.DoReq(ctx, method, d.path(path, options), chttpOpts)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	if err = chttp.ResponseError(resp); err != nil {
+		cancel()
 		return nil, err
 	}
-	return newRows(resp.Body), nil
+	rows := newRows(&cancelBody{ReadCloser: resp.Body, cancel: cancel})
+	rows.withAttachments = withAttachments
+	return rows, nil
+}
+
+// keysBody removes the keys option from opts, returning the method and body
+// with which to send it. Keys are sent in the body of a POST request, as a
+// long list of keys could exceed the server's limit on the length of a URL.
+func keysBody(opts map[string]interface{}) (string, []byte, error) {
+	keys, ok := opts["keys"]
+	if !ok {
+		return kivik.MethodGet, nil, nil
+	}
+	encoded, err := chttp.EncodeParam("keys", keys)
+	if err != nil {
+		return "", nil, err
+	}
+	delete(opts, "keys")
+	return kivik.MethodPost, []byte(`{"keys":` + encoded[0] + `}`), nil
 }
 
 // AllDocs returns all of the documents in the database.
@@ -90,10 +139,21 @@ func (d *db) Query(ctx context.Context, ddoc, view string, opts map[string]inter
 
 // Get fetches the requested document.
 func (d *db) Get(ctx context.Context, docID string, options map[string]interface{}) (*driver.Document, error) {
-	resp, rev, err := d.get(ctx, http.MethodGet, docID, options)
+	includeTombstone, err := tombstone(options)
+	if err != nil {
+		return nil, err
+	}
+	lookupDeletedRev, err := deletedRev(options)
 	if err != nil {
 		return nil, err
 	}
+	resp, rev, err := d.get(ctx, http.MethodGet, docID, options)
+	if includeTombstone && kivik.StatusCode(err) == kivik.StatusNotFound {
+		return d.getTombstone(ctx, docID)
+	}
+	if err != nil {
+		return nil, d.deletedError(ctx, docID, err, lookupDeletedRev)
+	}
 	ct, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
 	if err != nil {
 		return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
@@ -216,18 +276,38 @@ func (a *multipartAttachments) Close() error {
 	return a.content.Close()
 }
 
-// Rev returns the most current rev of the requested document.
+// GetMeta returns the size and current rev of the requested document, taken
+// from the Content-Length and ETag headers of a HEAD request, so that the
+// document itself is not transferred.
 func (d *db) GetMeta(ctx context.Context, docID string, options map[string]interface{}) (size int64, rev string, err error) {
 	resp, rev, err := d.get(ctx, http.MethodHead, docID, options)
 	if err != nil {
 		return 0, "", err
 	}
+	// Closing the empty body releases the connection, and any timeout set
+	// with OptionRequestTimeout.
+	_ = resp.Body.Close()
 	return resp.ContentLength, rev, err
 }
 
+// GetRev fetches the requested document, returning its raw JSON body along
+// with its revision, as reported in the ETag header.
+func (d *db) GetRev(ctx context.Context, docID string, options map[string]interface{}) (body []byte, rev string, err error) {
+	resp, rev, err := d.get(ctx, kivik.MethodGet, docID, options)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", errors.WrapStatus(kivik.StatusNetworkError, err)
+	}
+	return body, rev, nil
+}
+
 func (d *db) get(ctx context.Context, method string, docID string, options map[string]interface{}) (*http.Response, string, error) {
-	if docID == "" {
-		return nil, "", missingArg("docID")
+	if err := validateDocID(docID); err != nil {
+		return nil, "", err
 	}
 
 	inm, err := ifNoneMatch(options)
@@ -235,8 +315,13 @@ func (d *db) get(ctx context.Context, method string, docID string, options map[s
 		return nil, "", err
 	}
 
+	ctx, cancel, err := withTimeout(ctx, options)
+	if err != nil {
+		return nil, "", err
+	}
 	params, err := optionsToParams(options)
 	if err != nil {
+		cancel()
 		return nil, "", err
 	}
 	opts := &chttp.Options{
@@ -245,11 +330,14 @@ func (d *db) get(ctx context.Context, method string, docID string, options map[s
 	}
 	resp, err := d.Client.DoReq(ctx, method, d.path(chttp.EncodeDocID(docID), params), opts)
 	if err != nil {
+		cancel()
 		return nil, "", err
 	}
 	if respErr := chttp.ResponseError(resp); respErr != nil {
+		cancel()
 		return nil, "", respErr
 	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
 	rev, err := chttp.GetRev(resp)
 	return resp, rev, err
 }
@@ -260,10 +348,25 @@ func (d *db) CreateDoc(ctx context.Context, doc interface{}, options map[string]
 		Rev string `json:"rev"`
 	}{}
 
+	if err := d.checkQuota(ctx); err != nil {
+		return "", "", err
+	}
 	fullCommit, err := fullCommit(false, options)
 	if err != nil {
 		return "", "", err
 	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return "", "", err
+	}
+	if doc, err = withAccess(doc, options); err != nil {
+		return "", "", err
+	}
+	ctx, cancel, err := withTimeout(ctx, options)
+	if err != nil {
+		return "", "", err
+	}
+	defer cancel()
 
 	path := d.dbName
 	if len(options) > 0 {
@@ -278,30 +381,59 @@ func (d *db) CreateDoc(ctx context.Context, doc interface{}, options map[string]
 		Body:       chttp.EncodeBody(doc),
 		FullCommit: fullCommit,
 	}
-	_, err = d.Client.DoJSON(ctx, kivik.MethodPost, path, opts, &result)
+	resp, err := d.Client.DoJSON(ctx, kivik.MethodPost, path, opts, &result)
+	if err == nil {
+		wr.record(resp.StatusCode)
+	}
 	return result.ID, result.Rev, err
 }
 
+// Put stores doc as docID. If doc has an _attachments field of type
+// kivik.Attachments, those attachments with content are uploaded along with
+// it, in a single multipart/related request.
 func (d *db) Put(ctx context.Context, docID string, doc interface{}, options map[string]interface{}) (rev string, err error) {
-	if docID == "" {
-		return "", missingArg("docID")
+	if err := validateDocID(docID); err != nil {
+		return "", err
+	}
+	if err := d.checkQuota(ctx, doc); err != nil {
+		return "", err
 	}
 	fullCommit, err := fullCommit(false, options)
 	if err != nil {
 		return "", err
 	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel, err := withTimeout(ctx, options)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	atts, hasContent := extractAttachments(doc)
+	if doc, err = withAccess(doc, options); err != nil {
+		return "", err
+	}
 	opts := &chttp.Options{
-		Body:       chttp.EncodeBody(doc),
 		FullCommit: fullCommit,
 	}
+	if hasContent {
+		if opts.Body, opts.ContentType, opts.ContentLength, err = newMultipartBody(doc, atts); err != nil {
+			return "", err
+		}
+	} else {
+		opts.Body = chttp.EncodeBody(doc)
+	}
 	var result struct {
 		ID  string `json:"id"`
 		Rev string `json:"rev"`
 	}
-	_, err = d.Client.DoJSON(ctx, kivik.MethodPut, d.path(chttp.EncodeDocID(docID), nil), opts, &result)
+	resp, err := d.Client.DoJSON(ctx, kivik.MethodPut, d.path(chttp.EncodeDocID(docID), nil), opts, &result)
 	if err != nil {
 		return "", err
 	}
+	wr.record(resp.StatusCode)
 	if result.ID != docID {
 		// This should never happen; this is mostly for debugging and internal use
 		return result.Rev, errors.Statusf(kivik.StatusBadResponse, "modified document ID (%s) does not match that requested (%s)", result.ID, docID)
@@ -310,17 +442,26 @@ func (d *db) Put(ctx context.Context, docID string, doc interface{}, options map
 }
 
 func (d *db) Delete(ctx context.Context, docID, rev string, options map[string]interface{}) (string, error) {
-	if docID == "" {
-		return "", missingArg("docID")
+	if err := validateDocID(docID); err != nil {
+		return "", err
 	}
-	if rev == "" {
-		return "", missingArg("rev")
+	if err := validateRev(rev); err != nil {
+		return "", err
 	}
 
 	fullCommit, err := fullCommit(false, options)
 	if err != nil {
 		return "", err
 	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel, err := withTimeout(ctx, options)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
 
 	query, err := optionsToParams(options)
 	if err != nil {
@@ -335,7 +476,11 @@ func (d *db) Delete(ctx context.Context, docID, rev string, options map[string]i
 		return "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
-	return chttp.GetRev(resp)
+	newRev, err := chttp.GetRev(resp)
+	if err == nil {
+		wr.record(resp.StatusCode)
+	}
+	return newRev, err
 }
 
 func (d *db) Flush(ctx context.Context) error {
@@ -343,29 +488,43 @@ func (d *db) Flush(ctx context.Context) error {
 	return err
 }
 
-func (d *db) Stats(ctx context.Context) (*driver.DBStats, error) {
-	result := struct {
-		driver.DBStats
-		Sizes struct {
-			File     int64 `json:"file"`
-			External int64 `json:"external"`
-			Active   int64 `json:"active"`
-		} `json:"sizes"`
-		UpdateSeq json.RawMessage `json:"update_seq"`
-	}{}
-	_, err := d.Client.DoJSON(ctx, kivik.MethodGet, d.dbName, nil, &result)
-	stats := result.DBStats
-	if result.Sizes.File > 0 {
-		stats.DiskSize = result.Sizes.File
+// dbStats is the database info returned by CouchDB, which varies somewhat
+// between versions.
+type dbStats struct {
+	driver.DBStats
+	Sizes struct {
+		File     int64 `json:"file"`
+		External int64 `json:"external"`
+		Active   int64 `json:"active"`
+	} `json:"sizes"`
+	UpdateSeq Sequence `json:"update_seq"`
+	Props     struct {
+		Partitioned bool `json:"partitioned"`
+	} `json:"props"`
+}
+
+func (s *dbStats) driverStats() *driver.DBStats {
+	stats := s.DBStats
+	if s.Sizes.File > 0 {
+		stats.DiskSize = s.Sizes.File
+	}
+	if s.Sizes.External > 0 {
+		stats.ExternalSize = s.Sizes.External
 	}
-	if result.Sizes.External > 0 {
-		stats.ExternalSize = result.Sizes.External
+	if s.Sizes.Active > 0 {
+		stats.ActiveSize = s.Sizes.Active
 	}
-	if result.Sizes.Active > 0 {
-		stats.ActiveSize = result.Sizes.Active
+	stats.UpdateSeq = s.UpdateSeq.String()
+	return &stats
+}
+
+func (d *db) Stats(ctx context.Context) (*driver.DBStats, error) {
+	result := &dbStats{}
+	_, err := d.Client.DoJSON(ctx, kivik.MethodGet, d.dbName, nil, result)
+	if err == nil {
+		d.setProps(result)
 	}
-	stats.UpdateSeq = string(bytes.Trim(result.UpdateSeq, `"`))
-	return &stats, err
+	return result.driverStats(), err
 }
 
 func (d *db) Compact(ctx context.Context) error {
@@ -420,10 +579,22 @@ func (d *db) Copy(ctx context.Context, targetID, sourceID string, options map[st
 	if targetID == "" {
 		return "", errors.Status(kivik.StatusBadRequest, "kivik: targetID required")
 	}
+	for _, id := range []string{sourceID, targetID} {
+		if err := validateDocID(id); err != nil {
+			return "", err
+		}
+	}
+	if err := d.checkQuota(ctx); err != nil {
+		return "", err
+	}
 	fullCommit, err := fullCommit(false, options)
 	if err != nil {
 		return "", err
 	}
+	wr, err := writeResult(options)
+	if err != nil {
+		return "", err
+	}
 	params, err := optionsToParams(options)
 	if err != nil {
 		return "", err
@@ -437,5 +608,9 @@ func (d *db) Copy(ctx context.Context, targetID, sourceID string, options map[st
 		return "", err
 	}
 	defer resp.Body.Close() // nolint: errcheck
-	return chttp.GetRev(resp)
+	rev, err := chttp.GetRev(resp)
+	if err == nil {
+		wr.record(resp.StatusCode)
+	}
+	return rev, err
 }
@@ -34,6 +34,33 @@ func TestQuery(t *testing.T) {
 	testy.Error(t, "Get http://example.com/testdb/_design/ddoc/_view/view: test error", err)
 }
 
+func TestQueryKeys(t *testing.T) {
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		if req.Method != kivik.MethodPost {
+			t.Errorf("Unexpected method: %s", req.Method)
+		}
+		if req.URL.RawQuery != "include_docs=true" {
+			t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if d := diff.JSON([]byte(`{"keys":["foo","bar"]}`), body); d != nil {
+			t.Error(d)
+		}
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"total_rows":2,"offset":0,"rows":[]}`)}, nil
+	})
+	rows, err := db.Query(context.Background(), "ddoc", "view", map[string]interface{}{
+		"keys":         []string{"foo", "bar"},
+		"include_docs": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+}
+
 type Attachment struct {
 	Filename    string
 	ContentType string
@@ -1429,6 +1456,76 @@ func TestGetMeta(t *testing.T) {
 	}
 }
 
+func TestGetMetaClosesBody(t *testing.T) {
+	body := &closeTracker{ReadCloser: Body("")}
+	db := newTestDB(&http.Response{
+		StatusCode:    kivik.StatusOK,
+		Header:        http.Header{"ETag": {`"1-xxx"`}},
+		ContentLength: 70,
+		Body:          body,
+	}, nil)
+	if _, _, err := db.GetMeta(context.Background(), "foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !body.closed {
+		t.Errorf("Response body not closed")
+	}
+}
+
+func TestGetRev(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     *db
+		id     string
+		body   string
+		rev    string
+		status int
+		err    string
+	}{
+		{
+			name:   "no doc id",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: docID required",
+		},
+		{
+			name: "not found",
+			id:   "foo",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusNotFound,
+				Body:       Body(""),
+			}, nil),
+			status: kivik.StatusNotFound,
+			err:    "Not Found",
+		},
+		{
+			name: "success",
+			id:   "foo",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Header: http.Header{
+					"Content-Type": {"application/json"},
+					"ETag":         {`"1-xxx"`},
+				},
+				Body: Body(`{"_id":"foo","_rev":"1-xxx"}`),
+			}, nil),
+			body: `{"_id":"foo","_rev":"1-xxx"}` + "\n",
+			rev:  "1-xxx",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, rev, err := test.db.GetRev(context.Background(), test.id, nil)
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Text(test.body, string(body)); d != nil {
+				t.Error(d)
+			}
+			if rev != test.rev {
+				t.Errorf("Got rev %s, expected %s", rev, test.rev)
+			}
+		})
+	}
+}
+
 func TestCopy(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -0,0 +1,162 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// DBDiff is the result of Diff.
+type DBDiff struct {
+	// MissingFromA and MissingFromB list, in order, the IDs of documents
+	// which exist in only one of the databases. Deleted documents count as
+	// missing.
+	MissingFromA []string
+	MissingFromB []string
+
+	// Different lists the documents whose winning revisions differ.
+	Different []DocDiff
+}
+
+// Converged returns true if the databases hold the same documents, at the
+// same winning revisions.
+func (d *DBDiff) Converged() bool {
+	return len(d.MissingFromA) == 0 && len(d.MissingFromB) == 0 && len(d.Different) == 0
+}
+
+// DocDiff describes a document whose winning revision differs between two
+// databases.
+type DocDiff struct {
+	ID   string
+	RevA string
+	RevB string
+
+	// AHasRevB and BHasRevA report whether each database has the other's
+	// winning revision. If one does, that database is ahead of the other;
+	// if neither does, the document has diverged.
+	AHasRevB bool
+	BHasRevA bool
+}
+
+// Diverged returns true if neither database has the other's winning revision.
+func (d *DocDiff) Diverged() bool {
+	return !d.AHasRevB && !d.BHasRevA
+}
+
+// Diff compares the document IDs and winning revisions of dbA and dbB, with
+// _all_docs, and checks any differing revisions with _revs_diff, to validate
+// that a migration or replication has converged.
+func (c *client) Diff(ctx context.Context, dbA, dbB string) (*DBDiff, error) {
+	if dbA == "" {
+		return nil, missingArg("dbA")
+	}
+	if dbB == "" {
+		return nil, missingArg("dbB")
+	}
+	for _, dbName := range []string{dbA, dbB} {
+		if err := c.checkDB(dbName); err != nil {
+			return nil, err
+		}
+	}
+	a, b := &db{client: c, dbName: dbA}, &db{client: c, dbName: dbB}
+	revsA, err := a.winningRevs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	revsB, err := b.winningRevs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diff := &DBDiff{}
+	var different []DocDiff
+	for id, revA := range revsA {
+		revB, ok := revsB[id]
+		switch {
+		case !ok:
+			diff.MissingFromB = append(diff.MissingFromB, id)
+		case revA != revB:
+			different = append(different, DocDiff{ID: id, RevA: revA, RevB: revB})
+		}
+	}
+	for id := range revsB {
+		if _, ok := revsA[id]; !ok {
+			diff.MissingFromA = append(diff.MissingFromA, id)
+		}
+	}
+	sort.Strings(diff.MissingFromA)
+	sort.Strings(diff.MissingFromB)
+	sort.Slice(different, func(i, j int) bool { return different[i].ID < different[j].ID })
+	if len(different) > 0 {
+		revsOfA := make(map[string][]string, len(different))
+		revsOfB := make(map[string][]string, len(different))
+		for _, doc := range different {
+			revsOfA[doc.ID] = []string{doc.RevA}
+			revsOfB[doc.ID] = []string{doc.RevB}
+		}
+		missingInB, err := b.revsDiff(ctx, revsOfA)
+		if err != nil {
+			return nil, err
+		}
+		missingInA, err := a.revsDiff(ctx, revsOfB)
+		if err != nil {
+			return nil, err
+		}
+		for i := range different {
+			doc := &different[i]
+			doc.BHasRevA = !missingInB[doc.ID][doc.RevA]
+			doc.AHasRevB = !missingInA[doc.ID][doc.RevB]
+		}
+	}
+	diff.Different = different
+	return diff, nil
+}
+
+// winningRevs returns the winning revision of each document in the database.
+func (d *db) winningRevs(ctx context.Context) (map[string]string, error) {
+	rows, err := d.AllDocs(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	revs := make(map[string]string)
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				return revs, nil
+			}
+			return nil, err
+		}
+		var value struct {
+			Rev string `json:"rev"`
+		}
+		if err := json.Unmarshal(row.Value, &value); err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		revs[row.ID] = value.Rev
+	}
+}
+
+// revsDiff returns, for each document, which of revs the database lacks.
+func (d *db) revsDiff(ctx context.Context, revs map[string][]string) (map[string]map[string]bool, error) {
+	var result map[string]struct {
+		Missing []string `json:"missing"`
+	}
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodPost, d.path("_revs_diff", nil), &chttp.Options{Body: chttp.EncodeBody(revs)}, &result); err != nil {
+		return nil, err
+	}
+	missing := make(map[string]map[string]bool, len(result))
+	for id, doc := range result {
+		missing[id] = make(map[string]bool, len(doc.Missing))
+		for _, rev := range doc.Missing {
+			missing[id][rev] = true
+		}
+	}
+	return missing, nil
+}
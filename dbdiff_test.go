@@ -0,0 +1,81 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestDiff(t *testing.T) {
+	allDocs := map[string]string{
+		"/a/_all_docs": `{"total_rows":4,"offset":0,"rows":[
+{"id":"ahead","key":"ahead","value":{"rev":"2-a"}},
+{"id":"conflict","key":"conflict","value":{"rev":"2-x"}},
+{"id":"only-a","key":"only-a","value":{"rev":"1-a"}},
+{"id":"same","key":"same","value":{"rev":"1-s"}}
+]}`,
+		"/b/_all_docs": `{"total_rows":4,"offset":0,"rows":[
+{"id":"ahead","key":"ahead","value":{"rev":"1-a"}},
+{"id":"conflict","key":"conflict","value":{"rev":"2-y"}},
+{"id":"only-b","key":"only-b","value":{"rev":"1-b"}},
+{"id":"same","key":"same","value":{"rev":"1-s"}}
+]}`,
+	}
+	revsDiff := map[string]string{
+		"/a/_revs_diff": `{"conflict":{"missing":["2-y"]}}`,
+		"/b/_revs_diff": `{"ahead":{"missing":["2-a"]},"conflict":{"missing":["2-x"]}}`,
+	}
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		body := allDocs[req.URL.Path]
+		if req.Method == kivik.MethodPost {
+			var revs map[string][]string
+			if err := json.NewDecoder(req.Body).Decode(&revs); err != nil {
+				return nil, err
+			}
+			if len(revs) != 2 {
+				t.Errorf("Unexpected _revs_diff request: %v", revs)
+			}
+			body = revsDiff[req.URL.Path]
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       Body(body),
+		}, nil
+	})
+	result, err := c.Diff(context.Background(), "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &DBDiff{
+		MissingFromA: []string{"only-b"},
+		MissingFromB: []string{"only-a"},
+		Different: []DocDiff{
+			{ID: "ahead", RevA: "2-a", RevB: "1-a", AHasRevB: true},
+			{ID: "conflict", RevA: "2-x", RevB: "2-y"},
+		},
+	}
+	if d := diff.Interface(expected, result); d != nil {
+		t.Error(d)
+	}
+	if result.Converged() {
+		t.Error("Expected databases not to have converged")
+	}
+	if result.Different[0].Diverged() || !result.Different[1].Diverged() {
+		t.Error("Unexpected divergence")
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	_, err := (&client{}).Diff(context.Background(), "", "b")
+	testy.StatusError(t, "kivik: dbA required", kivik.StatusBadRequest, err)
+	c := newTestClient(&http.Response{StatusCode: kivik.StatusNotFound, Body: Body("")}, nil)
+	_, err = c.Diff(context.Background(), "a", "b")
+	testy.StatusError(t, "Not Found", kivik.StatusNotFound, err)
+}
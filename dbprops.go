@@ -0,0 +1,57 @@
+package couchdb
+
+import (
+	"context"
+)
+
+// DBProps describes how a database is stored, which determines the endpoints
+// available for it.
+type DBProps struct {
+	// Partitioned is true for partitioned databases (CouchDB 3.0 and later),
+	// which support the partition-scoped /{db}/_partition endpoints.
+	Partitioned bool
+	// Shards (q) and Replicas (n) describe the database's clustering. They
+	// are zero for servers which do not report them, such as CouchDB 1.x.
+	Shards   int
+	Replicas int
+}
+
+// setProps caches the properties reported in stats.
+func (d *db) setProps(stats *dbStats) {
+	props := &DBProps{Partitioned: stats.Props.Partitioned}
+	if stats.Cluster != nil {
+		props.Shards = stats.Cluster.Shards
+		props.Replicas = stats.Cluster.Replicas
+	}
+	d.propsMU.Lock()
+	d.props = props
+	d.propsMU.Unlock()
+}
+
+// Props returns the database's properties. They are fetched on first use,
+// or taken from the most recent call to Stats, and cached for the life of the
+// db handle, as they cannot change without recreating the database.
+func (d *db) Props(ctx context.Context) (*DBProps, error) {
+	d.propsMU.Lock()
+	props := d.props
+	d.propsMU.Unlock()
+	if props != nil {
+		return props, nil
+	}
+	if _, err := d.Stats(ctx); err != nil {
+		return nil, err
+	}
+	d.propsMU.Lock()
+	defer d.propsMU.Unlock()
+	return d.props, nil
+}
+
+// Partitioned reports whether the database is partitioned, as cached by
+// Props.
+func (d *db) Partitioned(ctx context.Context) (bool, error) {
+	props, err := d.Props(ctx)
+	if err != nil {
+		return false, err
+	}
+	return props.Partitioned, nil
+}
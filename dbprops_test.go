@@ -0,0 +1,89 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestProps(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected *DBProps
+		err      string
+	}{
+		{
+			name:     "CouchDB 3.x partitioned",
+			status:   kivik.StatusOK,
+			body:     `{"db_name":"testdb","cluster":{"q":2,"n":3,"w":2,"r":2},"props":{"partitioned":true},"doc_count":0}`,
+			expected: &DBProps{Partitioned: true, Shards: 2, Replicas: 3},
+		},
+		{
+			name:     "CouchDB 1.x",
+			status:   kivik.StatusOK,
+			body:     `{"db_name":"testdb","doc_count":0}`,
+			expected: &DBProps{},
+		},
+		{
+			name:   "not found",
+			status: kivik.StatusNotFound,
+			err:    "Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				requests++
+				return &http.Response{StatusCode: test.status, Request: req, Body: Body(test.body)}, nil
+			})
+			for i := 0; i < 2; i++ {
+				props, err := db.Props(context.Background())
+				if test.err != "" {
+					testy.StatusError(t, test.err, test.status, err)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d := diff.Interface(test.expected, props); d != nil {
+					t.Error(d)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("Expected props to be fetched once, got %d requests", requests)
+			}
+			partitioned, err := db.Partitioned(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if partitioned != test.expected.Partitioned {
+				t.Errorf("Unexpected Partitioned: %t", partitioned)
+			}
+		})
+	}
+}
+
+func TestStatsCachesProps(t *testing.T) {
+	db := newTestDB(&http.Response{
+		StatusCode: kivik.StatusOK,
+		Body:       Body(`{"db_name":"testdb","props":{"partitioned":true}}`),
+	}, nil)
+	if _, err := db.Stats(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	db.client = nil // Any further request would panic
+	partitioned, err := db.Partitioned(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !partitioned {
+		t.Error("Expected partitioned database")
+	}
+}
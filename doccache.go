@@ -0,0 +1,169 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// DocCache is a cache of documents, stored in a local directory, which allows
+// documents to be read while the server is unreachable. Documents are added
+// to the cache as they are fetched with CachedGet, and kept up to date by
+// SyncDocCache, which follows the changes feed of each database.
+//
+// The directory holds a subdirectory for each database, containing a file for
+// each cached document, and the sequence of the last change applied.
+type DocCache struct {
+	dir string
+}
+
+// OpenDocCache opens the DocCache stored in dir, creating the directory if it
+// does not exist.
+func OpenDocCache(dir string) (*DocCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DocCache{dir: dir}, nil
+}
+
+// cacheName encodes a database name or document ID as a file name.
+func cacheName(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func (c *DocCache) dbDir(dbName string) string {
+	return filepath.Join(c.dir, cacheName(dbName))
+}
+
+func (c *DocCache) docPath(dbName, docID string) string {
+	return filepath.Join(c.dbDir(dbName), cacheName(docID)+".json")
+}
+
+// Get returns the cached copy of docID from dbName, or a Not Found error if
+// it is not cached.
+func (c *DocCache) Get(dbName, docID string) (json.RawMessage, error) {
+	doc, err := ioutil.ReadFile(c.docPath(dbName, docID))
+	if os.IsNotExist(err) {
+		return nil, errors.Status(kivik.StatusNotFound, "kivik: document not cached")
+	}
+	return doc, err
+}
+
+// put stores doc as the cached copy of docID in dbName.
+func (c *DocCache) put(dbName, docID string, doc []byte) error {
+	if err := os.MkdirAll(c.dbDir(dbName), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.docPath(dbName, docID), doc)
+}
+
+// remove removes any cached copy of docID in dbName.
+func (c *DocCache) remove(dbName, docID string) error {
+	err := os.Remove(c.docPath(dbName, docID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// cached returns true if docID in dbName is cached.
+func (c *DocCache) cached(dbName, docID string) bool {
+	_, err := os.Stat(c.docPath(dbName, docID))
+	return err == nil
+}
+
+// seq returns the sequence of the last change applied to dbName, or an empty
+// string if none has been.
+func (c *DocCache) seq(dbName string) (string, error) {
+	seq, err := ioutil.ReadFile(filepath.Join(c.dbDir(dbName), "seq"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(seq), err
+}
+
+func (c *DocCache) setSeq(dbName, seq string) error {
+	if err := os.MkdirAll(c.dbDir(dbName), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(c.dbDir(dbName), "seq"), []byte(seq))
+}
+
+// CachedGet fetches docID from dbName, storing it in cache. If the server
+// cannot be reached, or fails with a server error, the cached copy is
+// returned instead, if there is one. If the document is not found, any cached
+// copy is removed.
+func (c *client) CachedGet(ctx context.Context, cache *DocCache, dbName, docID string) (json.RawMessage, error) {
+	if err := c.checkDB(dbName); err != nil {
+		return nil, err
+	}
+	d := &db{client: c, dbName: dbName}
+	doc, err := d.Get(ctx, docID, nil)
+	if err != nil {
+		switch {
+		case kivik.StatusCode(err) == kivik.StatusNotFound:
+			if e := cache.remove(dbName, docID); e != nil {
+				return nil, e
+			}
+		case transientError(err):
+			if cached, e := cache.Get(dbName, docID); e == nil {
+				return cached, nil
+			}
+		}
+		return nil, err
+	}
+	defer doc.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(doc.Body)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusNetworkError, err)
+	}
+	if err := cache.put(dbName, docID, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// SyncDocCache follows the changes feed of dbName, reconnecting as Tail does,
+// updating or removing the cached copy of each changed document which is in
+// cache. Syncing resumes from the last change applied, so that documents
+// changed while the server was unreachable are updated when it returns. The
+// first sync of a database starts from the current sequence, as the cached
+// documents were fetched from the server in their current state. It returns
+// when ctx is cancelled, or when the server rejects the request.
+func (c *client) SyncDocCache(ctx context.Context, cache *DocCache, dbName string) error {
+	if dbName == "" {
+		return missingArg("dbName")
+	}
+	if err := c.checkDB(dbName); err != nil {
+		return err
+	}
+	since, err := cache.seq(dbName)
+	if err != nil {
+		return err
+	}
+	if since == "" {
+		since = "now"
+	}
+	d := &db{client: c, dbName: dbName}
+	return d.follow(ctx, since, func(change *driver.Change) error {
+		if cache.cached(dbName, change.ID) {
+			var err error
+			if change.Deleted {
+				err = cache.remove(dbName, change.ID)
+			} else {
+				err = cache.put(dbName, change.ID, change.Doc)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return cache.setSeq(dbName, change.Seq)
+	})
+}
@@ -0,0 +1,121 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func tempDocCache(t *testing.T) (*DocCache, func()) {
+	dir, err := ioutil.TempDir("", "doccache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := OpenDocCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestCachedGet(t *testing.T) {
+	cache, cleanup := tempDocCache(t)
+	defer cleanup()
+	var response *http.Response
+	var netErr error
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if netErr != nil {
+			return nil, netErr
+		}
+		response.Request = req
+		return response, nil
+	})
+	get := func() string {
+		doc, err := c.CachedGet(context.Background(), cache, "db", "_design/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(doc))
+	}
+
+	response = &http.Response{
+		StatusCode: kivik.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}, "ETag": {`"1-xxx"`}},
+		Body:       Body(`{"_id":"_design/foo","_rev":"1-xxx"}`),
+	}
+	if doc := get(); doc != `{"_id":"_design/foo","_rev":"1-xxx"}` {
+		t.Errorf("Unexpected doc: %s", doc)
+	}
+
+	netErr = errors.New("connection refused")
+	if doc := get(); doc != `{"_id":"_design/foo","_rev":"1-xxx"}` {
+		t.Errorf("Unexpected cached doc: %s", doc)
+	}
+
+	netErr = nil
+	response = &http.Response{StatusCode: kivik.StatusNotFound, Body: Body("")}
+	t.Run("deleted", func(t *testing.T) {
+		_, err := c.CachedGet(context.Background(), cache, "db", "_design/foo")
+		testy.StatusError(t, "Not Found", kivik.StatusNotFound, err)
+	})
+	t.Run("evicted", func(t *testing.T) {
+		_, err := cache.Get("db", "_design/foo")
+		testy.StatusError(t, "kivik: document not cached", kivik.StatusNotFound, err)
+	})
+
+	netErr = errors.New("connection refused")
+	_, err := c.CachedGet(context.Background(), cache, "db", "_design/foo")
+	testy.StatusErrorRE(t, "connection refused", kivik.StatusNetworkError, err)
+}
+
+func TestSyncDocCache(t *testing.T) {
+	cache, cleanup := tempDocCache(t)
+	defer cleanup()
+	for _, id := range []string{"foo", "bar"} {
+		if err := cache.put("db", id, []byte(`{"_id":"`+id+`","_rev":"1-xxx"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.setSeq("db", "1-xxx"); err != nil {
+		t.Fatal(err)
+	}
+	var sinces []string
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		sinces = append(sinces, req.URL.Query().Get("since"))
+		if len(sinces) > 1 {
+			return &http.Response{StatusCode: kivik.StatusBadRequest, Request: req, Body: Body("")}, nil
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body: Body(`{"seq":"2-xxx","id":"foo","changes":[{"rev":"2-aaa"}],"doc":{"_id":"foo","_rev":"2-aaa"}}
+{"seq":"3-xxx","id":"bar","deleted":true,"changes":[{"rev":"2-bbb"}],"doc":{"_id":"bar","_rev":"2-bbb","_deleted":true}}
+{"seq":"4-xxx","id":"baz","changes":[{"rev":"1-ccc"}],"doc":{"_id":"baz","_rev":"1-ccc"}}
+`),
+		}, nil
+	})
+	err := c.SyncDocCache(context.Background(), cache, "db")
+	if sinces[0] != "1-xxx" {
+		t.Errorf("Unexpected initial since: %s", sinces[0])
+	}
+	if doc, err := cache.Get("db", "foo"); err != nil || string(doc) != `{"_id":"foo","_rev":"2-aaa"}` {
+		t.Errorf("Unexpected foo: %s, %v", doc, err)
+	}
+	if _, err := cache.Get("db", "bar"); kivik.StatusCode(err) != kivik.StatusNotFound {
+		t.Errorf("Expected bar to be removed, got %v", err)
+	}
+	if _, err := cache.Get("db", "baz"); kivik.StatusCode(err) != kivik.StatusNotFound {
+		t.Errorf("Expected baz not to be cached, got %v", err)
+	}
+	if seq, _ := cache.seq("db"); seq != "4-xxx" {
+		t.Errorf("Unexpected seq: %s", seq)
+	}
+	testy.StatusError(t, "Bad Request", kivik.StatusBadRequest, err)
+}
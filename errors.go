@@ -1,6 +1,9 @@
 package couchdb
 
 import (
+	"net/http"
+
+	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
 	"github.com/go-kivik/kivik/errors"
 )
@@ -8,3 +11,77 @@ import (
 func missingArg(arg string) error {
 	return errors.Statusf(kivik.StatusBadRequest, "kivik: %s required", arg)
 }
+
+// ErrorKind classifies an error reported by the server by its error and
+// reason, so that applications may distinguish, for instance, a deleted
+// document from one which never existed, without parsing reason strings.
+type ErrorKind int
+
+// Kinds of error returned by ErrorKindOf.
+const (
+	// KindOther is any error not described below, including errors not
+	// reported by the server.
+	KindOther ErrorKind = iota
+	// KindNotFound is a missing document, or other resource not described
+	// below.
+	KindNotFound
+	// KindDeleted is a document which has been deleted.
+	KindDeleted
+	// KindMissingDB is a database which does not exist.
+	KindMissingDB
+	// KindMissingView is a view missing from an existing design document.
+	KindMissingView
+	// KindNoUsableIndex is a Mango query for which no index can be used.
+	KindNoUsableIndex
+	// KindGone is an endpoint which the server no longer supports, such as
+	// _temp_view on CouchDB 2.0 and later.
+	KindGone
+)
+
+var errorKindNames = map[ErrorKind]string{
+	KindOther:         "other",
+	KindNotFound:      "not found",
+	KindDeleted:       "deleted",
+	KindMissingDB:     "missing database",
+	KindMissingView:   "missing view",
+	KindNoUsableIndex: "no usable index",
+	KindGone:          "gone",
+}
+
+func (k ErrorKind) String() string {
+	return errorKindNames[k]
+}
+
+// ErrorKindOf returns the kind of err. Responses to HEAD requests carry no
+// error or reason, so a missing document and a deleted one are both reported
+// as KindNotFound.
+func ErrorKindOf(err error) ErrorKind {
+	if _, ok := err.(*DeletedError); ok {
+		return KindDeleted
+	}
+	httpErr, ok := err.(*chttp.HTTPError)
+	if !ok {
+		return KindOther
+	}
+	switch httpErr.Code {
+	case kivik.StatusNotFound:
+		switch httpErr.Reason {
+		case "deleted":
+			return KindDeleted
+		case "missing_named_view":
+			return KindMissingView
+		case "Database does not exist.", "no_db_file":
+			return KindMissingDB
+		}
+		if httpErr.Name == "db_not_found" {
+			return KindMissingDB
+		}
+		return KindNotFound
+	case http.StatusGone:
+		return KindGone
+	}
+	if httpErr.Name == "no_usable_index" {
+		return KindNoUsableIndex
+	}
+	return KindOther
+}
@@ -0,0 +1,80 @@
+package couchdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+	}{
+		{
+			name:     "not an HTTP error",
+			err:      errors.New("foo"),
+			expected: KindOther,
+		},
+		{
+			name:     "missing doc",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound, Name: "not_found", Reason: "missing"},
+			expected: KindNotFound,
+		},
+		{
+			name:     "HEAD",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound},
+			expected: KindNotFound,
+		},
+		{
+			name:     "deleted doc",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound, Name: "not_found", Reason: "deleted"},
+			expected: KindDeleted,
+		},
+		{
+			name:     "missing view",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound, Name: "not_found", Reason: "missing_named_view"},
+			expected: KindMissingView,
+		},
+		{
+			name:     "missing db 2.x",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound, Name: "not_found", Reason: "Database does not exist."},
+			expected: KindMissingDB,
+		},
+		{
+			name:     "missing db 1.x",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound, Name: "not_found", Reason: "no_db_file"},
+			expected: KindMissingDB,
+		},
+		{
+			name:     "db not found",
+			err:      &chttp.HTTPError{Code: kivik.StatusNotFound, Name: "db_not_found", Reason: "could not open foo"},
+			expected: KindMissingDB,
+		},
+		{
+			name:     "no usable index",
+			err:      &chttp.HTTPError{Code: kivik.StatusBadRequest, Name: "no_usable_index", Reason: "No index exists for this sort"},
+			expected: KindNoUsableIndex,
+		},
+		{
+			name:     "gone",
+			err:      &chttp.HTTPError{Code: 410},
+			expected: KindGone,
+		},
+		{
+			name:     "other",
+			err:      &chttp.HTTPError{Code: kivik.StatusConflict, Name: "conflict"},
+			expected: KindOther,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if kind := ErrorKindOf(test.err); kind != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// Documents opt in to expiry by setting ExpiresAtField to the Unix time, in
+// seconds, after which they may be removed. EnsureExpiryView installs a view
+// indexing documents by that field, and ExpireDocs or RunExpiry remove those
+// which have expired. Until they are removed, expired documents remain
+// readable; the convention is enforced only by the maintenance worker.
+const (
+	ExpiresAtField = "expires_at"
+	ExpiryDDoc     = "expiry"
+	ExpiryView     = "by_expiry"
+)
+
+// expiryMapFunc emits the expiry time and current rev of each document with a
+// numeric ExpiresAtField.
+const expiryMapFunc = `function(doc) {
+	if (typeof doc.` + ExpiresAtField + ` === "number") {
+		emit(doc.` + ExpiresAtField + `, doc._rev);
+	}
+}`
+
+// ExpiresAt returns the value of ExpiresAtField for a document which expires
+// at t.
+func ExpiresAt(t time.Time) int64 {
+	return t.Unix()
+}
+
+// ExpiryOptions configures ExpireDocs and RunExpiry.
+type ExpiryOptions struct {
+	// BatchSize is the number of expired documents removed per request. It
+	// defaults to 100.
+	BatchSize int
+
+	// Purge purges expired documents, rather than deleting them, so that no
+	// tombstone remains, and the removal is not replicated.
+	Purge bool
+
+	// Interval is the delay between passes of RunExpiry. It defaults to one
+	// minute.
+	Interval time.Duration
+}
+
+// expiredDoc identifies a document returned by the expiry view.
+type expiredDoc struct {
+	ID  string
+	Rev string
+}
+
+// EnsureExpiryView creates the design document containing the expiry view, if
+// it does not already exist.
+func (d *db) EnsureExpiryView(ctx context.Context) error {
+	ddoc := map[string]interface{}{
+		"language": "javascript",
+		"views": map[string]interface{}{
+			ExpiryView: map[string]string{"map": expiryMapFunc},
+		},
+	}
+	_, err := d.Put(ctx, "_design/"+ExpiryDDoc, ddoc, nil)
+	if kivik.StatusCode(err) == kivik.StatusConflict {
+		return nil
+	}
+	return err
+}
+
+// ExpireDocs removes, in batches, every document which has expired, and
+// returns the number removed. Documents which change while being removed are
+// left for the next pass.
+func (d *db) ExpireDocs(ctx context.Context, opts *ExpiryOptions) (int, error) {
+	if opts == nil {
+		opts = &ExpiryOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize < 1 {
+		batchSize = 100
+	}
+	now := d.clock().Now().Unix()
+	var total int
+	for {
+		expired, err := d.expiredDocs(ctx, now, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(expired) == 0 {
+			return total, nil
+		}
+		var removed int
+		if opts.Purge {
+			removed, err = d.purgeExpired(ctx, expired)
+		} else {
+			removed, err = d.deleteExpired(ctx, expired)
+		}
+		total += removed
+		if err != nil {
+			return total, err
+		}
+		// A short batch was the last one, and a batch in which nothing could
+		// be removed would only be returned again.
+		if len(expired) < batchSize || removed == 0 {
+			return total, nil
+		}
+	}
+}
+
+// RunExpiry calls ExpireDocs every opts.Interval, until ctx is cancelled or
+// a pass fails.
+func (d *db) RunExpiry(ctx context.Context, opts *ExpiryOptions) error {
+	if opts == nil {
+		opts = &ExpiryOptions{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	for {
+		if _, err := d.ExpireDocs(ctx, opts); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.clock().After(interval):
+		}
+	}
+}
+
+// expiredDocs returns up to limit documents which expired at or before now.
+func (d *db) expiredDocs(ctx context.Context, now int64, limit int) ([]expiredDoc, error) {
+	opts, err := KeyRange{End: now}.Options()
+	if err != nil {
+		return nil, err
+	}
+	opts["limit"] = limit
+	rows, err := d.Query(ctx, ExpiryDDoc, ExpiryView, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	var expired []expiredDoc
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				return expired, nil
+			}
+			return nil, err
+		}
+		var rev string
+		if err := json.Unmarshal(row.Value, &rev); err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		expired = append(expired, expiredDoc{ID: row.ID, Rev: rev})
+	}
+}
+
+// deleteExpired deletes expired with a single bulk request, and returns the
+// number deleted.
+func (d *db) deleteExpired(ctx context.Context, expired []expiredDoc) (int, error) {
+	docs := make([]interface{}, len(expired))
+	for i, doc := range expired {
+		docs[i] = map[string]interface{}{"_id": doc.ID, "_rev": doc.Rev, "_deleted": true}
+	}
+	results, err := d.BulkDocs(ctx, docs, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer results.Close() // nolint: errcheck
+	var deleted int
+	for {
+		var result driver.BulkResult
+		if err := results.Next(&result); err != nil {
+			if err == io.EOF {
+				return deleted, nil
+			}
+			return deleted, err
+		}
+		if result.Error == nil {
+			deleted++
+		}
+	}
+}
+
+// purgeExpired purges the expired revisions with a single _purge request, and
+// returns the number of documents purged.
+func (d *db) purgeExpired(ctx context.Context, expired []expiredDoc) (int, error) {
+	revs := make(map[string][]string, len(expired))
+	for _, doc := range expired {
+		revs[doc.ID] = []string{doc.Rev}
+	}
+	var result struct {
+		Purged map[string][]string `json:"purged"`
+	}
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodPost, d.path("_purge", nil), &chttp.Options{Body: chttp.EncodeBody(revs)}, &result); err != nil {
+		return 0, err
+	}
+	var purged int
+	for _, revs := range result.Purged {
+		if len(revs) > 0 {
+			purged++
+		}
+	}
+	return purged, nil
+}
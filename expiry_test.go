@@ -0,0 +1,146 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestEnsureExpiryView(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     *db
+		status int
+		err    string
+	}{
+		{
+			name: "created",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/testdb/_design/expiry" {
+					return nil, errors.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`{"ok":true,"id":"_design/expiry","rev":"1-xxx"}`),
+				}, nil
+			}),
+		},
+		{
+			name: "exists",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusConflict,
+				Request:    &http.Request{Method: kivik.MethodPut},
+				Body:       Body(`{"error":"conflict","reason":"Document update conflict."}`),
+			}, nil),
+		},
+		{
+			name: "forbidden",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusUnauthorized,
+				Request:    &http.Request{Method: kivik.MethodPut},
+				Body:       Body(""),
+			}, nil),
+			status: kivik.StatusUnauthorized,
+			err:    "Unauthorized",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.db.EnsureExpiryView(context.Background())
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestExpireDocs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *ExpiryOptions
+		requests []string
+		expected int
+		status   int
+		err      string
+	}{
+		{
+			name: "delete",
+			opts: &ExpiryOptions{BatchSize: 2},
+			requests: []string{
+				"GET /testdb/_design/expiry/_view/by_expiry",
+				`POST /testdb/_bulk_docs {"docs":[{"_deleted":true,"_id":"a","_rev":"1-a"},{"_deleted":true,"_id":"b","_rev":"1-b"}]}`,
+				"GET /testdb/_design/expiry/_view/by_expiry",
+				`POST /testdb/_bulk_docs {"docs":[{"_deleted":true,"_id":"c","_rev":"1-c"}]}`,
+			},
+			expected: 2,
+		},
+		{
+			name: "purge",
+			opts: &ExpiryOptions{BatchSize: 2, Purge: true},
+			requests: []string{
+				"GET /testdb/_design/expiry/_view/by_expiry",
+				`POST /testdb/_purge {"a":["1-a"],"b":["1-b"]}`,
+				"GET /testdb/_design/expiry/_view/by_expiry",
+				`POST /testdb/_purge {"c":["1-c"]}`,
+			},
+			expected: 3,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+			batches := [][]string{{"a", "b"}, {"c"}}
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					requests = append(requests, req.Method+" "+req.URL.Path)
+					if limit := req.URL.Query().Get("limit"); limit != "2" {
+						return nil, errors.Errorf("Unexpected limit: %s", limit)
+					}
+					endkey, err := strconv.ParseInt(req.URL.Query().Get("endkey"), 10, 64)
+					if err != nil || endkey <= 0 {
+						return nil, errors.Errorf("Unexpected endkey: %s", req.URL.Query().Get("endkey"))
+					}
+					var rows []map[string]interface{}
+					for _, id := range batches[0] {
+						rows = append(rows, map[string]interface{}{"id": id, "key": endkey - 1, "value": "1-" + id})
+					}
+					batches = batches[1:]
+					body, _ := json.Marshal(map[string]interface{}{"rows": rows})
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(string(body))}, nil
+				}
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				requests = append(requests, req.Method+" "+req.URL.Path+" "+strings.TrimSpace(string(body)))
+				if req.URL.Path == "/testdb/_purge" {
+					var revs map[string][]string
+					_ = json.Unmarshal(body, &revs)
+					result, _ := json.Marshal(map[string]interface{}{"purge_seq": 1, "purged": revs})
+					return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(string(result))}, nil
+				}
+				// The second document was updated since it was found.
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`[{"id":"a","rev":"2-a","ok":true},{"id":"b","error":"conflict","reason":"Document update conflict."}]`),
+				}, nil
+			})
+			result, err := db.ExpireDocs(context.Background(), test.opts)
+			testy.StatusError(t, test.err, test.status, err)
+			if result != test.expected {
+				t.Errorf("Expected %d removed, got %d", test.expected, result)
+			}
+			if d := diff.Interface(test.requests, requests); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package couchdb
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
+)
+
+// FeedMetrics counts the activity of the changes feeds followed by Tail,
+// Process and AggregateChanges, when attached to their context with
+// WithFeedMetrics. It is safe for concurrent use, so it may be read by a
+// metrics exporter while feeds are running.
+type FeedMetrics struct {
+	// These are accessed atomically, so must remain 64-bit aligned.
+	changes    int64
+	reconnects int64
+	lastChange int64 // Unix nanoseconds
+
+	mu sync.Mutex
+	// clock is the clock of the client which reported the last change.
+	clock chttp.Clock
+}
+
+type feedMetricsKey struct{}
+
+// WithFeedMetrics returns a context which causes changes feeds followed with
+// it to update m.
+func WithFeedMetrics(ctx context.Context, m *FeedMetrics) context.Context {
+	return context.WithValue(ctx, feedMetricsKey{}, m)
+}
+
+// feedMetrics returns the FeedMetrics attached to ctx, or nil.
+func feedMetrics(ctx context.Context) *FeedMetrics {
+	m, _ := ctx.Value(feedMetricsKey{}).(*FeedMetrics)
+	return m
+}
+
+// Changes returns the number of changes received.
+func (m *FeedMetrics) Changes() int64 {
+	return atomic.LoadInt64(&m.changes)
+}
+
+// Reconnects returns the number of times a feed has been reconnected.
+func (m *FeedMetrics) Reconnects() int64 {
+	return atomic.LoadInt64(&m.reconnects)
+}
+
+// LastChangeAge returns the time since the last change was received, or zero
+// if none has been. A growing age on a busy database suggests the consumer
+// has stalled. It is measured by the Clock of the client which received the
+// change.
+func (m *FeedMetrics) LastChangeAge() time.Duration {
+	last := atomic.LoadInt64(&m.lastChange)
+	if last == 0 {
+		return 0
+	}
+	m.mu.Lock()
+	clock := m.clock
+	m.mu.Unlock()
+	return clock.Now().Sub(time.Unix(0, last))
+}
+
+// observeChange records a change, received at the time given by clock.
+func (m *FeedMetrics) observeChange(clock chttp.Clock) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.clock = clock
+	m.mu.Unlock()
+	atomic.AddInt64(&m.changes, 1)
+	atomic.StoreInt64(&m.lastChange, clock.Now().UnixNano())
+}
+
+func (m *FeedMetrics) observeReconnect() {
+	if m == nil {
+		return
+	}
+	atomic.AddInt64(&m.reconnects, 1)
+}
@@ -17,6 +17,13 @@ func (d *db) CreateIndex(ctx context.Context, ddoc, name string, index interface
 	if d.client.noFind || d.client.Compat == CompatCouch16 {
 		return findNotImplemented
 	}
+	var indexType string
+	if text, ok := textIndex(index); ok {
+		if err := text.validate(); err != nil {
+			return err
+		}
+		indexType = "text"
+	}
 	indexObj, err := deJSONify(index)
 	if err != nil {
 		return err
@@ -25,10 +32,12 @@ func (d *db) CreateIndex(ctx context.Context, ddoc, name string, index interface
 		Index interface{} `json:"index"`
 		Ddoc  string      `json:"ddoc,omitempty"`
 		Name  string      `json:"name,omitempty"`
+		Type  string      `json:"type,omitempty"`
 	}{
 		Index: indexObj,
 		Ddoc:  ddoc,
 		Name:  name,
+		Type:  indexType,
 	}
 	opts := &chttp.Options{
 		Body: chttp.EncodeBody(parameters),
@@ -60,17 +69,40 @@ func (d *db) DeleteIndex(ctx context.Context, ddoc, name string) error {
 	}
 	path := fmt.Sprintf("_index/%s/json/%s", ddoc, name)
 	_, err := d.Client.DoError(ctx, kivik.MethodDelete, d.path(path, nil), nil)
+	if kivik.StatusCode(err) == kivik.StatusNotFound {
+		// The index may be a text index.
+		path = fmt.Sprintf("_index/%s/text/%s", ddoc, name)
+		if _, textErr := d.Client.DoError(ctx, kivik.MethodDelete, d.path(path, nil), nil); kivik.StatusCode(textErr) != kivik.StatusNotFound {
+			return textErr
+		}
+	}
 	return err
 }
 
 func (d *db) Find(ctx context.Context, query interface{}) (driver.Rows, error) {
+	return d.find(ctx, "_find", query)
+}
+
+// find performs a Mango query at path.
+func (d *db) find(ctx context.Context, path string, query interface{}) (driver.Rows, error) {
 	if d.client.noFind || d.client.Compat == CompatCouch16 {
 		return nil, findNotImplemented
 	}
+	if d.queryCache != nil {
+		body, err := json.Marshal(query)
+		if err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+		}
+		rows, err := d.cachedQuery(ctx, kivik.MethodPost, d.path(path, nil), body)
+		if err != nil {
+			return nil, err
+		}
+		return newRows(rows), nil
+	}
 	opts := &chttp.Options{
 		Body: chttp.EncodeBody(query),
 	}
-	resp, err := d.Client.DoReq(ctx, kivik.MethodPost, d.path("_find", nil), opts)
+	resp, err := d.Client.DoReq(ctx, kivik.MethodPost, d.path(path, nil), opts)
 	if err != nil {
 		return nil, err
 	}
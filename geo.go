@@ -0,0 +1,233 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// GeoQuery describes a query of a Cloudant geospatial index. Exactly one of
+// BBox, Radius or Polygon must be set. Coordinates are in the order longitude,
+// latitude.
+type GeoQuery struct {
+	// BBox selects the geometries within the bounding box given by its
+	// minimum longitude, minimum latitude, maximum longitude and maximum
+	// latitude.
+	BBox []float64
+	// Lon and Lat are the centre of the circle of Radius meters within
+	// which geometries are selected.
+	Lon, Lat float64
+	Radius   float64
+	// Polygon selects the geometries within the polygon with these vertices.
+	// The ring is closed automatically.
+	Polygon [][2]float64
+
+	// Relation is the spatial relationship between the query shape and
+	// the selected geometries, such as "intersects" or "contains". It defaults
+	// to "intersects".
+	Relation string
+	// Nearest, if true, sorts the results by their distance from the
+	// centre of the query shape.
+	Nearest bool
+	// Limit, if greater than zero, is the maximum number of results.
+	Limit int
+	// Skip is the number of results to skip.
+	Skip int
+	// Bookmark continues from the end of a previous page of results.
+	Bookmark string
+	// IncludeDocs, if true, includes each document in its result.
+	IncludeDocs bool
+}
+
+// params returns the query parameters for q.
+func (q *GeoQuery) params() (url.Values, error) {
+	params := url.Values{"format": []string{"view"}}
+	var shapes int
+	if len(q.BBox) > 0 {
+		shapes++
+		if len(q.BBox) != 4 {
+			return nil, errors.Status(kivik.StatusBadRequest, "kivik: bbox requires 4 coordinates")
+		}
+		params.Set("bbox", formatCoords(q.BBox, ","))
+	}
+	if q.Radius > 0 {
+		shapes++
+		params.Set("lon", formatCoord(q.Lon))
+		params.Set("lat", formatCoord(q.Lat))
+		params.Set("radius", formatCoord(q.Radius))
+	}
+	if len(q.Polygon) > 0 {
+		shapes++
+		if len(q.Polygon) < 3 {
+			return nil, errors.Status(kivik.StatusBadRequest, "kivik: polygon requires at least 3 vertices")
+		}
+		ring := q.Polygon
+		if ring[0] != ring[len(ring)-1] {
+			ring = append(ring[:len(ring):len(ring)], ring[0])
+		}
+		points := make([]string, len(ring))
+		for i, p := range ring {
+			points[i] = formatCoords(p[:], " ")
+		}
+		params.Set("g", "POLYGON(("+strings.Join(points, ",")+"))")
+	}
+	if shapes != 1 {
+		return nil, errors.Status(kivik.StatusBadRequest, "kivik: exactly one of bbox, radius or polygon required")
+	}
+	if q.Relation != "" {
+		params.Set("relation", q.Relation)
+	}
+	if q.Nearest {
+		params.Set("nearest", "true")
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Skip > 0 {
+		params.Set("skip", strconv.Itoa(q.Skip))
+	}
+	if q.Bookmark != "" {
+		params.Set("bookmark", q.Bookmark)
+	}
+	if q.IncludeDocs {
+		params.Set("include_docs", "true")
+	}
+	return params, nil
+}
+
+func formatCoord(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatCoords(coords []float64, sep string) string {
+	s := make([]string, len(coords))
+	for i, c := range coords {
+		s[i] = formatCoord(c)
+	}
+	return strings.Join(s, sep)
+}
+
+// GeoQuery queries the geospatial index of the design document ddoc,
+// returning the matching geometries as they are read from the response.
+// Geospatial indexes are a Cloudant feature.
+func (d *db) GeoQuery(ctx context.Context, ddoc, index string, q *GeoQuery) (*GeoRows, error) {
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	if index == "" {
+		return nil, missingArg("index")
+	}
+	if q == nil {
+		q = &GeoQuery{}
+	}
+	params, err := q.params()
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("_design/%s/_geo/%s", chttp.EncodeDocID(ddoc), chttp.EncodeDocID(index))
+	resp, err := d.Client.DoReq(ctx, kivik.MethodGet, d.path(path, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		return nil, err
+	}
+	return &GeoRows{body: resp.Body}, nil
+}
+
+// GeoRow is a result of a GeoQuery.
+type GeoRow struct {
+	ID  string `json:"id"`
+	Rev string `json:"rev"`
+	// Geometry is the GeoJSON geometry of the document.
+	Geometry json.RawMessage `json:"geometry"`
+	// Doc is the document, if IncludeDocs was set.
+	Doc json.RawMessage `json:"doc,omitempty"`
+}
+
+// GeoRows is an iterator over the results of a GeoQuery.
+type GeoRows struct {
+	body     io.ReadCloser
+	dec      *json.Decoder
+	bookmark string
+	closed   bool
+}
+
+// Next reads the next result into row. It returns io.EOF when there are no
+// more results.
+func (r *GeoRows) Next(row *GeoRow) error {
+	if r.closed {
+		return io.EOF
+	}
+	if r.dec == nil {
+		r.dec = json.NewDecoder(r.body)
+		if err := consumeDelim(r.dec, json.Delim('{')); err != nil {
+			return err
+		}
+		if err := r.readMeta(); err != nil {
+			r.closed = true
+			return err
+		}
+	}
+	if !r.dec.More() {
+		r.closed = true
+		if err := consumeDelim(r.dec, json.Delim(']')); err != nil {
+			return err
+		}
+		if err := r.readMeta(); err != io.EOF {
+			return err
+		}
+		return io.EOF
+	}
+	*row = GeoRow{}
+	if err := r.dec.Decode(row); err != nil {
+		r.closed = true
+		return errors.WrapStatus(kivik.StatusBadResponse, err)
+	}
+	return nil
+}
+
+// readMeta reads the top-level fields of the response, until the start of
+// the rows, or the end of the response, when it returns io.EOF.
+func (r *GeoRows) readMeta() error {
+	for {
+		t, err := r.dec.Token()
+		if err != nil {
+			return errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		if t == json.Delim('}') {
+			return io.EOF
+		}
+		switch t {
+		case "rows":
+			return consumeDelim(r.dec, json.Delim('['))
+		case "bookmark":
+			err = r.dec.Decode(&r.bookmark)
+		default:
+			var ignored json.RawMessage
+			err = r.dec.Decode(&ignored)
+		}
+		if err != nil {
+			return errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+	}
+}
+
+// Bookmark returns the bookmark with which to request the next page of
+// results. It is valid once Next has returned io.EOF.
+func (r *GeoRows) Bookmark() string {
+	return r.bookmark
+}
+
+// Close closes the response body.
+func (r *GeoRows) Close() error {
+	return r.body.Close()
+}
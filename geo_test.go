@@ -0,0 +1,154 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestGeoQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		ddoc     string
+		index    string
+		query    *GeoQuery
+		body     string
+		path     string
+		rawQuery string
+		rows     []GeoRow
+		bookmark string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no ddoc",
+			index:  "geoidx",
+			query:  &GeoQuery{BBox: []float64{0, 0, 1, 1}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: ddoc required",
+		},
+		{
+			name:   "no index",
+			ddoc:   "geodd",
+			query:  &GeoQuery{BBox: []float64{0, 0, 1, 1}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: index required",
+		},
+		{
+			name:   "no shape",
+			ddoc:   "geodd",
+			index:  "geoidx",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: exactly one of bbox, radius or polygon required",
+		},
+		{
+			name:   "two shapes",
+			ddoc:   "geodd",
+			index:  "geoidx",
+			query:  &GeoQuery{BBox: []float64{0, 0, 1, 1}, Radius: 100},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: exactly one of bbox, radius or polygon required",
+		},
+		{
+			name:   "short bbox",
+			ddoc:   "geodd",
+			index:  "geoidx",
+			query:  &GeoQuery{BBox: []float64{0, 0, 1}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: bbox requires 4 coordinates",
+		},
+		{
+			name:   "short polygon",
+			ddoc:   "geodd",
+			index:  "geoidx",
+			query:  &GeoQuery{Polygon: [][2]float64{{0, 0}, {1, 1}}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: polygon requires at least 3 vertices",
+		},
+		{
+			name:     "bbox",
+			ddoc:     "geodd",
+			index:    "geoidx",
+			query:    &GeoQuery{BBox: []float64{-11.05, 32.28, -10.5, 33.1}, Limit: 10, IncludeDocs: true},
+			body:     `{"bookmark":"g1AAAA","rows":[{"id":"a","rev":"1-x","geometry":{"type":"Point","coordinates":[-10.8,32.5]},"doc":{"_id":"a"}}]}`,
+			path:     "/testdb/_design/geodd/_geo/geoidx",
+			rawQuery: "bbox=-11.05%2C32.28%2C-10.5%2C33.1&format=view&include_docs=true&limit=10",
+			rows: []GeoRow{
+				{ID: "a", Rev: "1-x", Geometry: []byte(`{"type":"Point","coordinates":[-10.8,32.5]}`), Doc: []byte(`{"_id":"a"}`)},
+			},
+			bookmark: "g1AAAA",
+		},
+		{
+			name:     "radius",
+			ddoc:     "geodd",
+			index:    "geoidx",
+			query:    &GeoQuery{Lon: -71.06, Lat: 42.36, Radius: 1000, Relation: "contains", Nearest: true},
+			body:     `{"rows":[{"id":"a","rev":"1-x","geometry":{"type":"Point","coordinates":[-71.06,42.36]}},{"id":"b","rev":"2-y","geometry":{"type":"Point","coordinates":[-71.05,42.36]}}],"bookmark":"g2BBBB"}`,
+			path:     "/testdb/_design/geodd/_geo/geoidx",
+			rawQuery: "format=view&lat=42.36&lon=-71.06&nearest=true&radius=1000&relation=contains",
+			rows: []GeoRow{
+				{ID: "a", Rev: "1-x", Geometry: []byte(`{"type":"Point","coordinates":[-71.06,42.36]}`)},
+				{ID: "b", Rev: "2-y", Geometry: []byte(`{"type":"Point","coordinates":[-71.05,42.36]}`)},
+			},
+			bookmark: "g2BBBB",
+		},
+		{
+			name:     "polygon",
+			ddoc:     "geodd",
+			index:    "geoidx",
+			query:    &GeoQuery{Polygon: [][2]float64{{0, 0}, {1, 0}, {1, 1}}, Bookmark: "g1AAAA", Skip: 5},
+			body:     `{"rows":[]}`,
+			path:     "/testdb/_design/geodd/_geo/geoidx",
+			rawQuery: "bookmark=g1AAAA&format=view&g=POLYGON%28%280+0%2C1+0%2C1+1%2C0+0%29%29&skip=5",
+		},
+		{
+			name:   "not found",
+			ddoc:   "geodd",
+			index:  "geoidx",
+			query:  &GeoQuery{BBox: []float64{0, 0, 1, 1}},
+			status: kivik.StatusNotFound,
+			err:    "Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if test.body == "" {
+					return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+				}
+				if req.URL.Path != test.path {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				if req.URL.RawQuery != test.rawQuery {
+					t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.body)}, nil
+			})
+			rows, err := db.GeoQuery(context.Background(), test.ddoc, test.index, test.query)
+			testy.StatusError(t, test.err, test.status, err)
+			defer rows.Close() // nolint: errcheck
+			var result []GeoRow
+			for {
+				var row GeoRow
+				if err := rows.Next(&row); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				result = append(result, row)
+			}
+			if d := diff.AsJSON(test.rows, result); d != nil {
+				t.Error(d)
+			}
+			if bookmark := rows.Bookmark(); bookmark != test.bookmark {
+				t.Errorf("Unexpected bookmark: %s", bookmark)
+			}
+		})
+	}
+}
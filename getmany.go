@@ -0,0 +1,163 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// DocResult is the outcome of fetching a single document with GetMany.
+type DocResult struct {
+	ID  string
+	Rev string
+	Doc json.RawMessage
+	// Err is set if the document could not be fetched. Its status is
+	// kivik.StatusNotFound for missing and deleted documents.
+	Err error
+}
+
+// GetMany fetches the documents ids, returning a result for each, in the same
+// order. Errors for individual documents are reported in the results; the
+// returned error is set only if the request as a whole fails.
+//
+// The _bulk_get endpoint is used, with opts as its query parameters. Servers
+// which do not support it (CouchDB before 2.0) are instead sent a request to
+// _all_docs with include_docs=true, in which case opts are ignored.
+func (d *db) GetMany(ctx context.Context, ids []string, opts map[string]interface{}) ([]DocResult, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	ctx, cancel, err := withTimeout(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	results, err := d.bulkGet(ctx, ids, opts)
+	if bulkGetUnsupported(err) {
+		return d.getManyAllDocs(ctx, ids)
+	}
+	return results, err
+}
+
+// bulkGetUnsupported returns true if err shows that the server does not
+// support _bulk_get. CouchDB 1.x takes _bulk_get for a document ID, which it
+// rejects as reserved.
+func bulkGetUnsupported(err error) bool {
+	switch kivik.StatusCode(err) {
+	case kivik.StatusNotFound, kivik.StatusMethodNotAllowed:
+		return true
+	case kivik.StatusBadRequest:
+		httpErr, ok := err.(*chttp.HTTPError)
+		return ok && strings.Contains(httpErr.Reason, "Only reserved document ids may start with underscore")
+	}
+	return false
+}
+
+// bulkGet fetches ids with a single _bulk_get request.
+func (d *db) bulkGet(ctx context.Context, ids []string, opts map[string]interface{}) ([]DocResult, error) {
+	docs := make([]driver.BulkGetReference, len(ids))
+	for i, id := range ids {
+		docs[i] = driver.BulkGetReference{ID: id}
+	}
+	rows, err := d.postBulkGet(ctx, docs, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	var response []*bulkGetResult
+	for {
+		result, err := rows.nextResult()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, result)
+	}
+	if len(response) != len(ids) {
+		return nil, errors.Statusf(kivik.StatusBadResponse, "kivik: expected %d results from _bulk_get, got %d", len(ids), len(response))
+	}
+	results := make([]DocResult, len(ids))
+	for i, result := range response {
+		results[i].ID = ids[i]
+		if len(result.Docs) == 0 {
+			results[i].Err = errors.Status(kivik.StatusNotFound, "missing")
+			continue
+		}
+		doc := result.Docs[0]
+		if doc.Error != nil {
+			results[i].Err = rowError(doc.Error.Error, doc.Error.Reason)
+			continue
+		}
+		var meta struct {
+			Rev string `json:"_rev"`
+		}
+		if err := json.Unmarshal(doc.OK, &meta); err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		results[i].Rev = meta.Rev
+		results[i].Doc = doc.OK
+	}
+	return results, nil
+}
+
+// getManyAllDocs fetches ids by POSTing them as keys to _all_docs.
+func (d *db) getManyAllDocs(ctx context.Context, ids []string) ([]DocResult, error) {
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(map[string]interface{}{"keys": ids}),
+	}
+	query := url.Values{"include_docs": []string{"true"}}
+	resp, err := d.Client.DoReq(ctx, kivik.MethodPost, d.path("_all_docs", query), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		return nil, err
+	}
+	rows := newRows(resp.Body)
+	defer rows.Close() // nolint: errcheck
+	results := make([]DocResult, 0, len(ids))
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		result := DocResult{ID: row.ID, Err: row.Error}
+		if result.ID == "" {
+			// Error rows have only a key.
+			_ = json.Unmarshal(row.Key, &result.ID)
+		}
+		var value struct {
+			Rev     string `json:"rev"`
+			Deleted bool   `json:"deleted"`
+		}
+		if row.Error == nil {
+			if err := json.Unmarshal(row.Value, &value); err != nil {
+				return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+			}
+		}
+		switch {
+		case value.Deleted:
+			result.Err = errors.Status(kivik.StatusNotFound, "deleted")
+		case row.Error == nil:
+			result.Rev = value.Rev
+			result.Doc = row.Doc
+		}
+		results = append(results, result)
+	}
+	if len(results) != len(ids) {
+		return nil, errors.Statusf(kivik.StatusBadResponse, "kivik: expected %d rows from _all_docs, got %d", len(ids), len(results))
+	}
+	return results, nil
+}
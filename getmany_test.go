@@ -0,0 +1,154 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestGetMany(t *testing.T) {
+	type result struct {
+		ID     string
+		Rev    string
+		Doc    string
+		Status int
+	}
+	tests := []struct {
+		name     string
+		db       *db
+		expected []result
+		status   int
+		err      string
+	}{
+		{
+			name: "bulk get",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/testdb/_bulk_get" {
+					return nil, errors.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				if revs := req.URL.Query().Get("revs"); revs != "true" {
+					return nil, errors.Errorf("Unexpected revs: %s", revs)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body: Body(`{"results":[
+{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-a"}}]},
+{"id":"b","docs":[{"error":{"id":"b","rev":"undefined","error":"not_found","reason":"missing"}}]},
+{"id":"c","docs":[{"ok":{"_id":"c","_rev":"2-c","x":1}}]}
+]}`),
+				}, nil
+			}),
+			expected: []result{
+				{ID: "a", Rev: "1-a", Doc: `{"_id":"a","_rev":"1-a"}`},
+				{ID: "b", Status: kivik.StatusNotFound},
+				{ID: "c", Rev: "2-c", Doc: `{"_id":"c","_rev":"2-c","x":1}`},
+			},
+		},
+		{
+			name: "all docs fallback",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/testdb/_bulk_get" {
+					return &http.Response{StatusCode: kivik.StatusMethodNotAllowed, Request: req, Body: Body("")}, nil
+				}
+				if req.URL.Path != "/testdb/_all_docs" {
+					return nil, errors.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				if _, ok := req.URL.Query()["revs"]; ok {
+					return nil, errors.New("Unexpected revs option")
+				}
+				if req.URL.Query().Get("include_docs") != "true" {
+					return nil, errors.New("include_docs not set")
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body: Body(`{"total_rows":5,"offset":0,"rows":[
+{"id":"a","key":"a","value":{"rev":"1-a"},"doc":{"_id":"a","_rev":"1-a"}},
+{"key":"b","error":"not_found"},
+{"id":"c","key":"c","value":{"rev":"3-c","deleted":true},"doc":null}
+]}`),
+				}, nil
+			}),
+			expected: []result{
+				{ID: "a", Rev: "1-a", Doc: `{"_id":"a","_rev":"1-a"}`},
+				{ID: "b", Status: kivik.StatusNotFound},
+				{ID: "c", Status: kivik.StatusNotFound},
+			},
+		},
+		{
+			name: "CouchDB 1.x fallback",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/testdb/_bulk_get" {
+					return &http.Response{
+						StatusCode:    kivik.StatusBadRequest,
+						Request:       req,
+						ContentLength: -1,
+						Header:        http.Header{"Content-Type": {"application/json"}},
+						Body:          Body(`{"error":"bad_request","reason":"Only reserved document ids may start with underscore."}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body: Body(`{"total_rows":5,"offset":0,"rows":[
+{"id":"a","key":"a","value":{"rev":"1-a"},"doc":{"_id":"a","_rev":"1-a"}},
+{"key":"b","error":"not_found"},
+{"key":"c","error":"not_found"}
+]}`),
+				}, nil
+			}),
+			expected: []result{
+				{ID: "a", Rev: "1-a", Doc: `{"_id":"a","_rev":"1-a"}`},
+				{ID: "b", Status: kivik.StatusNotFound},
+				{ID: "c", Status: kivik.StatusNotFound},
+			},
+		},
+		{
+			name: "bad request",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/testdb/_bulk_get" {
+					return nil, errors.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				return &http.Response{
+					StatusCode:    kivik.StatusBadRequest,
+					Request:       req,
+					ContentLength: -1,
+					Header:        http.Header{"Content-Type": {"application/json"}},
+					Body:          Body(`{"error":"bad_request","reason":"Invalid revs value"}`),
+				}, nil
+			}),
+			status: kivik.StatusBadRequest,
+			err:    "Bad Request: Invalid revs value",
+		},
+		{
+			name: "short response",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"results":[]}`),
+			}, nil),
+			status: kivik.StatusBadResponse,
+			err:    "kivik: expected 3 results from _bulk_get, got 0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := test.db.GetMany(context.Background(), []string{"a", "b", "c"}, map[string]interface{}{"revs": true})
+			testy.StatusError(t, test.err, test.status, err)
+			if len(results) != len(test.expected) {
+				t.Fatalf("Expected %d results, got %d", len(test.expected), len(results))
+			}
+			for i, r := range results {
+				got := result{ID: r.ID, Rev: r.Rev, Doc: string(r.Doc)}
+				if r.Err != nil {
+					got.Status = kivik.StatusCode(r.Err)
+				}
+				if got != test.expected[i] {
+					t.Errorf("Unexpected result %d: %+v", i, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/go-kivik/kivik/driver"
+)
+
+// globalChangesDB is the name of the database in which CouchDB 2.x records
+// database-level events.
+const globalChangesDB = "_global_changes"
+
+// GlobalChange is a database event recorded in the _global_changes database.
+type GlobalChange struct {
+	// DBName is the name of the database to which the event applies.
+	DBName string
+	// Type is the type of event, such as "created", "updated" or "deleted".
+	Type string
+	// Seq is the sequence of the event in the _global_changes changes feed.
+	Seq string
+}
+
+// parseGlobalChange parses a _global_changes document ID, which takes the
+// form <type>:<db name>. ok is false for any other ID, such as a design doc.
+func parseGlobalChange(docID string) (eventType, dbName string, ok bool) {
+	parts := strings.SplitN(docID, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.HasPrefix(docID, "_") {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// GlobalChanges is a feed of events from the _global_changes database.
+type GlobalChanges struct {
+	changes driver.Changes
+	client  *client
+}
+
+// GlobalChanges returns a feed of the events recorded in the _global_changes
+// database. options are passed through to the underlying changes feed.
+func (c *client) GlobalChanges(ctx context.Context, options map[string]interface{}) (*GlobalChanges, error) {
+	d := &db{client: c, dbName: globalChangesDB}
+	changes, err := d.Changes(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return &GlobalChanges{changes: changes, client: c}, nil
+}
+
+// Next populates event with the next event in the feed, skipping any
+// documents which do not describe an event, and events of databases which the
+// client may not access. It returns io.EOF at the end of the feed.
+func (g *GlobalChanges) Next(event *GlobalChange) error {
+	for {
+		change := &driver.Change{}
+		if err := g.changes.Next(change); err != nil {
+			return err
+		}
+		eventType, dbName, ok := parseGlobalChange(change.ID)
+		if !ok || !g.client.dbAllowed(dbName) {
+			continue
+		}
+		*event = GlobalChange{
+			DBName: dbName,
+			Type:   eventType,
+			Seq:    string(change.Seq),
+		}
+		return nil
+	}
+}
+
+// Close closes the feed.
+func (g *GlobalChanges) Close() error {
+	return g.changes.Close()
+}
+
+// WatchGlobalChanges calls fn for each event in the _global_changes database,
+// until the feed ends, ctx is cancelled, or fn returns an error, which is then
+// returned. options are passed through to the underlying changes feed.
+func (c *client) WatchGlobalChanges(ctx context.Context, options map[string]interface{}, fn func(*GlobalChange) error) error {
+	feed, err := c.GlobalChanges(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer feed.Close() // nolint: errcheck
+	for {
+		event := &GlobalChange{}
+		if err := feed.Next(event); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
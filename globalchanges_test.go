@@ -0,0 +1,71 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestParseGlobalChange(t *testing.T) {
+	tests := []struct {
+		id       string
+		typ, db  string
+		expected bool
+	}{
+		{id: "updated:foo", typ: "updated", db: "foo", expected: true},
+		{id: "created:foo:bar", typ: "created", db: "foo:bar", expected: true},
+		{id: "_design/foo"},
+		{id: "foo"},
+		{id: "updated:"},
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			typ, db, ok := parseGlobalChange(test.id)
+			if ok != test.expected || typ != test.typ || db != test.db {
+				t.Errorf("Unexpected result: %s, %s, %t", typ, db, ok)
+			}
+		})
+	}
+}
+
+func TestWatchGlobalChanges(t *testing.T) {
+	client := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/_global_changes/_changes" {
+			return nil, errors.New("Unexpected path: " + req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body: Body(`{"seq":"1-xxx","id":"created:foo","changes":[{"rev":"1-aaa"}]}
+{"seq":"2-xxx","id":"_design/_auth","changes":[{"rev":"1-bbb"}]}
+{"seq":"3-xxx","id":"deleted:foo","changes":[{"rev":"2-ccc"}]}
+`),
+		}, nil
+	})
+	var events []GlobalChange
+	err := client.WatchGlobalChanges(context.Background(), nil, func(event *GlobalChange) error {
+		events = append(events, *event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []GlobalChange{
+		{DBName: "foo", Type: "created", Seq: "1-xxx"},
+		{DBName: "foo", Type: "deleted", Seq: "3-xxx"},
+	}
+	if d := diff.Interface(expected, events); d != nil {
+		t.Error(d)
+	}
+	stop := errors.New("stop")
+	err = client.WatchGlobalChanges(context.Background(), nil, func(_ *GlobalChange) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
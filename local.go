@@ -0,0 +1,115 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+const prefixLocal = "_local/"
+
+// localPath returns the path of the local document id, which may be given with
+// or without the _local/ prefix.
+func (d *db) localPath(id string, query url.Values) (string, error) {
+	id = strings.TrimPrefix(id, prefixLocal)
+	if id == "" {
+		return "", missingArg("id")
+	}
+	return d.path(chttp.EncodeDocID(prefixLocal+id), query), nil
+}
+
+// GetLocal returns the raw JSON body of the local document id. The _local/
+// prefix is optional.
+func (d *db) GetLocal(ctx context.Context, id string) ([]byte, error) {
+	path, err := d.localPath(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.Client.DoReq(ctx, kivik.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusNetworkError, err)
+	}
+	return body, nil
+}
+
+// localRev returns the current rev of the local document id.
+func (d *db) localRev(ctx context.Context, id string) (string, error) {
+	body, err := d.GetLocal(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	var doc struct {
+		Rev string `json:"_rev"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", errors.WrapStatus(kivik.StatusBadResponse, err)
+	}
+	return doc.Rev, nil
+}
+
+// PutLocal stores doc as the local document id, replacing any existing
+// version. Unlike Put, no rev is required; any _rev field in doc is ignored.
+func (d *db) PutLocal(ctx context.Context, id string, doc interface{}) error {
+	path, err := d.localPath(id, nil)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return errors.Status(kivik.StatusBadRequest, "kivik: local document must be a JSON object")
+	}
+	delete(fields, "_rev")
+	put := func() error {
+		_, err := d.Client.DoError(ctx, kivik.MethodPut, path, &chttp.Options{
+			Body: chttp.EncodeBody(fields),
+		})
+		return err
+	}
+	err = put()
+	if kivik.StatusCode(err) != kivik.StatusConflict {
+		return err
+	}
+	// Some server versions require the current rev even for local documents.
+	rev, err := d.localRev(ctx, id)
+	if err != nil {
+		return err
+	}
+	fields["_rev"] = rev
+	return put()
+}
+
+// DeleteLocal deletes the local document id, whatever its current rev.
+func (d *db) DeleteLocal(ctx context.Context, id string) error {
+	rev, err := d.localRev(ctx, id)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if rev != "" {
+		query.Set("rev", rev)
+	}
+	path, err := d.localPath(id, query)
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.DoError(ctx, kivik.MethodDelete, path, nil)
+	return err
+}
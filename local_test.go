@@ -0,0 +1,135 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestGetLocal(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       *db
+		id       string
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no id",
+			id:     "_local/",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: id required",
+		},
+		{
+			name: "success",
+			id:   "foo/bar",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if path := req.URL.EscapedPath(); path != "/testdb/_local/foo%2Fbar" {
+					t.Errorf("Unexpected path: %s", path)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"_id":"_local/foo/bar","_rev":"0-1","seq":10}`),
+				}, nil
+			}),
+			expected: `{"_id":"_local/foo/bar","_rev":"0-1","seq":10}`,
+		},
+		{
+			name: "not found",
+			id:   "_local/foo",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusNotFound,
+				Request:    &http.Request{Method: kivik.MethodGet},
+				Body:       Body(""),
+			}, nil),
+			status: kivik.StatusNotFound,
+			err:    "Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.db.GetLocal(context.Background(), test.id)
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.JSON([]byte(test.expected), result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestPutLocal(t *testing.T) {
+	var requests []string
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			var err error
+			if body, err = ioutil.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+		}
+		requests = append(requests, req.Method+" "+string(body))
+		switch {
+		case req.Method == kivik.MethodGet:
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"_id":"_local/foo","_rev":"0-3","seq":5}`),
+			}, nil
+		case len(requests) == 1:
+			return &http.Response{
+				StatusCode: kivik.StatusConflict,
+				Request:    req,
+				Body:       Body(""),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusCreated,
+			Body:       Body(`{"ok":true,"id":"_local/foo","rev":"0-4"}`),
+		}, nil
+	})
+	err := db.PutLocal(context.Background(), "foo", map[string]interface{}{"_rev": "bogus", "seq": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"PUT {\"seq\":10}\n",
+		"GET ",
+		"PUT {\"_rev\":\"0-3\",\"seq\":10}\n",
+	}
+	if d := diff.Interface(expected, requests); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestDeleteLocal(t *testing.T) {
+	var requests []string
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.String())
+		if req.Method == kivik.MethodGet {
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"_id":"_local/foo","_rev":"0-3"}`),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body:       Body(`{"ok":true,"id":"_local/foo","rev":"0-0"}`),
+		}, nil
+	})
+	if err := db.DeleteLocal(context.Background(), "_local/foo"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"GET http://example.com/testdb/_local/foo",
+		"DELETE http://example.com/testdb/_local/foo?rev=0-3",
+	}
+	if d := diff.Interface(expected, requests); d != nil {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,106 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// Principal is a user or role named in a database's security object.
+type Principal struct {
+	Name string
+	// Role is true if Name is a role, rather than a user name.
+	Role bool
+}
+
+// securityRetries is the number of times a change to the security object is
+// retried after being overwritten by a concurrent change.
+var securityRetries = 3
+
+// AddMember grants p read and write access to the database.
+func (d *db) AddMember(ctx context.Context, p Principal) error {
+	return d.updateSecurity(ctx, "members", p, true)
+}
+
+// RemoveMember revokes the membership of p. If no members remain, the database
+// becomes readable and writable by anyone.
+func (d *db) RemoveMember(ctx context.Context, p Principal) error {
+	return d.updateSecurity(ctx, "members", p, false)
+}
+
+// AddAdmin makes p an admin of the database.
+func (d *db) AddAdmin(ctx context.Context, p Principal) error {
+	return d.updateSecurity(ctx, "admins", p, true)
+}
+
+// RemoveAdmin revokes the admin privileges of p.
+func (d *db) RemoveAdmin(ctx context.Context, p Principal) error {
+	return d.updateSecurity(ctx, "admins", p, false)
+}
+
+// updateSecurity adds p to, or removes it from, the field of the security
+// object, leaving the rest of it unchanged. As the security object has no
+// revision with which to detect a concurrent change, it is read back after
+// each write, and the change is repeated if it was overwritten.
+func (d *db) updateSecurity(ctx context.Context, field string, p Principal, add bool) error {
+	if p.Name == "" {
+		return missingArg("name")
+	}
+	for attempt := 0; ; attempt++ {
+		var sec map[string]json.RawMessage
+		if _, err := d.Client.DoJSON(ctx, kivik.MethodGet, d.path("_security", nil), nil, &sec); err != nil {
+			return err
+		}
+		if sec == nil {
+			sec = map[string]json.RawMessage{}
+		}
+		var members driver.Members
+		if raw, ok := sec[field]; ok {
+			if err := json.Unmarshal(raw, &members); err != nil {
+				return errors.WrapStatus(kivik.StatusBadResponse, err)
+			}
+		}
+		list := &members.Names
+		if p.Role {
+			list = &members.Roles
+		}
+		updated, changed := updateList(*list, p.Name, add)
+		if !changed {
+			return nil
+		}
+		if attempt == securityRetries {
+			return errors.Status(kivik.StatusConflict, "kivik: security object modified concurrently")
+		}
+		*list = updated
+		var err error
+		if sec[field], err = json.Marshal(members); err != nil {
+			return errors.WrapStatus(kivik.StatusBadRequest, err)
+		}
+		opts := &chttp.Options{
+			Body: chttp.EncodeBody(sec),
+		}
+		if _, err = d.Client.DoError(ctx, kivik.MethodPut, d.path("_security", nil), opts); err != nil {
+			return err
+		}
+	}
+}
+
+// updateList returns list with name added or removed, and whether it changed.
+func updateList(list []string, name string, add bool) ([]string, bool) {
+	for i, n := range list {
+		if n == name {
+			if add {
+				return list, false
+			}
+			return append(list[:i:i], list[i+1:]...), true
+		}
+	}
+	if !add {
+		return list, false
+	}
+	return append(list, name), true
+}
@@ -0,0 +1,122 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestUpdateSecurity(t *testing.T) {
+	tests := []struct {
+		name     string
+		security string
+		update   func(*db) error
+		// lost is the number of writes to be overwritten by a concurrent
+		// change.
+		lost     int
+		expected string
+		puts     int
+		status   int
+		err      string
+	}{
+		{
+			name:   "no name",
+			update: func(d *db) error { return d.AddMember(context.Background(), Principal{}) },
+			status: kivik.StatusBadRequest,
+			err:    "kivik: name required",
+		},
+		{
+			name:     "add member to empty security object",
+			security: `{}`,
+			update:   func(d *db) error { return d.AddMember(context.Background(), Principal{Name: "bob"}) },
+			expected: `{"members":{"names":["bob"]}}`,
+			puts:     1,
+		},
+		{
+			name:     "add admin role, preserving other fields",
+			security: `{"admins":{"names":["alice"]},"members":{"roles":["staff"]},"couchdb_auth_only":true}`,
+			update:   func(d *db) error { return d.AddAdmin(context.Background(), Principal{Name: "ops", Role: true}) },
+			expected: `{"admins":{"names":["alice"],"roles":["ops"]},"members":{"roles":["staff"]},"couchdb_auth_only":true}`,
+			puts:     1,
+		},
+		{
+			name:     "already a member",
+			security: `{"members":{"names":["bob"]}}`,
+			update:   func(d *db) error { return d.AddMember(context.Background(), Principal{Name: "bob"}) },
+			expected: `{"members":{"names":["bob"]}}`,
+		},
+		{
+			name:     "remove member",
+			security: `{"members":{"names":["bob","carol"],"roles":["bob"]}}`,
+			update:   func(d *db) error { return d.RemoveMember(context.Background(), Principal{Name: "bob"}) },
+			expected: `{"members":{"names":["carol"],"roles":["bob"]}}`,
+			puts:     1,
+		},
+		{
+			name:     "remove absent admin",
+			security: `{"admins":{"names":["alice"]}}`,
+			update:   func(d *db) error { return d.RemoveAdmin(context.Background(), Principal{Name: "bob"}) },
+			expected: `{"admins":{"names":["alice"]}}`,
+		},
+		{
+			name:     "retry after concurrent change",
+			security: `{}`,
+			update:   func(d *db) error { return d.AddMember(context.Background(), Principal{Name: "bob"}) },
+			lost:     1,
+			expected: `{"members":{"names":["bob"]}}`,
+			puts:     2,
+		},
+		{
+			name:     "retries exhausted",
+			security: `{}`,
+			update:   func(d *db) error { return d.AddMember(context.Background(), Principal{Name: "bob"}) },
+			lost:     5,
+			expected: `{}`,
+			puts:     3,
+			status:   kivik.StatusConflict,
+			err:      "kivik: security object modified concurrently",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			security := test.security
+			lost := test.lost
+			var puts int
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/testdb/_security" {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				if req.Method == kivik.MethodPut {
+					puts++
+					body, err := ioutil.ReadAll(req.Body)
+					if err != nil {
+						return nil, err
+					}
+					if lost > 0 {
+						lost--
+					} else {
+						security = string(body)
+					}
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"ok":true}`)}, nil
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(security)}, nil
+			})
+			err := test.update(db)
+			if puts != test.puts {
+				t.Errorf("Expected %d writes, got %d", test.puts, puts)
+			}
+			if test.expected != "" {
+				if d := diff.JSON([]byte(test.expected), []byte(security)); d != nil {
+					t.Error(d)
+				}
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// DBRow is a view result row returned by QueryMany, along with the name of the
+// database from which it came.
+type DBRow struct {
+	DBName string
+	driver.Row
+}
+
+// QueryMany runs the same view query against each of dbNames concurrently,
+// and merges the results into a single list, ordered as CouchDB would order
+// the results of a single database, according to CollateKeys. Rows with equal
+// keys are ordered by document ID, then by the order of dbNames. Rows from
+// different databases are not combined, so reduced results with equal keys
+// appear once per database.
+//
+// The descending and limit options apply to the merged result. As each
+// database must be queried from the start of the range, the skip option is
+// not supported.
+func (c *client) QueryMany(ctx context.Context, dbNames []string, ddoc, view string, opts map[string]interface{}) ([]DBRow, error) {
+	if _, ok := opts["skip"]; ok {
+		return nil, errors.Status(kivik.StatusBadRequest, "kivik: skip is not supported by QueryMany")
+	}
+	for _, dbName := range dbNames {
+		if err := c.checkDB(dbName); err != nil {
+			return nil, err
+		}
+	}
+	descending, err := queryDescending(opts)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryLimit(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	results := make([][]DBRow, len(dbNames))
+	for i, dbName := range dbNames {
+		// The query options are modified as they are processed.
+		dbOpts := copyOptions(opts)
+		wg.Add(1)
+		go func(i int, dbName string) {
+			defer wg.Done()
+			rows, err := c.queryRows(ctx, dbName, ddoc, view, dbOpts)
+			if err != nil {
+				// Only the first error is reported, as it causes the others.
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = rows
+		}(i, dbName)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return mergeRows(results, descending, limit), nil
+}
+
+// queryRows returns all of the rows of a view query against dbName.
+func (c *client) queryRows(ctx context.Context, dbName, ddoc, view string, opts map[string]interface{}) ([]DBRow, error) {
+	d := &db{client: c, dbName: dbName}
+	rows, err := d.Query(ctx, ddoc, view, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	var result []DBRow
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				return result, nil
+			}
+			return nil, err
+		}
+		result = append(result, DBRow{DBName: dbName, Row: row})
+	}
+}
+
+// mergeRows merges the sorted lists of rows, stopping after limit rows if
+// limit is not negative.
+func mergeRows(lists [][]DBRow, descending bool, limit int) []DBRow {
+	var merged []DBRow
+	for limit < 0 || len(merged) < limit {
+		next := -1
+		for i, list := range lists {
+			if len(list) == 0 {
+				continue
+			}
+			if next < 0 {
+				next = i
+				continue
+			}
+			c := compareRows(&list[0], &lists[next][0])
+			if descending {
+				c = -c
+			}
+			if c < 0 {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		merged = append(merged, lists[next][0])
+		lists[next] = lists[next][1:]
+	}
+	return merged
+}
+
+// compareRows compares rows by key, then by document ID. Rows with malformed
+// keys, which CouchDB never returns, compare as equal.
+func compareRows(a, b *DBRow) int {
+	c, err := CollateKeys(a.Key, b.Key)
+	if err != nil {
+		return 0
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
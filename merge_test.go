@@ -0,0 +1,162 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestQueryMany(t *testing.T) {
+	responses := map[string]string{
+		"/a/_design/ddoc/_view/view": `{"rows":[
+{"id":"1","key":null,"value":1},
+{"id":"2","key":"a","value":1},
+{"id":"3","key":["b"],"value":1}
+]}`,
+		"/b/_design/ddoc/_view/view": `{"rows":[
+{"id":"4","key":false,"value":2},
+{"id":"1","key":"a","value":2},
+{"id":"5","key":"B","value":2}
+]}`,
+	}
+	descending := map[string]string{
+		"/a/_design/ddoc/_view/view": `{"rows":[
+{"id":"3","key":["b"],"value":1},
+{"id":"2","key":"a","value":1},
+{"id":"1","key":null,"value":1}
+]}`,
+		"/b/_design/ddoc/_view/view": `{"rows":[
+{"id":"5","key":"B","value":2},
+{"id":"1","key":"a","value":2},
+{"id":"4","key":false,"value":2}
+]}`,
+	}
+	newClient := func() *client {
+		return newCustomClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("limit") == "" {
+				return nil, errors.New("limit not sent")
+			}
+			views := responses
+			if req.URL.Query().Get("descending") == "true" {
+				views = descending
+			}
+			body, ok := views[req.URL.Path]
+			if !ok {
+				return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+			}
+			return &http.Response{StatusCode: kivik.StatusOK, Body: Body(body)}, nil
+		})
+	}
+	tests := []struct {
+		name     string
+		dbNames  []string
+		opts     map[string]interface{}
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:     "ascending",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": 10},
+			expected: []string{"a/1 null", "b/4 false", "b/1 \"a\"", "a/2 \"a\"", "b/5 \"B\"", "a/3 [\"b\"]"},
+		},
+		{
+			name:     "limit",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": 2},
+			expected: []string{"a/1 null", "b/4 false"},
+		},
+		{
+			name:     "descending",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": 10, "descending": true},
+			expected: []string{"a/3 [\"b\"]", "b/5 \"B\"", "a/2 \"a\"", "b/1 \"a\"", "b/4 false", "a/1 null"},
+		},
+		{
+			name:     "descending as string",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": 10, "descending": "true"},
+			expected: []string{"a/3 [\"b\"]", "b/5 \"B\"", "a/2 \"a\"", "b/1 \"a\"", "b/4 false", "a/1 null"},
+		},
+		{
+			name:     "int64 limit",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": int64(2)},
+			expected: []string{"a/1 null", "b/4 false"},
+		},
+		{
+			name:     "float64 limit",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": float64(2)},
+			expected: []string{"a/1 null", "b/4 false"},
+		},
+		{
+			name:     "string limit",
+			dbNames:  []string{"a", "b"},
+			opts:     map[string]interface{}{"limit": "2"},
+			expected: []string{"a/1 null", "b/4 false"},
+		},
+		{
+			name:    "invalid limit",
+			dbNames: []string{"a", "b"},
+			opts:    map[string]interface{}{"limit": 1.5},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: invalid value '1.5' for integer option 'limit'",
+		},
+		{
+			name:    "skip",
+			dbNames: []string{"a", "b"},
+			opts:    map[string]interface{}{"skip": 1},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: skip is not supported by QueryMany",
+		},
+		{
+			name:    "missing database",
+			dbNames: []string{"a", "c"},
+			opts:    map[string]interface{}{"limit": 10},
+			status:  kivik.StatusNotFound,
+			err:     "Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := newClient().QueryMany(context.Background(), test.dbNames, "ddoc", "view", test.opts)
+			testy.StatusError(t, test.err, test.status, err)
+			var result []string
+			for _, row := range rows {
+				result = append(result, row.DBName+"/"+row.ID+" "+string(row.Key))
+			}
+			if d := diff.Interface(test.expected, result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestMergeRowsDescending(t *testing.T) {
+	row := func(dbName, id, key string) DBRow {
+		r := DBRow{DBName: dbName}
+		r.ID = id
+		r.Key = []byte(key)
+		return r
+	}
+	lists := [][]DBRow{
+		{row("a", "3", `"c"`), row("a", "1", `"a"`)},
+		{row("b", "2", `"b"`), row("b", "1", `"a"`)},
+	}
+	var result []string
+	for _, r := range mergeRows(lists, true, -1) {
+		result = append(result, r.DBName+"/"+r.ID+" "+string(r.Key))
+	}
+	expected := []string{`a/3 "c"`, `b/2 "b"`, `a/1 "a"`, `b/1 "a"`}
+	if d := diff.Interface(expected, result); d != nil {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,259 @@
+package couchdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// extractAttachments returns the attachments of doc, a map or struct with an
+// _attachments field of type kivik.Attachments, and true if any of them has
+// content to upload.
+func extractAttachments(doc interface{}) (kivik.Attachments, bool) {
+	var atts kivik.Attachments
+	switch t := doc.(type) {
+	case map[string]interface{}:
+		atts = toAttachments(t["_attachments"])
+	default:
+		v := reflect.ValueOf(doc)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil, false
+		}
+		for i := 0; i < v.NumField(); i++ {
+			tag := strings.SplitN(v.Type().Field(i).Tag.Get("json"), ",", 2)[0]
+			if tag == "_attachments" && v.Field(i).CanInterface() {
+				atts = toAttachments(v.Field(i).Interface())
+				break
+			}
+		}
+	}
+	for _, att := range atts {
+		if att != nil && !att.Stub && att.Content != nil {
+			return atts, true
+		}
+	}
+	return nil, false
+}
+
+func toAttachments(i interface{}) kivik.Attachments {
+	switch t := i.(type) {
+	case kivik.Attachments:
+		return t
+	case *kivik.Attachments:
+		if t != nil {
+			return *t
+		}
+	}
+	return nil
+}
+
+// multipartAttachment is an attachment to be sent in a multipart/related
+// request.
+type multipartAttachment struct {
+	filename    string
+	contentType string
+	size        int64
+	content     io.ReadCloser
+}
+
+// newMultipartBody returns a multipart/related request body, its content
+// type, and its length, for doc and those of atts with content, so that
+// attachments are sent as they are, rather than base64-encoded in the JSON
+// document. Attachments without content are sent as stubs, to be kept from the
+// current revision. The content of each attachment is streamed as the body is
+// read. As CouchDB requires the length of each, the content of attachments of
+// unknown size (Size of 0 or less) is first copied to a temporary file.
+func newMultipartBody(doc interface{}, atts kivik.Attachments) (io.ReadCloser, string, int64, error) {
+	filenames := make([]string, 0, len(atts))
+	for filename := range atts {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	stubs := make(map[string]interface{}, len(atts))
+	mb := &multipartBody{}
+	for _, filename := range filenames {
+		att := atts[filename]
+		if att == nil || att.Stub || att.Content == nil {
+			stubs[filename] = map[string]interface{}{"stub": true}
+			continue
+		}
+		part := &multipartAttachment{
+			filename:    filename,
+			contentType: att.ContentType,
+			size:        att.Size,
+			content:     att.Content,
+		}
+		if part.contentType == "" {
+			part.contentType = "application/octet-stream"
+		}
+		if part.size <= 0 {
+			var err error
+			if part.content, part.size, err = spoolAttachment(att.Content); err != nil {
+				_ = mb.Close()
+				return nil, "", 0, err
+			}
+		}
+		stubs[filename] = map[string]interface{}{
+			"follows":      true,
+			"content_type": part.contentType,
+			"length":       part.size,
+		}
+		mb.parts = append(mb.parts, part)
+	}
+	body, err := multipartDoc(doc, atts, stubs)
+	if err != nil {
+		_ = mb.Close()
+		return nil, "", 0, err
+	}
+	var length int64
+	var boundary string
+	mb.Reader, length, boundary = multipartReader(body, mb.parts)
+	return mb, "multipart/related; boundary=" + boundary, length, nil
+}
+
+// multipartBody is a multipart/related request body, which closes the content
+// of each attachment when it is closed.
+type multipartBody struct {
+	io.Reader
+	parts []*multipartAttachment
+}
+
+func (b *multipartBody) Close() error {
+	for _, part := range b.parts {
+		_ = part.content.Close()
+	}
+	return nil
+}
+
+// spoolAttachment copies content, of unknown size, to a temporary file, which
+// is removed when the returned ReadCloser is closed, so that its size is known
+// without holding it in memory.
+func spoolAttachment(content io.ReadCloser) (io.ReadCloser, int64, error) {
+	defer content.Close() // nolint: errcheck
+	f, err := ioutil.TempFile("", "kivik-attachment-")
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := io.Copy(f, content)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, 0, err
+	}
+	return &tempFile{File: f}, size, nil
+}
+
+// tempFile is a temporary file, removed when it is closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	if e := os.Remove(f.Name()); err == nil {
+		err = e
+	}
+	return err
+}
+
+// multipartDoc encodes doc with its _attachments, atts, replaced by stubs.
+func multipartDoc(doc interface{}, atts kivik.Attachments, stubs map[string]interface{}) ([]byte, error) {
+	// Attachments with content are marked as following while doc is encoded,
+	// so that their content is not read and base64-encoded into it.
+	for _, att := range atts {
+		if att != nil && !att.Stub && !att.Follows {
+			att.Follows = true
+			defer func(att *kivik.Attachment) { att.Follows = false }(att)
+		}
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return nil, errors.Status(kivik.StatusBadRequest, "kivik: document must be a JSON object")
+	}
+	if fields["_attachments"], err = json.Marshal(stubs); err != nil {
+		return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	return json.Marshal(fields)
+}
+
+// multipartReader returns a reader of the multipart/related body made of the
+// JSON document, followed by the content of each attachment, in the order in
+// which they appear in the document, along with its length and boundary. Only
+// the boundaries and part headers are held in memory; the content of each
+// attachment is read as the body is.
+func multipartReader(doc []byte, parts []*multipartAttachment) (io.Reader, int64, string) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	var readers []io.Reader
+	var length int64
+	// flush adds what mw has written since the last call to the body.
+	flush := func() {
+		segment := make([]byte, buf.Len())
+		copy(segment, buf.Bytes())
+		buf.Reset()
+		readers = append(readers, bytes.NewReader(segment))
+		length += int64(len(segment))
+	}
+	// Writes to a bytes.Buffer cannot fail.
+	w, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	_, _ = w.Write(doc)
+	for _, part := range parts {
+		_, _ = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		flush()
+		readers = append(readers, &exactReader{r: part.content, n: part.size, filename: part.filename})
+		length += part.size
+	}
+	_ = mw.Close()
+	flush()
+	return io.MultiReader(readers...), length, mw.Boundary()
+}
+
+// exactReader reads the content of an attachment, failing if it is shorter or
+// longer than the length declared for it in the document.
+type exactReader struct {
+	r        io.Reader
+	n        int64
+	filename string
+}
+
+func (r *exactReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		var b [1]byte
+		if n, _ := io.ReadFull(r.r, b[:]); n > 0 {
+			return 0, errors.Statusf(kivik.StatusBadRequest, "kivik: attachment '%s' is longer than its size", r.filename)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.r.Read(p)
+	r.n -= int64(n)
+	if err == io.EOF {
+		if r.n > 0 {
+			return n, errors.Statusf(kivik.StatusBadRequest, "kivik: attachment '%s' is shorter than its size", r.filename)
+		}
+		err = nil
+	}
+	return n, err
+}
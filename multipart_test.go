@@ -0,0 +1,164 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestExtractAttachments(t *testing.T) {
+	content := &kivik.Attachment{Content: Body("foo")}
+	stub := &kivik.Attachment{Stub: true}
+	type doc struct {
+		ID          string             `json:"_id"`
+		Attachments *kivik.Attachments `json:"_attachments,omitempty"`
+	}
+	tests := []struct {
+		name     string
+		doc      interface{}
+		expected bool
+	}{
+		{
+			name: "no attachments",
+			doc:  map[string]interface{}{"_id": "foo"},
+		},
+		{
+			name:     "map",
+			doc:      map[string]interface{}{"_attachments": kivik.Attachments{"foo.txt": content}},
+			expected: true,
+		},
+		{
+			name: "stubs only",
+			doc:  map[string]interface{}{"_attachments": kivik.Attachments{"foo.txt": stub}},
+		},
+		{
+			name:     "struct",
+			doc:      &doc{Attachments: &kivik.Attachments{"foo.txt": content}},
+			expected: true,
+		},
+		{
+			name: "struct without attachments",
+			doc:  doc{},
+		},
+		{
+			name: "raw JSON",
+			doc:  []byte(`{"_attachments":{}}`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, ok := extractAttachments(test.doc); ok != test.expected {
+				t.Errorf("Expected %t, got %t", test.expected, ok)
+			}
+		})
+	}
+}
+
+func TestPutMultipart(t *testing.T) {
+	var parts []string
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		ct, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		if ct != "multipart/related" {
+			t.Errorf("Unexpected Content-Type: %s", ct)
+		}
+		raw, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if req.ContentLength != int64(len(raw)) {
+			t.Errorf("Content-Length %d, but %d bytes sent", req.ContentLength, len(raw))
+		}
+		mr := multipart.NewReader(bytes.NewReader(raw), params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			body, err := ioutil.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part.Header.Get("Content-Type")+" "+string(body))
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusCreated,
+			Body:       Body(`{"ok":true,"id":"foo","rev":"1-xxx"}`),
+		}, nil
+	})
+	doc := map[string]interface{}{
+		"_id":   "foo",
+		"value": 1,
+		"_attachments": kivik.Attachments{
+			"b.txt":   {ContentType: "text/plain", Content: ioutil.NopCloser(strings.NewReader("bbb")), Size: 3},
+			"a.bin":   {Content: ioutil.NopCloser(strings.NewReader("aaaa"))},
+			"old.txt": {Stub: true},
+		},
+	}
+	rev, err := db.Put(context.Background(), "foo", doc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != "1-xxx" {
+		t.Errorf("Unexpected rev: %s", rev)
+	}
+	expected := []string{
+		`application/json {"_attachments":{"a.bin":{"content_type":"application/octet-stream","follows":true,"length":4},"b.txt":{"content_type":"text/plain","follows":true,"length":3},"old.txt":{"stub":true}},"_id":"foo","value":1}`,
+		"application/octet-stream aaaa",
+		"text/plain bbb",
+	}
+	if d := diff.Interface(expected, parts); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestPutMultipartSizeMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "short",
+			content: "aaa",
+			err:     "kivik: attachment 'a.txt' is shorter than its size",
+		},
+		{
+			name:    "long",
+			content: "aaaaaaa",
+			err:     "kivik: attachment 'a.txt' is longer than its size",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if _, err := ioutil.ReadAll(req.Body); err != nil {
+					return nil, err
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`{"ok":true,"id":"foo","rev":"1-xxx"}`),
+				}, nil
+			})
+			doc := map[string]interface{}{
+				"_attachments": kivik.Attachments{
+					"a.txt": {ContentType: "text/plain", Content: ioutil.NopCloser(strings.NewReader(test.content)), Size: 5},
+				},
+			}
+			_, err := db.Put(context.Background(), "foo", doc, nil)
+			testy.StatusError(t, test.err, kivik.StatusBadRequest, err)
+		})
+	}
+}
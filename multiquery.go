@@ -0,0 +1,76 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+var multiQueryNotImplemented = errors.Status(kivik.StatusNotImplemented, "kivik: multiple queries not supported prior to CouchDB 2.2.0")
+
+// QueryMulti performs several queries of a view in a single request,
+// returning a result set for each query, in the same order. Each query is a
+// map of view query options, which are sent as JSON in the request body, so
+// that keys are given as Go values rather than as JSON-encoded strings. It
+// requires CouchDB 2.2 or later.
+//
+// Example:
+//
+//    results, err := db.QueryMulti(ctx, "ddoc", "view", []map[string]interface{}{
+//        {"keys": []string{"foo", "bar"}},
+//        {"startkey": "a", "endkey": "m", "limit": 10},
+//    })
+func (d *db) QueryMulti(ctx context.Context, ddoc, view string, queries []map[string]interface{}) ([]driver.Rows, error) {
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	if view == "" {
+		return nil, missingArg("view")
+	}
+	return d.multiQuery(ctx, fmt.Sprintf("_design/%s/_view/%s/queries", chttp.EncodeDocID(ddoc), chttp.EncodeDocID(view)), queries)
+}
+
+// AllDocsMulti performs several queries of _all_docs in a single request, as
+// described for QueryMulti.
+func (d *db) AllDocsMulti(ctx context.Context, queries []map[string]interface{}) ([]driver.Rows, error) {
+	return d.multiQuery(ctx, "_all_docs/queries", queries)
+}
+
+func (d *db) multiQuery(ctx context.Context, path string, queries []map[string]interface{}) ([]driver.Rows, error) {
+	if d.client.Compat == CompatCouch16 {
+		return nil, multiQueryNotImplemented
+	}
+	if len(queries) == 0 {
+		return nil, missingArg("queries")
+	}
+	for _, query := range queries {
+		if err := validateQueryOptions(query); err != nil {
+			return nil, err
+		}
+		d.client.warnQueryOptions(query)
+	}
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(map[string]interface{}{"queries": queries}),
+	}
+	var result struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodPost, d.path(path, nil), opts, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Results) != len(queries) {
+		return nil, errors.Statusf(kivik.StatusBadResponse, "kivik: %d results returned for %d queries", len(result.Results), len(queries))
+	}
+	rows := make([]driver.Rows, len(result.Results))
+	for i, raw := range result.Results {
+		rows[i] = newRows(ioutil.NopCloser(bytes.NewReader(raw)))
+	}
+	return rows, nil
+}
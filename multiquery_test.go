@@ -0,0 +1,135 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestQueryMulti(t *testing.T) {
+	tests := []struct {
+		name     string
+		compat   CompatMode
+		ddoc     string
+		view     string
+		queries  []map[string]interface{}
+		response string
+		path     string
+		body     string
+		expected [][]string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no ddoc",
+			view:   "bar",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: ddoc required",
+		},
+		{
+			name:   "no queries",
+			ddoc:   "foo",
+			view:   "bar",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: queries required",
+		},
+		{
+			name:    "CouchDB 1.6",
+			compat:  CompatCouch16,
+			ddoc:    "foo",
+			view:    "bar",
+			queries: []map[string]interface{}{{"limit": 1}},
+			status:  kivik.StatusNotImplemented,
+			err:     "kivik: multiple queries not supported prior to CouchDB 2.2.0",
+		},
+		{
+			name:    "invalid query",
+			ddoc:    "foo",
+			view:    "bar",
+			queries: []map[string]interface{}{{"keys": []string{"a"}, "key": "a"}},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: option 'keys' is incompatible with 'key'",
+		},
+		{
+			name:     "result count mismatch",
+			ddoc:     "foo",
+			view:     "bar",
+			queries:  []map[string]interface{}{{"limit": 1}, {"limit": 2}},
+			response: `{"results":[{"rows":[]}]}`,
+			path:     "/testdb/_design/foo/_view/bar/queries",
+			body:     `{"queries":[{"limit":1},{"limit":2}]}`,
+			status:   kivik.StatusBadResponse,
+			err:      "kivik: 1 results returned for 2 queries",
+		},
+		{
+			name:     "success",
+			ddoc:     "foo",
+			view:     "bar",
+			queries:  []map[string]interface{}{{"keys": []string{"a", "c"}}, {"startkey": "x", "limit": 1}},
+			response: `{"results":[{"total_rows":3,"offset":0,"rows":[{"id":"a","key":"a","value":1},{"id":"c","key":"c","value":3}]},{"total_rows":3,"offset":2,"rows":[{"id":"x","key":"x","value":24}]}]}`,
+			path:     "/testdb/_design/foo/_view/bar/queries",
+			body:     `{"queries":[{"keys":["a","c"]},{"startkey":"x","limit":1}]}`,
+			expected: [][]string{{"a", "c"}, {"x"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != test.path {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				if d := diff.JSON([]byte(test.body), body); d != nil {
+					t.Error(d)
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.response)}, nil
+			})
+			db.Compat = test.compat
+			results, err := db.QueryMulti(context.Background(), test.ddoc, test.view, test.queries)
+			testy.StatusError(t, test.err, test.status, err)
+			ids := make([][]string, len(results))
+			for i, rows := range results {
+				ids[i] = []string{}
+				var row driver.Row
+				for {
+					if err := rows.Next(&row); err == io.EOF {
+						break
+					} else if err != nil {
+						t.Fatal(err)
+					}
+					ids[i] = append(ids[i], row.ID)
+				}
+			}
+			if d := diff.Interface(test.expected, ids); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestAllDocsMulti(t *testing.T) {
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/testdb/_all_docs/queries" {
+			t.Errorf("Unexpected path: %s", req.URL.Path)
+		}
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"results":[{"rows":[]}]}`)}, nil
+	})
+	results, err := db.AllDocsMulti(context.Background(), []map[string]interface{}{{"limit": 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Unexpected results: %d", len(results))
+	}
+}
@@ -0,0 +1,127 @@
+package couchdb
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// LocalNode refers to the node which handles the request, in place of a node
+// name.
+const LocalNode = "_local"
+
+// Values of the couchdb/maintenance_mode configuration setting.
+const (
+	// MaintenanceOff is normal operation.
+	MaintenanceOff = "false"
+	// MaintenanceOn stops the node from responding to cluster requests, and
+	// makes its /_up endpoint report a 404, so that load balancers remove it.
+	MaintenanceOn = "true"
+	// MaintenanceNoLB makes /_up report a 404, but leaves the node
+	// participating in the cluster.
+	MaintenanceNoLB = "nolb"
+)
+
+// nodePollInterval is the interval at which WaitForNode polls the server.
+var nodePollInterval = time.Second
+
+// nodePath returns the path of endpoint on node, which defaults to LocalNode.
+func nodePath(node, endpoint string) string {
+	if node == "" {
+		node = LocalNode
+	}
+	return "/_node/" + url.PathEscape(node) + "/" + endpoint
+}
+
+// RestartNode restarts the CouchDB server running node (CouchDB 2.2 and
+// later). The server typically drops the connection while restarting, so a
+// network error does not necessarily mean the restart failed; use WaitForNode
+// to wait until the node is available again.
+func (c *client) RestartNode(ctx context.Context, node string) error {
+	_, err := c.DoError(ctx, kivik.MethodPost, nodePath(node, "_restart"), nil)
+	return err
+}
+
+// MaintenanceMode returns the current maintenance mode of node, one of
+// MaintenanceOff, MaintenanceOn or MaintenanceNoLB.
+func (c *client) MaintenanceMode(ctx context.Context, node string) (string, error) {
+	var mode string
+	_, err := c.DoJSON(ctx, kivik.MethodGet, nodePath(node, "_config/couchdb/maintenance_mode"), nil, &mode)
+	if kivik.StatusCode(err) == kivik.StatusNotFound {
+		return MaintenanceOff, nil
+	}
+	return mode, err
+}
+
+// SetMaintenanceMode sets the maintenance mode of node to mode, and returns
+// the previous mode, so that it may be restored.
+func (c *client) SetMaintenanceMode(ctx context.Context, node, mode string) (string, error) {
+	switch mode {
+	case MaintenanceOff, MaintenanceOn, MaintenanceNoLB:
+	default:
+		return "", errors.Statusf(kivik.StatusBadRequest, "kivik: invalid maintenance mode '%s'", mode)
+	}
+	opts := &chttp.Options{
+		// Strings are sent unencoded by EncodeBody, and mode needs no escaping.
+		Body: chttp.EncodeBody(`"` + mode + `"`),
+	}
+	var previous string
+	if _, err := c.DoJSON(ctx, kivik.MethodPut, nodePath(node, "_config/couchdb/maintenance_mode"), opts, &previous); err != nil {
+		return "", err
+	}
+	if previous == "" {
+		previous = MaintenanceOff
+	}
+	return previous, nil
+}
+
+// WaitForNode polls node until it responds, for instance after RestartNode,
+// or until ctx is cancelled.
+func (c *client) WaitForNode(ctx context.Context, node string) error {
+	for {
+		_, err := c.DoError(ctx, kivik.MethodGet, nodePath(node, "_system"), nil)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.clock().After(nodePollInterval):
+		}
+	}
+}
+
+// NodeVersions describes the versions of the software components of a node,
+// as reported by /_node/{node}/_versions (CouchDB 2.3.1 and later). Fields
+// which the server does not report are left empty.
+type NodeVersions struct {
+	Erlang struct {
+		Version         string   `json:"version"`
+		SupportedHashes []string `json:"supported_hashes,omitempty"`
+	} `json:"erlang"`
+	CollationDriver struct {
+		Name                      string `json:"name"`
+		LibraryVersion            string `json:"library_version"`
+		CollatorVersion           string `json:"collator_version"`
+		CollationAlgorithmVersion string `json:"collation_algorithm_version"`
+	} `json:"collation_driver"`
+	JavaScriptEngine struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"javascript_engine"`
+}
+
+// NodeVersions returns the versions of the Erlang runtime, collation library
+// and JavaScript engine used by node, so that the nodes of a cluster can be
+// checked for homogeneity.
+func (c *client) NodeVersions(ctx context.Context, node string) (*NodeVersions, error) {
+	versions := &NodeVersions{}
+	if _, err := c.DoJSON(ctx, kivik.MethodGet, nodePath(node, "_versions"), nil, versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
@@ -0,0 +1,181 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestRestartNode(t *testing.T) {
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != kivik.MethodPost || req.URL.Path != "/_node/couchdb@10.0.0.1/_restart" {
+			return nil, errors.Errorf("Unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"ok":true}`)}, nil
+	})
+	if err := c.RestartNode(context.Background(), "couchdb@10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   *client
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name: "set",
+			client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/_node/_local/_config/couchdb/maintenance_mode" {
+					return nil, errors.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`"nolb"`)}, nil
+			}),
+			expected: MaintenanceNoLB,
+		},
+		{
+			name: "unset",
+			client: newTestClient(&http.Response{
+				StatusCode: kivik.StatusNotFound,
+				Body:       Body(""),
+			}, nil),
+			expected: MaintenanceOff,
+		},
+		{
+			name: "unauthorized",
+			client: newTestClient(&http.Response{
+				StatusCode: kivik.StatusUnauthorized,
+				Body:       Body(""),
+			}, nil),
+			status: kivik.StatusUnauthorized,
+			err:    "Unauthorized",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mode, err := test.client.MaintenanceMode(context.Background(), "")
+			testy.StatusError(t, test.err, test.status, err)
+			if mode != test.expected {
+				t.Errorf("Unexpected mode: %s", mode)
+			}
+		})
+	}
+}
+
+func TestSetMaintenanceMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		response string
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name:   "invalid mode",
+			mode:   "maybe",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid maintenance mode 'maybe'",
+		},
+		{
+			name:     "previously unset",
+			mode:     MaintenanceOn,
+			response: `""`,
+			expected: MaintenanceOff,
+		},
+		{
+			name:     "restore",
+			mode:     MaintenanceOff,
+			response: `"true"`,
+			expected: MaintenanceOn,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				if value := strings.TrimSpace(string(body)); value != `"`+test.mode+`"` {
+					return nil, errors.Errorf("Unexpected body: %s", value)
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.response)}, nil
+			})
+			previous, err := c.SetMaintenanceMode(context.Background(), "", test.mode)
+			testy.StatusError(t, test.err, test.status, err)
+			if previous != test.expected {
+				t.Errorf("Unexpected previous mode: %s", previous)
+			}
+		})
+	}
+}
+
+func TestWaitForNode(t *testing.T) {
+	defer func(interval time.Duration) { nodePollInterval = interval }(nodePollInterval)
+	nodePollInterval = time.Millisecond
+	var attempts int
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if attempts++; attempts < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{}`)}, nil
+	})
+	if err := c.WaitForNode(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c = newTestClient(nil, errors.New("connection refused"))
+	err := c.WaitForNode(ctx, "")
+	testy.Error(t, "context deadline exceeded", err)
+}
+
+func TestNodeVersions(t *testing.T) {
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/_node/_local/_versions" {
+			return nil, errors.Errorf("Unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body: Body(`{
+				"javascript_engine":{"name":"spidermonkey","version":"1.8.5"},
+				"erlang":{"version":"22.3.4.26","supported_hashes":["sha","sha256"]},
+				"collation_driver":{"name":"libicu","library_version":"70.1","collator_version":"153.112","collation_algorithm_version":"14"}
+			}`),
+		}, nil
+	})
+	versions, err := c.NodeVersions(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &NodeVersions{}
+	expected.Erlang.Version = "22.3.4.26"
+	expected.Erlang.SupportedHashes = []string{"sha", "sha256"}
+	expected.CollationDriver.Name = "libicu"
+	expected.CollationDriver.LibraryVersion = "70.1"
+	expected.CollationDriver.CollatorVersion = "153.112"
+	expected.CollationDriver.CollationAlgorithmVersion = "14"
+	expected.JavaScriptEngine.Name = "spidermonkey"
+	expected.JavaScriptEngine.Version = "1.8.5"
+	if d := diff.Interface(expected, versions); d != nil {
+		t.Error(d)
+	}
+}
@@ -1,6 +1,12 @@
 package couchdb
 
 import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
 	"github.com/go-kivik/kivik"
 	"github.com/go-kivik/kivik/errors"
 )
@@ -35,3 +41,204 @@ func ifNoneMatch(opts map[string]interface{}) (string, error) {
 	}
 	return inmString, nil
 }
+
+func ifRange(opts map[string]interface{}) (string, error) {
+	ir, ok := opts[OptionIfRange]
+	if !ok {
+		return "", nil
+	}
+	irString, ok := ir.(string)
+	if !ok {
+		return "", errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be string, not %T", OptionIfRange, ir)
+	}
+	delete(opts, OptionIfRange)
+	if irString != "" && irString[0] != '"' {
+		return `"` + irString + `"`, nil
+	}
+	return irString, nil
+}
+
+func rangeHeader(opts map[string]interface{}) (string, error) {
+	r, ok := opts[OptionRange]
+	if !ok {
+		return "", nil
+	}
+	rString, ok := r.(string)
+	if !ok {
+		return "", errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be string, not %T", OptionRange, r)
+	}
+	delete(opts, OptionRange)
+	return rString, nil
+}
+
+func progressFunc(opts map[string]interface{}) (ProgressFunc, error) {
+	p, ok := opts[OptionProgress]
+	if !ok {
+		return nil, nil
+	}
+	delete(opts, OptionProgress)
+	switch fn := p.(type) {
+	case ProgressFunc:
+		return fn, nil
+	case func(int64):
+		return fn, nil
+	}
+	return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be ProgressFunc, not %T", OptionProgress, p)
+}
+
+func requestTimeout(opts map[string]interface{}) (time.Duration, error) {
+	t, ok := opts[OptionRequestTimeout]
+	if !ok {
+		return 0, nil
+	}
+	timeout, ok := t.(time.Duration)
+	if !ok {
+		return 0, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be time.Duration, not %T", OptionRequestTimeout, t)
+	}
+	if timeout < 0 {
+		return 0, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must not be negative", OptionRequestTimeout)
+	}
+	delete(opts, OptionRequestTimeout)
+	return timeout, nil
+}
+
+func tombstone(opts map[string]interface{}) (bool, error) {
+	t, ok := opts[OptionTombstone]
+	if !ok {
+		return false, nil
+	}
+	ts, ok := t.(bool)
+	if !ok {
+		return false, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be bool, not %T", OptionTombstone, t)
+	}
+	delete(opts, OptionTombstone)
+	return ts, nil
+}
+
+func deletedRev(opts map[string]interface{}) (bool, error) {
+	d, ok := opts[OptionDeletedRev]
+	if !ok {
+		return false, nil
+	}
+	lookup, ok := d.(bool)
+	if !ok {
+		return false, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be bool, not %T", OptionDeletedRev, d)
+	}
+	delete(opts, OptionDeletedRev)
+	return lookup, nil
+}
+
+func retryTimeouts(opts map[string]interface{}) (bool, error) {
+	r, ok := opts[OptionRetryTimeouts]
+	if !ok {
+		return false, nil
+	}
+	retry, ok := r.(bool)
+	if !ok {
+		return false, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be bool, not %T", OptionRetryTimeouts, r)
+	}
+	delete(opts, OptionRetryTimeouts)
+	return retry, nil
+}
+
+// allOrNothing returns the value of OptionAllOrNothing. As it is a request
+// parameter, it is left in opts.
+func allOrNothing(opts map[string]interface{}) (bool, error) {
+	a, ok := opts[OptionAllOrNothing]
+	if !ok {
+		return false, nil
+	}
+	aon, ok := a.(bool)
+	if !ok {
+		return false, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be bool, not %T", OptionAllOrNothing, a)
+	}
+	return aon, nil
+}
+
+func docAccess(opts map[string]interface{}) ([]string, error) {
+	a, ok := opts[OptionDocAccess]
+	if !ok {
+		return nil, nil
+	}
+	access, ok := a.([]string)
+	if !ok {
+		return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be []string, not %T", OptionDocAccess, a)
+	}
+	delete(opts, OptionDocAccess)
+	return access, nil
+}
+
+func writeResult(opts map[string]interface{}) (*WriteResult, error) {
+	w, ok := opts[OptionWriteResult]
+	if !ok {
+		return nil, nil
+	}
+	wr, ok := w.(*WriteResult)
+	if !ok {
+		return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' must be *WriteResult, not %T", OptionWriteResult, w)
+	}
+	delete(opts, OptionWriteResult)
+	return wr, nil
+}
+
+// queryLimit returns the value of the limit option, which may be given as any
+// type accepted for the query parameter, or -1 if it is not set. As it is a
+// request parameter, it is left in opts.
+func queryLimit(opts map[string]interface{}) (int, error) {
+	l, ok := opts["limit"]
+	if !ok {
+		return -1, nil
+	}
+	encoded, err := chttp.EncodeParam("limit", l)
+	if err != nil {
+		return 0, err
+	}
+	limit, err := strconv.Atoi(encoded[0])
+	if err != nil || limit < 0 {
+		return 0, errors.Statusf(kivik.StatusBadRequest, "kivik: invalid value '%v' for integer option 'limit'", l)
+	}
+	return limit, nil
+}
+
+// queryDescending returns the value of the descending option, which may be
+// given as any type accepted for the query parameter. As it is a request
+// parameter, it is left in opts.
+func queryDescending(opts map[string]interface{}) (bool, error) {
+	d, ok := opts["descending"]
+	if !ok {
+		return false, nil
+	}
+	encoded, err := chttp.EncodeParam("descending", d)
+	if err != nil {
+		return false, err
+	}
+	return encoded[0] == "true", nil
+}
+
+// withTimeout derives a context from ctx, which expires after the timeout set
+// by OptionRequestTimeout in opts, if any. The returned cancel function must
+// always be called.
+func withTimeout(ctx context.Context, opts map[string]interface{}) (context.Context, context.CancelFunc, error) {
+	timeout, err := requestTimeout(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeout == 0 {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// cancelBody wraps a streaming response body, releasing the request's context
+// once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -1,7 +1,10 @@
 package couchdb
 
 import (
+	"context"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/flimzy/testy"
 
@@ -136,3 +139,107 @@ func TestIfNoneMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestRangeHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     map[string]interface{}
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name:     "nil",
+			opts:     nil,
+			expected: "",
+		},
+		{
+			name:   "wrong type",
+			opts:   map[string]interface{}{OptionRange: 123},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'Range' must be string, not int",
+		},
+		{
+			name:     "valid",
+			opts:     map[string]interface{}{OptionRange: "bytes=10-"},
+			expected: "bytes=10-",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := rangeHeader(test.opts)
+			testy.StatusError(t, test.err, test.status, err)
+			if result != test.expected {
+				t.Errorf("Unexpected result: %s", result)
+			}
+			if _, ok := test.opts[OptionRange]; ok {
+				t.Errorf("%s still set in options", OptionRange)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     map[string]interface{}
+		expected time.Duration
+		status   int
+		err      string
+	}{
+		{
+			name:     "nil",
+			opts:     nil,
+			expected: 0,
+		},
+		{
+			name:   "wrong type",
+			opts:   map[string]interface{}{OptionRequestTimeout: 123},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'request_timeout' must be time.Duration, not int",
+		},
+		{
+			name:   "negative",
+			opts:   map[string]interface{}{OptionRequestTimeout: -time.Second},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'request_timeout' must not be negative",
+		},
+		{
+			name:     "valid",
+			opts:     map[string]interface{}{OptionRequestTimeout: time.Second},
+			expected: time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := requestTimeout(test.opts)
+			testy.StatusError(t, test.err, test.status, err)
+			if result != test.expected {
+				t.Errorf("Unexpected result: %s", result)
+			}
+			if _, ok := test.opts[OptionRequestTimeout]; ok {
+				t.Errorf("%s still set in options", OptionRequestTimeout)
+			}
+		})
+	}
+}
+
+func TestRequestTimeoutDeadline(t *testing.T) {
+	var deadline time.Time
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		deadline, _ = req.Context().Deadline()
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body:       Body(`{"rows":[]}`),
+		}, nil
+	})
+	start := time.Now()
+	rows, err := db.AllDocs(context.Background(), map[string]interface{}{OptionRequestTimeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close() // nolint: errcheck
+	if deadline.Before(start.Add(time.Minute)) || deadline.After(time.Now().Add(time.Minute)) {
+		t.Errorf("Unexpected deadline: %v", deadline)
+	}
+}
@@ -0,0 +1,283 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// Outbox is a queue of writes, persisted to a local file, for clients whose
+// connection to the server is intermittent. Put and Delete queue a write
+// without contacting the server, and ReplayOutbox applies the queued writes,
+// in order, retrying each until the server accepts or rejects it. Queued
+// writes survive restarts, but as the file is rewritten for each change to
+// the queue, it is intended for modest numbers of pending writes.
+//
+// Writes are replayed with last-write-wins semantics: a Put replaces whatever
+// revision of the document is current when it is replayed, as Upsert does,
+// and a Delete deletes the current revision, if any.
+type Outbox struct {
+	// RetryDelay is the delay before retrying a write which failed due to a
+	// network or server error. It doubles with each consecutive failure, up
+	// to MaxRetryDelay. It defaults to one second.
+	RetryDelay time.Duration
+
+	// MaxRetryDelay is the longest delay between retries. It defaults to one
+	// minute.
+	MaxRetryDelay time.Duration
+
+	// Rejected, if set, is called with each write which the server rejects,
+	// such as for being forbidden or invalid, along with the error. If it
+	// returns nil, the write is dropped, and replay continues. If Rejected is
+	// not set, or returns an error, replay stops, and the write remains
+	// queued.
+	Rejected func(op *OutboxOp, err error) error
+
+	// Clock, if set, is used to timestamp queued writes, and to time the
+	// delay between retries. It defaults to the chttp.SystemClock.
+	Clock chttp.Clock
+
+	path   string
+	mu     sync.Mutex
+	ops    []*OutboxOp
+	nextID int64
+	// queued is signalled when a write is queued.
+	queued chan struct{}
+}
+
+// OutboxOp is a write queued in an Outbox.
+type OutboxOp struct {
+	ID      int64           `json:"id"`
+	DBName  string          `json:"db"`
+	DocID   string          `json:"doc_id"`
+	Doc     json.RawMessage `json:"doc,omitempty"`
+	Deleted bool            `json:"deleted,omitempty"`
+	Queued  time.Time       `json:"queued"`
+}
+
+// outboxFile is the content of an Outbox's file.
+type outboxFile struct {
+	NextID int64       `json:"next_id"`
+	Ops    []*OutboxOp `json:"ops"`
+}
+
+// OpenOutbox opens the Outbox stored in the file at path, which is created
+// when the first write is queued, if it does not exist.
+func OpenOutbox(path string) (*Outbox, error) {
+	o := &Outbox{
+		path:   path,
+		nextID: 1,
+		queued: make(chan struct{}, 1),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file outboxFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "kivik: invalid outbox file %s", path)
+	}
+	o.ops = file.Ops
+	if file.NextID > o.nextID {
+		o.nextID = file.NextID
+	}
+	return o, nil
+}
+
+// Put queues the storing of doc as docID in dbName. Any _rev field in doc is
+// ignored when the write is replayed.
+func (o *Outbox) Put(dbName, docID string, doc interface{}) error {
+	if dbName == "" {
+		return missingArg("dbName")
+	}
+	if err := validateDocID(docID); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	return o.enqueue(&OutboxOp{DBName: dbName, DocID: docID, Doc: raw})
+}
+
+// Delete queues the deletion of docID from dbName.
+func (o *Outbox) Delete(dbName, docID string) error {
+	if dbName == "" {
+		return missingArg("dbName")
+	}
+	if err := validateDocID(docID); err != nil {
+		return err
+	}
+	return o.enqueue(&OutboxOp{DBName: dbName, DocID: docID, Deleted: true})
+}
+
+// Pending returns the queued writes, in the order in which they will be
+// replayed.
+func (o *Outbox) Pending() []OutboxOp {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	pending := make([]OutboxOp, len(o.ops))
+	for i, op := range o.ops {
+		pending[i] = *op
+	}
+	return pending
+}
+
+// clock returns the Outbox's Clock, or the chttp.SystemClock.
+func (o *Outbox) clock() chttp.Clock {
+	if o.Clock != nil {
+		return o.Clock
+	}
+	return chttp.SystemClock
+}
+
+func (o *Outbox) enqueue(op *OutboxOp) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	op.ID = o.nextID
+	op.Queued = o.clock().Now()
+	ops := append(o.ops[:len(o.ops):len(o.ops)], op)
+	if err := o.save(ops, o.nextID+1); err != nil {
+		return err
+	}
+	o.ops = ops
+	o.nextID++
+	select {
+	case o.queued <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// next returns the first queued write, or nil if there are none.
+func (o *Outbox) next() *OutboxOp {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.ops) == 0 {
+		return nil
+	}
+	return o.ops[0]
+}
+
+// remove removes the write id from the queue.
+func (o *Outbox) remove(id int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ops := make([]*OutboxOp, 0, len(o.ops))
+	for _, op := range o.ops {
+		if op.ID != id {
+			ops = append(ops, op)
+		}
+	}
+	if err := o.save(ops, o.nextID); err != nil {
+		return err
+	}
+	o.ops = ops
+	return nil
+}
+
+// save replaces the outbox file with one containing ops.
+func (o *Outbox) save(ops []*OutboxOp, nextID int64) error {
+	data, err := json.Marshal(outboxFile{NextID: nextID, Ops: ops})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(o.path, data)
+}
+
+// ReplayOutbox applies the writes queued in o, in order, waiting for more to
+// be queued when there are none. Writes which fail due to network or server
+// errors are retried, as configured by o, until they succeed, and writes
+// which the server rejects are passed to o.Rejected. ReplayOutbox returns when
+// ctx is cancelled, or when a rejected write stops replay.
+func (c *client) ReplayOutbox(ctx context.Context, o *Outbox) error {
+	delay := o.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	maxDelay := o.MaxRetryDelay
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+	wait := delay
+	for {
+		op := o.next()
+		if op == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-o.queued:
+			}
+			continue
+		}
+		err := c.applyOutboxOp(ctx, op)
+		if err != nil && transientError(err) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-o.clock().After(wait):
+			}
+			if wait *= 2; wait > maxDelay {
+				wait = maxDelay
+			}
+			continue
+		}
+		wait = delay
+		if err != nil {
+			if o.Rejected == nil {
+				return err
+			}
+			if e := o.Rejected(op, err); e != nil {
+				return e
+			}
+		}
+		if err := o.remove(op.ID); err != nil {
+			return err
+		}
+	}
+}
+
+// applyOutboxOp applies a single queued write.
+func (c *client) applyOutboxOp(ctx context.Context, op *OutboxOp) error {
+	if err := c.checkDB(op.DBName); err != nil {
+		return err
+	}
+	d := &db{client: c, dbName: op.DBName}
+	if !op.Deleted {
+		_, err := d.Upsert(ctx, op.DocID, op.Doc)
+		return err
+	}
+	rev, err := d.currentRev(ctx, op.DocID)
+	if err != nil || rev == "" {
+		return err
+	}
+	_, err = d.Delete(ctx, op.DocID, rev, nil)
+	if kivik.StatusCode(err) == kivik.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// transientError returns true if err may succeed when retried.
+func transientError(err error) bool {
+	switch status := kivik.StatusCode(err); status {
+	case kivik.StatusNetworkError, kivik.StatusConflict, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= 500
+	}
+}
@@ -0,0 +1,200 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func tempOutbox(t *testing.T) (*Outbox, func()) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := OpenOutbox(filepath.Join(dir, "outbox.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestOutboxPersistence(t *testing.T) {
+	o, cleanup := tempOutbox(t)
+	defer cleanup()
+	if err := o.Put("db", "foo", map[string]string{"foo": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Delete("db", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("no dbName", func(t *testing.T) {
+		err := o.Put("", "foo", nil)
+		testy.StatusError(t, "kivik: dbName required", kivik.StatusBadRequest, err)
+	})
+	reopened, err := OpenOutbox(o.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := reopened.Pending()
+	for i := range pending {
+		pending[i].Queued = time.Time{}
+	}
+	expected := []OutboxOp{
+		{ID: 1, DBName: "db", DocID: "foo", Doc: []byte(`{"foo":"bar"}`)},
+		{ID: 2, DBName: "db", DocID: "bar", Deleted: true},
+	}
+	if d := diff.Interface(expected, pending); d != nil {
+		t.Error(d)
+	}
+	if reopened.nextID != 3 {
+		t.Errorf("Unexpected next ID: %d", reopened.nextID)
+	}
+}
+
+func TestOpenOutboxInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	path := filepath.Join(dir, "outbox.json")
+	if err := ioutil.WriteFile(path, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenOutbox(path)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestReplayOutbox(t *testing.T) {
+	o, cleanup := tempOutbox(t)
+	defer cleanup()
+	o.RetryDelay = time.Millisecond
+	for _, id := range []string{"foo", "bad"} {
+		if err := o.Put("db", id, map[string]string{"foo": "bar"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Delete("db", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var requests []string
+	var failed bool
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case !failed:
+			failed = true
+			return nil, errors.New("connection refused")
+		case req.Method == kivik.MethodHead && req.URL.Path == "/db/baz":
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Header:     http.Header{"ETag": {`"1-xxx"`}},
+				Body:       Body(""),
+			}, nil
+		case req.Method == kivik.MethodHead:
+			return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+		case req.URL.Path == "/db/bad":
+			return &http.Response{StatusCode: kivik.StatusForbidden, Request: req, Body: Body("")}, nil
+		case req.Method == kivik.MethodDelete:
+			cancel()
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Header:     http.Header{"ETag": {`"2-xxx"`}},
+				Body:       Body(`{"ok":true,"id":"baz","rev":"2-xxx"}`),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusCreated,
+			Body:       Body(`{"ok":true,"id":"foo","rev":"1-xxx"}`),
+		}, nil
+	})
+	var rejected []string
+	o.Rejected = func(op *OutboxOp, err error) error {
+		rejected = append(rejected, op.DocID)
+		return nil
+	}
+	err := c.ReplayOutbox(ctx, o)
+	expected := []string{
+		"HEAD /db/foo",
+		"HEAD /db/foo",
+		"PUT /db/foo",
+		"HEAD /db/bad",
+		"PUT /db/bad",
+		"HEAD /db/baz",
+		"DELETE /db/baz",
+	}
+	if d := diff.Interface(expected, requests); d != nil {
+		t.Error(d)
+	}
+	if d := diff.Interface([]string{"bad"}, rejected); d != nil {
+		t.Error(d)
+	}
+	if pending := o.Pending(); len(pending) != 0 {
+		t.Errorf("Unexpected pending writes: %v", pending)
+	}
+	testy.Error(t, "context canceled", err)
+}
+
+func TestTransientError(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected bool
+	}{
+		{status: kivik.StatusNetworkError, expected: true},
+		{status: kivik.StatusInternalServerError, expected: true},
+		{status: http.StatusTooManyRequests, expected: true},
+		{status: kivik.StatusConflict, expected: true},
+		{status: kivik.StatusForbidden},
+		{status: kivik.StatusBadRequest},
+	}
+	for _, test := range tests {
+		err := errors.Status(test.status, "test error")
+		if result := transientError(err); result != test.expected {
+			t.Errorf("transientError(%d) = %t, expected %t", test.status, result, test.expected)
+		}
+	}
+}
+
+func TestOutboxClock(t *testing.T) {
+	o, cleanup := tempOutbox(t)
+	defer cleanup()
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := &fakeClock{now: now}
+	o.Clock = clock
+	o.MaxRetryDelay = 3 * time.Second
+	if err := o.Put("db", "foo", map[string]string{"foo": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if queued := o.Pending()[0].Queued; !queued.Equal(now) {
+		t.Errorf("Unexpected queued time: %s", queued)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var attempts int
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if attempts++; attempts == 4 {
+			cancel()
+		}
+		return nil, errors.New("connection refused")
+	})
+	err := c.ReplayOutbox(ctx, o)
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if d := diff.Interface(expected, clock.waits); d != nil {
+		t.Error(d)
+	}
+	testy.Error(t, "context canceled", err)
+}
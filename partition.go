@@ -0,0 +1,84 @@
+package couchdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// PartitionStats describes a single partition of a partitioned database.
+type PartitionStats struct {
+	DBName      string `json:"db_name"`
+	Partition   string `json:"partition"`
+	DocCount    int64  `json:"doc_count"`
+	DocDelCount int64  `json:"doc_del_count"`
+	Sizes       struct {
+		Active   int64 `json:"active"`
+		External int64 `json:"external"`
+	} `json:"sizes"`
+}
+
+// partitionPath returns the path of endpoint within partition.
+func partitionPath(partition, endpoint string) (string, error) {
+	if partition == "" {
+		return "", missingArg("partition")
+	}
+	if strings.HasPrefix(partition, "_") {
+		return "", errors.Statusf(kivik.StatusBadRequest, "kivik: invalid partition '%s'", partition)
+	}
+	path := "_partition/" + chttp.EncodeDocID(partition)
+	if endpoint != "" {
+		path += "/" + endpoint
+	}
+	return path, nil
+}
+
+// PartitionStats returns the document count and sizes of partition. It
+// requires a partitioned database (CouchDB 3.0 and later).
+func (d *db) PartitionStats(ctx context.Context, partition string) (*PartitionStats, error) {
+	path, err := partitionPath(partition, "")
+	if err != nil {
+		return nil, err
+	}
+	stats := &PartitionStats{}
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodGet, d.path(path, nil), nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// PartitionAllDocs returns the documents in partition, as AllDocs does for
+// the whole database.
+func (d *db) PartitionAllDocs(ctx context.Context, partition string, opts map[string]interface{}) (driver.Rows, error) {
+	path, err := partitionPath(partition, "_all_docs")
+	if err != nil {
+		return nil, err
+	}
+	return d.rowsQuery(ctx, path, opts)
+}
+
+// PartitionQuery queries a view, limited to the rows emitted by the documents
+// in partition. The design document must not be global, that is, it must not
+// set options.partitioned to false.
+func (d *db) PartitionQuery(ctx context.Context, partition, ddoc, view string, opts map[string]interface{}) (driver.Rows, error) {
+	path, err := partitionPath(partition, fmt.Sprintf("_design/%s/_view/%s", chttp.EncodeDocID(ddoc), chttp.EncodeDocID(view)))
+	if err != nil {
+		return nil, err
+	}
+	return d.rowsQuery(ctx, path, opts)
+}
+
+// PartitionFind performs a Mango query, as Find does, limited to the documents
+// in partition.
+func (d *db) PartitionFind(ctx context.Context, partition string, query interface{}) (driver.Rows, error) {
+	path, err := partitionPath(partition, "_find")
+	if err != nil {
+		return nil, err
+	}
+	return d.find(ctx, path, query)
+}
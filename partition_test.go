@@ -0,0 +1,115 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestPartitionStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		partition string
+		expected  *PartitionStats
+		status    int
+		err       string
+	}{
+		{
+			name:   "no partition",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: partition required",
+		},
+		{
+			name:      "invalid partition",
+			partition: "_design",
+			status:    kivik.StatusBadRequest,
+			err:       "kivik: invalid partition '_design'",
+		},
+		{
+			name:      "success",
+			partition: "sensor-1",
+			expected: func() *PartitionStats {
+				s := &PartitionStats{DBName: "testdb", Partition: "sensor-1", DocCount: 12, DocDelCount: 1}
+				s.Sizes.Active = 1024
+				s.Sizes.External = 800
+				return s
+			}(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/testdb/_partition/sensor-1" {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"db_name":"testdb","sizes":{"active":1024,"external":800},"partition":"sensor-1","doc_count":12,"doc_del_count":1}`),
+				}, nil
+			})
+			stats, err := db.PartitionStats(context.Background(), test.partition)
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, stats); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestPartitionQueries(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  func(*db) (driver.Rows, error)
+		method string
+		path   string
+	}{
+		{
+			name: "all docs",
+			query: func(d *db) (driver.Rows, error) {
+				return d.PartitionAllDocs(context.Background(), "sensor-1", nil)
+			},
+			method: kivik.MethodGet,
+			path:   "/testdb/_partition/sensor-1/_all_docs",
+		},
+		{
+			name: "view",
+			query: func(d *db) (driver.Rows, error) {
+				return d.PartitionQuery(context.Background(), "sensor-1", "foo", "bar", nil)
+			},
+			method: kivik.MethodGet,
+			path:   "/testdb/_partition/sensor-1/_design/foo/_view/bar",
+		},
+		{
+			name: "find",
+			query: func(d *db) (driver.Rows, error) {
+				return d.PartitionFind(context.Background(), "sensor-1", map[string]interface{}{"selector": map[string]interface{}{}})
+			},
+			method: kivik.MethodPost,
+			path:   "/testdb/_partition/sensor-1/_find",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method != test.method {
+					t.Errorf("Unexpected method: %s", req.Method)
+				}
+				if req.URL.Path != test.path {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"rows":[]}`)}, nil
+			})
+			rows, err := test.query(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = rows.Close()
+		})
+	}
+}
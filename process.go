@@ -0,0 +1,135 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+// ChangeProcessor configures at-least-once processing of a database's changes
+// feed, with Process. A change is acknowledged when Handler returns nil, or
+// when DeadLetter accepts it after every attempt has failed. With a
+// CheckpointID, the sequence of each acknowledged change is recorded, so that
+// processing resumes after the last acknowledged change when restarted, and
+// unacknowledged changes are delivered again.
+type ChangeProcessor struct {
+	// Handler processes a single change.
+	Handler TailFunc
+
+	// MaxAttempts is the number of times Handler is called for a change
+	// before it is given up on. It defaults to 3.
+	MaxAttempts int
+
+	// RetryDelay is the delay between attempts. It defaults to one second.
+	RetryDelay time.Duration
+
+	// DeadLetter, if set, is called with each change for which every attempt
+	// failed, along with the last error. If it returns nil, the change is
+	// acknowledged, and processing continues. If DeadLetter is not set, or
+	// returns an error, processing stops.
+	DeadLetter func(change *driver.Change, err error) error
+
+	// CheckpointID, if set, names the _local document in which the sequence
+	// of the last acknowledged change is stored.
+	CheckpointID string
+
+	// Since is the sequence from which to start, when there is no checkpoint.
+	// It defaults to "now".
+	Since string
+}
+
+// checkpoint is the content of a ChangeProcessor's checkpoint document.
+type checkpoint struct {
+	Seq Sequence `json:"seq"`
+}
+
+// Process follows the changes feed of dbName, reconnecting as Tail does,
+// delivering each change to p.Handler with the guarantees described for
+// ChangeProcessor. It returns when ctx is cancelled, or when processing stops
+// due to an error.
+func (c *client) Process(ctx context.Context, dbName string, p *ChangeProcessor) error {
+	if dbName == "" {
+		return missingArg("dbName")
+	}
+	if p.Handler == nil {
+		return missingArg("Handler")
+	}
+	if err := c.checkDB(dbName); err != nil {
+		return err
+	}
+	d := &db{client: c, dbName: dbName}
+	since := p.Since
+	if since == "" {
+		since = "now"
+	}
+	if p.CheckpointID != "" {
+		seq, err := d.loadCheckpoint(ctx, p.CheckpointID)
+		if err != nil {
+			return err
+		}
+		if seq != "" {
+			since = seq
+		}
+	}
+	return d.follow(ctx, since, func(change *driver.Change) error {
+		if err := p.deliver(ctx, c.clock(), change); err != nil {
+			return err
+		}
+		if p.CheckpointID == "" {
+			return nil
+		}
+		return d.PutLocal(ctx, p.CheckpointID, checkpoint{Seq: ParseSequence(string(change.Seq))})
+	})
+}
+
+// loadCheckpoint returns the sequence stored in the checkpoint document id, or
+// an empty string if there is none.
+func (d *db) loadCheckpoint(ctx context.Context, id string) (string, error) {
+	body, err := d.GetLocal(ctx, id)
+	if kivik.StatusCode(err) == kivik.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(body, &cp); err != nil {
+		return "", err
+	}
+	return cp.Seq.String(), nil
+}
+
+// deliver calls the handler for change until it succeeds, or the attempts are
+// exhausted, in which case the change goes to the dead letter callback. The
+// delay between attempts is timed by clock.
+func (p *ChangeProcessor) deliver(ctx context.Context, clock chttp.Clock, change *driver.Change) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 3
+	}
+	delay := p.RetryDelay
+	if delay == 0 {
+		delay = time.Second
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-clock.After(delay):
+			}
+		}
+		if err = p.Handler(change); err == nil {
+			return nil
+		}
+	}
+	if p.DeadLetter == nil {
+		return err
+	}
+	return p.DeadLetter(change, err)
+}
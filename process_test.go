@@ -0,0 +1,150 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestChangeProcessorDeliver(t *testing.T) {
+	failing := errors.New("failed")
+	tests := []struct {
+		name      string
+		failures  int
+		dead      bool
+		deadErr   error
+		attempts  int
+		deadCalls int
+		err       string
+	}{
+		{
+			name:     "success",
+			attempts: 1,
+		},
+		{
+			name:     "retried",
+			failures: 2,
+			attempts: 3,
+		},
+		{
+			name:     "exhausted",
+			failures: 3,
+			attempts: 3,
+			err:      "failed",
+		},
+		{
+			name:      "dead letter",
+			failures:  3,
+			dead:      true,
+			attempts:  3,
+			deadCalls: 1,
+		},
+		{
+			name:      "dead letter error",
+			failures:  3,
+			dead:      true,
+			deadErr:   errors.New("dead letter failed"),
+			attempts:  3,
+			deadCalls: 1,
+			err:       "dead letter failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts, deadCalls int
+			p := &ChangeProcessor{
+				RetryDelay: time.Millisecond,
+				Handler: func(_ *driver.Change) error {
+					attempts++
+					if attempts <= test.failures {
+						return failing
+					}
+					return nil
+				},
+			}
+			if test.dead {
+				p.DeadLetter = func(_ *driver.Change, err error) error {
+					deadCalls++
+					if err != failing {
+						t.Errorf("Unexpected error passed to DeadLetter: %v", err)
+					}
+					return test.deadErr
+				}
+			}
+			err := p.deliver(context.Background(), chttp.SystemClock, &driver.Change{ID: "foo"})
+			if attempts != test.attempts {
+				t.Errorf("Expected %d attempts, got %d", test.attempts, attempts)
+			}
+			if deadCalls != test.deadCalls {
+				t.Errorf("Expected %d dead letter calls, got %d", test.deadCalls, deadCalls)
+			}
+			testy.Error(t, test.err, err)
+		})
+	}
+}
+
+func TestProcessCheckpoint(t *testing.T) {
+	var requests []string
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/db/_local/worker":
+			if req.Method == kivik.MethodGet {
+				requests = append(requests, "GET checkpoint")
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"_id":"_local/worker","_rev":"0-1","seq":"5-xxx"}`),
+				}, nil
+			}
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			requests = append(requests, "PUT "+string(body))
+			return &http.Response{
+				StatusCode: kivik.StatusCreated,
+				Body:       Body(`{"ok":true}`),
+			}, nil
+		case "/db/_changes":
+			requests = append(requests, "changes since "+req.URL.Query().Get("since"))
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Body: Body(`{"seq":"6-xxx","id":"foo","changes":[{"rev":"1-aaa"}]}
+{"seq":"7-xxx","id":"bar","changes":[{"rev":"1-bbb"}]}
+`),
+			}, nil
+		}
+		return nil, errors.New("unexpected request: " + req.URL.Path)
+	})
+	stop := errors.New("stop")
+	err := c.Process(context.Background(), "db", &ChangeProcessor{
+		CheckpointID: "worker",
+		MaxAttempts:  1,
+		Handler: func(change *driver.Change) error {
+			if change.ID == "bar" {
+				return stop
+			}
+			return nil
+		},
+	})
+	if err != stop {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"GET checkpoint",
+		"changes since 5-xxx",
+		"PUT {\"seq\":\"6-xxx\"}\n",
+	}
+	if d := diff.Interface(expected, requests); d != nil {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,156 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// QueryCache configures the caching of view, _all_docs and _find results,
+// enabled with SetQueryCache. Results are cached by endpoint and options, so
+// repeating an identical query within TTL returns the cached result without
+// querying the server. Cached results are held in memory in full.
+//
+// When connected to CouchDB 1.x, the ETag of each cached view result is kept,
+// and once the result has expired, it is revalidated with If-None-Match, so
+// that an unchanged result is not transferred again. CouchDB 2.x does not
+// produce reliable ETags for views, so there expired results are always
+// fetched in full.
+type QueryCache struct {
+	// TTL is how long a result is cached. It defaults to one minute.
+	TTL time.Duration
+
+	// CheckUpdateSeq causes the database's update_seq to be fetched before a
+	// cached result is used, and the result discarded if the database has
+	// changed since it was cached. This costs a request, but is much cheaper
+	// than re-running a large query.
+	CheckUpdateSeq bool
+
+	// MaxEntries limits the number of cached results. When the cache is full,
+	// expired results are discarded, and if it is still full, new results
+	// are not cached. It defaults to 1000.
+	MaxEntries int
+}
+
+// queryCache holds cached query results for a client.
+type queryCache struct {
+	config  QueryCache
+	mu      sync.Mutex
+	entries map[string]*cachedResult
+}
+
+type cachedResult struct {
+	body      []byte
+	updateSeq string
+	etag      string
+	expires   time.Time
+}
+
+func newQueryCache(config QueryCache) *queryCache {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 1000
+	}
+	return &queryCache{
+		config:  config,
+		entries: make(map[string]*cachedResult),
+	}
+}
+
+// get returns the result cached for key, or nil, and whether it is fresh at
+// now. Expired results are returned for revalidation if they have an ETag.
+func (c *queryCache) get(key string, now time.Time) (*cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if now.After(entry.expires) {
+		if entry.etag == "" {
+			delete(c.entries, key)
+			return nil, false
+		}
+		return entry, false
+	}
+	return entry, true
+}
+
+func (c *queryCache) put(key string, entry *cachedResult, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.config.MaxEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.config.MaxEntries {
+			return
+		}
+	}
+	entry.expires = now.Add(c.config.TTL)
+	c.entries[key] = entry
+}
+
+// cachedQuery performs a query, returning the cached result if there is a
+// valid one, and caching the result otherwise. body is the JSON request body,
+// if any.
+func (d *db) cachedQuery(ctx context.Context, method, path string, body []byte) (io.ReadCloser, error) {
+	cache := d.client.queryCache
+	key := method + " " + path + "\n" + string(body)
+	var updateSeq string
+	if cache.config.CheckUpdateSeq {
+		stats, err := d.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		updateSeq = stats.UpdateSeq
+	}
+	entry, fresh := cache.get(key, d.clock().Now())
+	if fresh && entry.updateSeq == updateSeq {
+		return ioutil.NopCloser(bytes.NewReader(entry.body)), nil
+	}
+	opts := &chttp.Options{}
+	if body != nil {
+		opts.SetBody(body)
+	}
+	if entry != nil && entry.etag != "" {
+		opts.IfNoneMatch = `"` + entry.etag + `"`
+	}
+	resp, err := d.Client.DoReq(ctx, method, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil && entry.etag != "" && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		cache.put(key, &cachedResult{body: entry.body, updateSeq: updateSeq, etag: entry.etag}, d.clock().Now())
+		return ioutil.NopCloser(bytes.NewReader(entry.body)), nil
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	result, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WrapStatus(kivik.StatusNetworkError, err)
+	}
+	var etag string
+	if method == kivik.MethodGet && d.Compat == CompatCouch16 {
+		// View ETags are unreliable in CouchDB 2.x, as they may be derived
+		// from a single shard, so they are only trusted for 1.x.
+		etag, _ = chttp.ETag(resp)
+	}
+	cache.put(key, &cachedResult{body: result, updateSeq: updateSeq, etag: etag}, d.clock().Now())
+	return ioutil.NopCloser(bytes.NewReader(result)), nil
+}
@@ -0,0 +1,178 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func countRows(t *testing.T, rows driver.Rows) int {
+	defer rows.Close() // nolint: errcheck
+	var count int
+	for {
+		err := rows.Next(&driver.Row{})
+		if err == io.EOF {
+			return count
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *QueryCache
+		seqs     []string
+		query    func(*db) (driver.Rows, error)
+		queries  int
+		expected int // Expected number of queries sent to the server
+	}{
+		{
+			name:     "disabled",
+			query:    func(d *db) (driver.Rows, error) { return d.Query(context.Background(), "ddoc", "view", nil) },
+			queries:  2,
+			expected: 2,
+		},
+		{
+			name:     "cached",
+			config:   &QueryCache{TTL: time.Minute},
+			query:    func(d *db) (driver.Rows, error) { return d.Query(context.Background(), "ddoc", "view", nil) },
+			queries:  3,
+			expected: 1,
+		},
+		{
+			name:   "different options",
+			config: &QueryCache{},
+			query: func() func(d *db) (driver.Rows, error) {
+				var limit int
+				return func(d *db) (driver.Rows, error) {
+					limit++
+					return d.AllDocs(context.Background(), map[string]interface{}{"limit": limit})
+				}
+			}(),
+			queries:  2,
+			expected: 2,
+		},
+		{
+			name:     "expired",
+			config:   &QueryCache{TTL: time.Nanosecond},
+			query:    func(d *db) (driver.Rows, error) { return d.Query(context.Background(), "ddoc", "view", nil) },
+			queries:  2,
+			expected: 2,
+		},
+		{
+			name:     "update seq",
+			config:   &QueryCache{CheckUpdateSeq: true},
+			seqs:     []string{"1-x", "1-x", "2-x"},
+			query:    func(d *db) (driver.Rows, error) { return d.Query(context.Background(), "ddoc", "view", nil) },
+			queries:  3,
+			expected: 2,
+		},
+		{
+			name:   "find",
+			config: &QueryCache{},
+			query: func(d *db) (driver.Rows, error) {
+				return d.Find(context.Background(), map[string]interface{}{"selector": map[string]string{"a": "b"}})
+			},
+			queries:  2,
+			expected: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent int
+			seqs := test.seqs
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/testdb" {
+					seq := seqs[0]
+					seqs = seqs[1:]
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"db_name":"testdb","update_seq":"` + seq + `"}`)}, nil
+				}
+				sent++
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"rows":[{"id":"a","key":"a","value":1},{"id":"b","key":"b","value":1}]}`),
+				}, nil
+			})
+			if err := SetQueryCache(test.config).auth(context.Background(), c); err != nil {
+				t.Fatal(err)
+			}
+			d := &db{client: c, dbName: "testdb"}
+			for i := 0; i < test.queries; i++ {
+				rows, err := test.query(d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count := countRows(t, rows); count != 2 {
+					t.Errorf("Expected 2 rows, got %d", count)
+				}
+			}
+			if sent != test.expected {
+				t.Errorf("Expected %d queries sent, got %d", test.expected, sent)
+			}
+		})
+	}
+}
+
+func TestQueryCacheETag(t *testing.T) {
+	tests := []struct {
+		name     string
+		compat   CompatMode
+		expected []string
+	}{
+		{
+			name:     "CouchDB 1.x",
+			compat:   CompatCouch16,
+			expected: []string{"", `"abc"`, `"abc"`},
+		},
+		{
+			name:     "CouchDB 2.x",
+			compat:   CompatCouch20,
+			expected: []string{"", "", ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var inm []string
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				inm = append(inm, req.Header.Get("If-None-Match"))
+				if req.Header.Get("If-None-Match") == `"abc"` {
+					return &http.Response{StatusCode: http.StatusNotModified, Request: req, Body: Body("")}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Header:     http.Header{"ETag": {`"abc"`}},
+					Body:       Body(`{"rows":[{"id":"a","key":"a","value":1},{"id":"b","key":"b","value":1}]}`),
+				}, nil
+			})
+			c.Compat = test.compat
+			if err := SetQueryCache(&QueryCache{TTL: time.Nanosecond}).auth(context.Background(), c); err != nil {
+				t.Fatal(err)
+			}
+			d := &db{client: c, dbName: "testdb"}
+			for i := 0; i < 3; i++ {
+				rows, err := d.Query(context.Background(), "ddoc", "view", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count := countRows(t, rows); count != 2 {
+					t.Errorf("Expected 2 rows, got %d", count)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if d := diff.Interface(test.expected, inm); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
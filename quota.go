@@ -0,0 +1,179 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+// Quota limits the size of a database, for enforcing per-tenant limits on the
+// client. Quotas are set with SetQuota. Before each Put, CreateDoc, Copy,
+// BulkDocs or PutAttachment call, the database's stats are compared against
+// the quota, and the write is refused with a *QuotaExceededError if either
+// limit has been reached. Deletions, whether by Delete, or by writing
+// documents with _deleted set to true, are always permitted, so that space
+// can be reclaimed.
+//
+// Stats are cached, so a burst of writes, or writes by other clients, may
+// exceed the quota by the amount written within StatsTTL.
+type Quota struct {
+	// MaxDocs is the maximum number of documents, not counting deleted ones.
+	// Zero means no limit.
+	MaxDocs int64
+
+	// MaxBytes is the maximum size of the live data in the database, as
+	// reported by sizes.active (data_size in CouchDB 1.x), or the file size
+	// if that is unavailable. Zero means no limit.
+	MaxBytes int64
+
+	// StatsTTL is how long fetched stats are reused. It defaults to one
+	// minute.
+	StatsTTL time.Duration
+}
+
+// QuotaExceededError is returned by writes to a database which has reached
+// its Quota.
+type QuotaExceededError struct {
+	DBName string
+	// Limit is "docs" or "bytes".
+	Limit   string
+	Max     int64
+	Current int64
+}
+
+var _ error = &QuotaExceededError{}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("kivik: database %s has reached its quota of %d %s (currently %d)", e.DBName, e.Max, e.Limit, e.Current)
+}
+
+// StatusCode returns kivik.StatusForbidden.
+func (e *QuotaExceededError) StatusCode() int {
+	return kivik.StatusForbidden
+}
+
+// quotaGuard holds the quotas set for a client, and the cached stats against
+// which they are checked. The zero value is ready to use.
+type quotaGuard struct {
+	mu     sync.Mutex
+	quotas map[string]Quota
+	stats  map[string]cachedStats
+}
+
+type cachedStats struct {
+	stats   *driver.DBStats
+	expires time.Time
+}
+
+// set sets or, if q is nil, removes the quota for dbName.
+func (g *quotaGuard) set(dbName string, q *Quota) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.stats, dbName)
+	if q == nil {
+		delete(g.quotas, dbName)
+		return
+	}
+	if g.quotas == nil {
+		g.quotas = make(map[string]Quota)
+	}
+	g.quotas[dbName] = *q
+}
+
+// lookup returns the quota for dbName, and its cached stats, if still fresh.
+func (g *quotaGuard) lookup(dbName string, now time.Time) (quota Quota, stats *driver.DBStats, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	quota, ok = g.quotas[dbName]
+	if cached, found := g.stats[dbName]; found && now.Before(cached.expires) {
+		stats = cached.stats
+	}
+	return quota, stats, ok
+}
+
+func (g *quotaGuard) cache(dbName string, stats *driver.DBStats, expires time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stats == nil {
+		g.stats = make(map[string]cachedStats)
+	}
+	g.stats[dbName] = cachedStats{stats: stats, expires: expires}
+}
+
+// checkQuota returns a *QuotaExceededError if the database has reached its
+// quota, unless docs, the documents to be written, if any, are all deletions.
+func (d *db) checkQuota(ctx context.Context, docs ...interface{}) error {
+	if d.client == nil {
+		return nil
+	}
+	quota, stats, ok := d.quotas.lookup(d.dbName, d.clock().Now())
+	if !ok || allDeletions(docs) {
+		return nil
+	}
+	if stats == nil {
+		var err error
+		if stats, err = d.Stats(ctx); err != nil {
+			return err
+		}
+		ttl := quota.StatsTTL
+		if ttl <= 0 {
+			ttl = time.Minute
+		}
+		d.quotas.cache(d.dbName, stats, d.clock().Now().Add(ttl))
+	}
+	if quota.MaxDocs > 0 && stats.DocCount >= quota.MaxDocs {
+		return &QuotaExceededError{DBName: d.dbName, Limit: "docs", Max: quota.MaxDocs, Current: stats.DocCount}
+	}
+	size := stats.ActiveSize
+	if size == 0 {
+		size = stats.DiskSize
+	}
+	if quota.MaxBytes > 0 && size >= quota.MaxBytes {
+		return &QuotaExceededError{DBName: d.dbName, Limit: "bytes", Max: quota.MaxBytes, Current: size}
+	}
+	return nil
+}
+
+// allDeletions returns true if docs is not empty, and each of them has
+// _deleted set to true.
+func allDeletions(docs []interface{}) bool {
+	for _, doc := range docs {
+		if !isDeletion(doc) {
+			return false
+		}
+	}
+	return len(docs) > 0
+}
+
+// isDeletion returns true if doc has _deleted set to true.
+func isDeletion(doc interface{}) bool {
+	var raw []byte
+	switch t := doc.(type) {
+	case map[string]interface{}:
+		deleted, _ := t["_deleted"].(bool)
+		return deleted
+	case json.RawMessage:
+		raw = t
+	case []byte:
+		raw = t
+	default:
+		if _, hasContent := extractAttachments(doc); hasContent {
+			// Encoding doc would consume the attachments' content, and
+			// deletions carry none.
+			return false
+		}
+		var err error
+		if raw, err = json.Marshal(doc); err != nil {
+			return false
+		}
+	}
+	var tomb struct {
+		Deleted bool `json:"_deleted"`
+	}
+	return json.Unmarshal(raw, &tomb) == nil && tomb.Deleted
+}
@@ -0,0 +1,153 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestCheckQuota(t *testing.T) {
+	tests := []struct {
+		name   string
+		quota  *Quota
+		stats  string
+		status int
+		err    string
+	}{
+		{
+			name:  "no quota",
+			stats: `{"db_name":"testdb","doc_count":100}`,
+		},
+		{
+			name:  "under quota",
+			quota: &Quota{MaxDocs: 101, MaxBytes: 5000},
+			stats: `{"db_name":"testdb","doc_count":100,"sizes":{"active":4999,"file":9000}}`,
+		},
+		{
+			name:   "doc count",
+			quota:  &Quota{MaxDocs: 100},
+			stats:  `{"db_name":"testdb","doc_count":100}`,
+			status: kivik.StatusForbidden,
+			err:    "kivik: database testdb has reached its quota of 100 docs (currently 100)",
+		},
+		{
+			name:   "data size",
+			quota:  &Quota{MaxBytes: 5000},
+			stats:  `{"db_name":"testdb","doc_count":100,"data_size":6000}`,
+			status: kivik.StatusForbidden,
+			err:    "kivik: database testdb has reached its quota of 5000 bytes (currently 6000)",
+		},
+		{
+			name:   "file size fallback",
+			quota:  &Quota{MaxBytes: 5000},
+			stats:  `{"db_name":"testdb","doc_count":100,"disk_size":5000}`,
+			status: kivik.StatusForbidden,
+			err:    "kivik: database testdb has reached its quota of 5000 bytes (currently 5000)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var statsRequests, puts int
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					statsRequests++
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.stats)}, nil
+				}
+				puts++
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`{"ok":true,"id":"foo","rev":"1-xxx"}`),
+				}, nil
+			})
+			if err := SetQuota("testdb", test.quota).auth(context.Background(), db.client); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			for i := 0; i < 2; i++ {
+				if _, err = db.Put(context.Background(), "foo", map[string]string{}, nil); err != nil {
+					break
+				}
+			}
+			if test.quota != nil && statsRequests != 1 {
+				t.Errorf("Expected stats to be fetched once, got %d", statsRequests)
+			}
+			if test.err != "" {
+				if _, ok := err.(*QuotaExceededError); !ok {
+					t.Errorf("Expected *QuotaExceededError, got %T", err)
+				}
+				if puts != 0 {
+					t.Errorf("Expected no writes, got %d", puts)
+				}
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestQuotaDeletions(t *testing.T) {
+	type tombstone struct {
+		ID      string `json:"_id"`
+		Rev     string `json:"_rev"`
+		Deleted bool   `json:"_deleted"`
+	}
+	tests := []struct {
+		name   string
+		write  func(*db) error
+		status int
+		err    string
+	}{
+		{
+			name: "bulk tombstones",
+			write: func(d *db) error {
+				_, err := d.BulkDocs(context.Background(), []interface{}{
+					map[string]interface{}{"_id": "foo", "_rev": "1-xxx", "_deleted": true},
+					tombstone{ID: "bar", Rev: "1-yyy", Deleted: true},
+				}, nil)
+				return err
+			},
+		},
+		{
+			name: "bulk tombstone and new doc",
+			write: func(d *db) error {
+				_, err := d.BulkDocs(context.Background(), []interface{}{
+					map[string]interface{}{"_id": "foo", "_rev": "1-xxx", "_deleted": true},
+					map[string]interface{}{"_id": "baz"},
+				}, nil)
+				return err
+			},
+			status: kivik.StatusForbidden,
+			err:    "kivik: database testdb has reached its quota of 100 docs (currently 100)",
+		},
+		{
+			name: "put tombstone",
+			write: func(d *db) error {
+				_, err := d.Put(context.Background(), "foo", tombstone{ID: "foo", Rev: "1-xxx", Deleted: true}, nil)
+				return err
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				switch req.Method {
+				case kivik.MethodGet:
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"db_name":"testdb","doc_count":100}`)}, nil
+				case kivik.MethodPost:
+					return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`[]`)}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`{"ok":true,"id":"foo","rev":"2-xxx"}`),
+				}, nil
+			})
+			if err := SetQuota("testdb", &Quota{MaxDocs: 100}).auth(context.Background(), db.client); err != nil {
+				t.Fatal(err)
+			}
+			testy.StatusError(t, test.err, test.status, test.write(db))
+		})
+	}
+}
@@ -207,10 +207,10 @@ func (r *replication) setFromReplicatorDoc(doc *replicatorDoc) {
 		r.err = nil
 	}
 	if r.source == "" {
-		r.source = doc.Source
+		r.source = string(doc.Source)
 	}
 	if r.target == "" {
-		r.target = doc.Target
+		r.target = string(doc.Target)
 	}
 	if r.replicationID == "" {
 		r.replicationID = doc.ReplicationID
@@ -229,8 +229,8 @@ func (r *replication) Delete(ctx context.Context) error {
 type replicatorDoc struct {
 	DocID         string               `json:"_id"`
 	ReplicationID string               `json:"_replication_id"`
-	Source        string               `json:"source"`
-	Target        string               `json:"target"`
+	Source        endpointURL          `json:"source"`
+	Target        endpointURL          `json:"target"`
 	State         string               `json:"_replication_state"`
 	StateTime     replicationStateTime `json:"_replication_state_time"`
 	Error         *replicationError    `json:"_replication_state_reason,omitempty"`
@@ -296,6 +296,11 @@ func (c *client) Replicate(ctx context.Context, targetDSN, sourceDSN string, opt
 	if s, _ := options["source"]; s == "" {
 		return nil, missingArg("sourceDSN")
 	}
+	for _, endpoint := range []string{"source", "target"} {
+		if err := c.checkEndpoint(options[endpoint]); err != nil {
+			return nil, err
+		}
+	}
 
 	scheduler, err := c.schedulerSupported(ctx)
 	if err != nil {
@@ -305,6 +310,12 @@ func (c *client) Replicate(ctx context.Context, targetDSN, sourceDSN string, opt
 		Body: chttp.EncodeBody(options),
 	}
 
+	return c.postReplication(ctx, scheduler, opts)
+}
+
+// postReplication creates a _replicator document, and returns a handle to the
+// resulting replication.
+func (c *client) postReplication(ctx context.Context, scheduler bool, opts *chttp.Options) (driver.Replication, error) {
 	var repStub struct {
 		ID string `json:"id"`
 	}
@@ -0,0 +1,151 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// ReplicationEndpoint is the source or target of a replication.
+type ReplicationEndpoint struct {
+	// URL is the URL of the database, which should not include credentials
+	// if Headers are used for authentication.
+	URL string `json:"url"`
+	// Headers are sent with each request the replicator makes to the
+	// database, typically to authenticate.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// SetBasicAuth sets the Authorization header for the endpoint, so that
+// credentials need not be embedded in the URL.
+func (e *ReplicationEndpoint) SetBasicAuth(username, password string) {
+	if e.Headers == nil {
+		e.Headers = make(map[string]string)
+	}
+	e.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// MarshalJSON encodes the endpoint as a plain URL when there are no headers,
+// which all CouchDB versions understand.
+func (e ReplicationEndpoint) MarshalJSON() ([]byte, error) {
+	if len(e.Headers) == 0 {
+		return json.Marshal(e.URL)
+	}
+	type endpoint ReplicationEndpoint
+	return json.Marshal(endpoint(e))
+}
+
+// endpointURL is the URL of a replication endpoint, as stored in a
+// _replicator document, either as a string or an object.
+type endpointURL string
+
+func (u *endpointURL) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var endpoint ReplicationEndpoint
+		if err := json.Unmarshal(data, &endpoint); err != nil {
+			return err
+		}
+		*u = endpointURL(endpoint.URL)
+		return nil
+	}
+	var url string
+	if err := json.Unmarshal(data, &url); err != nil {
+		return err
+	}
+	*u = endpointURL(url)
+	return nil
+}
+
+// ReplicationDoc is a _replicator document.
+type ReplicationDoc struct {
+	// ID is the document ID. If empty, the server assigns one.
+	ID           string              `json:"_id,omitempty"`
+	Source       ReplicationEndpoint `json:"source"`
+	Target       ReplicationEndpoint `json:"target"`
+	CreateTarget bool                `json:"create_target,omitempty"`
+	Continuous   bool                `json:"continuous,omitempty"`
+	// Selector is a Mango selector used to filter the replicated documents.
+	// It requires CouchDB 2.0 or later, and is checked with ValidateSelector
+	// before the document is created. Use json.RawMessage for raw JSON.
+	Selector interface{} `json:"selector,omitempty"`
+	DocIDs   []string    `json:"doc_ids,omitempty"`
+	// SinceSeq is the source sequence from which to start replicating.
+	SinceSeq string `json:"since_seq,omitempty"`
+	// CheckpointInterval is the interval, in milliseconds, between
+	// checkpoints.
+	CheckpointInterval int `json:"checkpoint_interval,omitempty"`
+}
+
+// CreateReplication writes doc to the _replicator database, and returns a
+// handle with which to follow the status of the replication.
+func (c *client) CreateReplication(ctx context.Context, doc *ReplicationDoc) (driver.Replication, error) {
+	if doc.Source.URL == "" {
+		return nil, missingArg("Source.URL")
+	}
+	if doc.Target.URL == "" {
+		return nil, missingArg("Target.URL")
+	}
+	for _, dsn := range []string{doc.Source.URL, doc.Target.URL} {
+		if err := c.checkEndpoint(dsn); err != nil {
+			return nil, err
+		}
+	}
+	if len(doc.DocIDs) > 0 && doc.Selector != nil {
+		return nil, errors.Status(kivik.StatusBadRequest, "kivik: DocIDs and Selector are mutually exclusive")
+	}
+	if doc.Selector != nil {
+		if err := ValidateSelector(doc.Selector); err != nil {
+			return nil, err
+		}
+	}
+	scheduler, err := c.schedulerSupported(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.postReplication(ctx, scheduler, &chttp.Options{
+		Body: chttp.EncodeBody(doc),
+	})
+}
+
+// CancelReplication stops the replication described by the _replicator
+// document docID. CouchDB 1.x is asked to cancel the running replication with
+// a POST to /_replicate, falling back to deleting the document if the
+// replication is not running. For all later versions, the document is deleted.
+func (c *client) CancelReplication(ctx context.Context, docID string) error {
+	if docID == "" {
+		return missingArg("docID")
+	}
+	if c.Compat == CompatCouch16 {
+		err := c.legacyCancelReplication(ctx, docID)
+		if kivik.StatusCode(err) != kivik.StatusNotFound {
+			return err
+		}
+	}
+	return c.newReplication(docID).Delete(ctx)
+}
+
+// legacyCancelReplication cancels a running replication on CouchDB 1.x. A 404
+// error is returned if the replication is not running.
+func (c *client) legacyCancelReplication(ctx context.Context, docID string) error {
+	rep := c.newReplication(docID)
+	doc, err := rep.getReplicatorDoc(ctx)
+	if err != nil {
+		return err
+	}
+	if doc.ReplicationID == "" {
+		return errors.Status(kivik.StatusNotFound, "kivik: replication not running")
+	}
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(map[string]interface{}{
+			"replication_id": doc.ReplicationID,
+			"cancel":         true,
+		}),
+	}
+	_, err = c.DoError(ctx, kivik.MethodPost, "/_replicate", opts)
+	return err
+}
@@ -0,0 +1,207 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestReplicationDocJSON(t *testing.T) {
+	target := ReplicationEndpoint{URL: "http://localhost:5984/bar"}
+	target.SetBasicAuth("admin", "abc123")
+	doc := &ReplicationDoc{
+		ID:                 "foo-bar",
+		Source:             ReplicationEndpoint{URL: "http://example.com/foo"},
+		Target:             target,
+		CreateTarget:       true,
+		Continuous:         true,
+		Selector:           map[string]interface{}{"type": "user"},
+		SinceSeq:           "0",
+		CheckpointInterval: 5000,
+	}
+	expected := `{
+		"_id": "foo-bar",
+		"source": "http://example.com/foo",
+		"target": {"url": "http://localhost:5984/bar", "headers": {"Authorization": "Basic YWRtaW46YWJjMTIz"}},
+		"create_target": true,
+		"continuous": true,
+		"selector": {"type": "user"},
+		"since_seq": "0",
+		"checkpoint_interval": 5000
+	}`
+	result, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff.JSON([]byte(expected), result); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestEndpointURLUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected endpointURL
+		err      string
+	}{
+		{name: "string", input: `"http://foo.com/db"`, expected: "http://foo.com/db"},
+		{name: "object", input: `{"url":"http://foo.com/db","headers":{"Authorization":"xxx"}}`, expected: "http://foo.com/db"},
+		{name: "invalid", input: `123`, err: "json: cannot unmarshal number into Go value of type string"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result endpointURL
+			err := json.Unmarshal([]byte(test.input), &result)
+			testy.Error(t, test.err, err)
+			if result != test.expected {
+				t.Errorf("Unexpected result: %s", result)
+			}
+		})
+	}
+}
+
+func TestCreateReplication(t *testing.T) {
+	legacy := func(fn func(*http.Request) (*http.Response, error)) *client {
+		c := newCustomClient(fn)
+		b := false
+		c.schedulerDetected = &b
+		return c
+	}
+	tests := []struct {
+		name   string
+		client *client
+		doc    *ReplicationDoc
+		status int
+		err    string
+	}{
+		{
+			name:   "no source",
+			doc:    &ReplicationDoc{},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: Source.URL required",
+		},
+		{
+			name:   "no target",
+			doc:    &ReplicationDoc{Source: ReplicationEndpoint{URL: "foo"}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: Target.URL required",
+		},
+		{
+			name: "doc_ids and selector",
+			doc: &ReplicationDoc{
+				Source:   ReplicationEndpoint{URL: "foo"},
+				Target:   ReplicationEndpoint{URL: "bar"},
+				DocIDs:   []string{"a"},
+				Selector: map[string]interface{}{},
+			},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: DocIDs and Selector are mutually exclusive",
+		},
+		{
+			name: "success",
+			doc: &ReplicationDoc{
+				Source:     ReplicationEndpoint{URL: "foo"},
+				Target:     ReplicationEndpoint{URL: "bar"},
+				Continuous: true,
+			},
+			client: legacy(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodPost {
+					body, err := ioutil.ReadAll(req.Body)
+					if err != nil {
+						return nil, err
+					}
+					if d := diff.JSON([]byte(`{"source":"foo","target":"bar","continuous":true}`), body); d != nil {
+						return nil, errors.Errorf("Unexpected body:\n%s", d)
+					}
+					return &http.Response{
+						StatusCode: kivik.StatusCreated,
+						Body:       Body(`{"ok":true,"id":"abc","rev":"1-xxx"}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Header:     http.Header{"Content-Type": {"application/json"}, "ETag": {`"1-xxx"`}},
+					Body:       Body(`{"_id":"abc","_rev":"1-xxx","source":"foo","target":"bar"}`),
+					Request:    req,
+				}, nil
+			}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rep, err := test.client.CreateReplication(context.Background(), test.doc)
+			testy.StatusError(t, test.err, test.status, err)
+			if rep.Source() != "foo" || rep.Target() != "bar" {
+				t.Errorf("Unexpected replication: %s -> %s", rep.Source(), rep.Target())
+			}
+		})
+	}
+}
+
+func TestCancelReplication(t *testing.T) {
+	tests := []struct {
+		name     string
+		compat   CompatMode
+		doc      string
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:     "2.x",
+			compat:   CompatCouch20,
+			doc:      `{"_id":"foo","_rev":"1-xxx"}`,
+			expected: []string{"HEAD /_replicator/foo", "DELETE /_replicator/foo"},
+		},
+		{
+			name:     "1.x running",
+			compat:   CompatCouch16,
+			doc:      `{"_id":"foo","_rev":"1-xxx","_replication_id":"abc"}`,
+			expected: []string{"GET /_replicator/foo", "POST /_replicate"},
+		},
+		{
+			name:     "1.x not running",
+			compat:   CompatCouch16,
+			doc:      `{"_id":"foo","_rev":"1-xxx"}`,
+			expected: []string{"GET /_replicator/foo", "HEAD /_replicator/foo", "DELETE /_replicator/foo"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				requests = append(requests, req.Method+" "+req.URL.Path)
+				if req.Method == kivik.MethodPost {
+					body, err := ioutil.ReadAll(req.Body)
+					if err != nil {
+						return nil, err
+					}
+					if d := diff.JSON([]byte(`{"replication_id":"abc","cancel":true}`), body); d != nil {
+						return nil, errors.Errorf("Unexpected body:\n%s", d)
+					}
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Header:     http.Header{"Content-Type": {"application/json"}, "ETag": {`"1-xxx"`}},
+					Request:    req,
+					Body:       Body(test.doc),
+				}, nil
+			})
+			c.Compat = test.compat
+			err := c.CancelReplication(context.Background(), "foo")
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, requests); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package couchdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// replicationPollInterval is the interval at which WaitForReplication polls
+// the server.
+var replicationPollInterval = time.Second
+
+// replicationStatus is implemented by both scheduler and legacy replications.
+type replicationStatus interface {
+	State() string
+	Err() error
+}
+
+// WaitForReplication polls the status of the replication described by the
+// _replicator document docID until it completes or fails, calling fn, if not
+// nil, each time the state changes. An error is returned if the replication
+// fails, or ctx is cancelled first. Continuous replications never complete.
+func (c *client) WaitForReplication(ctx context.Context, docID string, fn func(state string)) error {
+	if docID == "" {
+		return missingArg("docID")
+	}
+	scheduler, err := c.schedulerSupported(ctx)
+	if err != nil {
+		return err
+	}
+	var lastState string
+	for {
+		rep, err := c.replicationStatus(ctx, docID, scheduler)
+		switch {
+		case kivik.StatusCode(err) == kivik.StatusNotFound:
+			// The replicator may not have picked up the document yet
+		case err != nil:
+			return err
+		default:
+			state := rep.State()
+			if state != lastState && fn != nil {
+				fn(state)
+			}
+			lastState = state
+			if kivik.ReplicationState(state) == kivik.ReplicationComplete {
+				return nil
+			}
+			// The scheduler retries replications in the error state, and only
+			// gives up on those which have failed.
+			if state == "failed" || (!scheduler && kivik.ReplicationState(state) == kivik.ReplicationError) {
+				if err := rep.Err(); err != nil {
+					return err
+				}
+				return errors.Statusf(kivik.StatusUnknownError, "kivik: replication %s %s", docID, state)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.clock().After(replicationPollInterval):
+		}
+	}
+}
+
+// replicationStatus fetches the current status of a replication, from the
+// scheduler if it is supported, or otherwise from the _replication_state
+// fields of the replicator document.
+func (c *client) replicationStatus(ctx context.Context, docID string, scheduler bool) (replicationStatus, error) {
+	if scheduler {
+		rep := &schedulerReplication{
+			docID:    docID,
+			database: "_replicator",
+			db:       &db{client: c, dbName: "_replicator"},
+		}
+		return rep, rep.update(ctx)
+	}
+	rep := c.newReplication(docID)
+	return rep, rep.updateMain(ctx)
+}
@@ -0,0 +1,131 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestWaitForReplication(t *testing.T) {
+	defer func(interval time.Duration) { replicationPollInterval = interval }(replicationPollInterval)
+	replicationPollInterval = time.Millisecond
+
+	// sequence returns a client which responds with each of responses in turn,
+	// using the scheduler if scheduler is true.
+	sequence := func(scheduler bool, responses ...string) *client {
+		var i int
+		c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+			body := responses[i]
+			if i < len(responses)-1 {
+				i++
+			}
+			if body == "" {
+				return &http.Response{
+					StatusCode: kivik.StatusNotFound,
+					Request:    req,
+					Body:       Body(""),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/json"}, "ETag": {`"1-xxx"`}},
+				Request:    req,
+				Body:       Body(body),
+			}, nil
+		})
+		c.schedulerDetected = &scheduler
+		return c
+	}
+	tests := []struct {
+		name     string
+		client   *client
+		docID    string
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no doc ID",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: docID required",
+		},
+		{
+			name:  "scheduler completed",
+			docID: "foo",
+			client: sequence(true,
+				"",
+				`{"doc_id":"foo","state":"running","info":null}`,
+				`{"doc_id":"foo","state":"running","info":null}`,
+				`{"doc_id":"foo","state":"completed","info":{"docs_read":1}}`,
+			),
+			expected: []string{"running", "completed"},
+		},
+		{
+			name:  "scheduler failed",
+			docID: "foo",
+			client: sequence(true,
+				`{"doc_id":"foo","state":"error","info":"db_not_found: could not open foo"}`,
+				`{"doc_id":"foo","state":"failed","info":"db_not_found: could not open foo"}`,
+			),
+			expected: []string{"error", "failed"},
+			status:   kivik.StatusNotFound,
+			err:      "db_not_found: could not open foo",
+		},
+		{
+			name:  "legacy completed",
+			docID: "foo",
+			client: sequence(false,
+				`{"_id":"foo","source":"a","target":"b"}`,
+				`{"_id":"foo","source":"a","target":"b","_replication_state":"triggered","_replication_state_time":"2017-01-01T01:01:01Z"}`,
+				`{"_id":"foo","source":"a","target":"b","_replication_state":"completed","_replication_state_time":"2017-01-01T01:01:02Z"}`,
+			),
+			expected: []string{"triggered", "completed"},
+		},
+		{
+			name:  "legacy error",
+			docID: "foo",
+			client: sequence(false,
+				`{"_id":"foo","source":"a","target":"b","_replication_state":"error","_replication_state_reason":"unauthorized: nope"}`,
+			),
+			expected: []string{"error"},
+			status:   kivik.StatusUnauthorized,
+			err:      "unauthorized: nope",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var states []string
+			err := test.client.WaitForReplication(context.Background(), test.docID, func(state string) {
+				states = append(states, state)
+			})
+			if d := diff.Interface(test.expected, states); d != nil {
+				t.Error(d)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestWaitForReplicationCancel(t *testing.T) {
+	b := true
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Request:    req,
+			Body:       Body(`{"doc_id":"foo","state":"running","info":null}`),
+		}, nil
+	})
+	c.schedulerDetected = &b
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.WaitForReplication(ctx, "foo", nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+)
+
+// DoRequest makes an arbitrary request to the server, for endpoints which the
+// driver does not otherwise support. path is relative to the server root, and
+// may include a query string, to which options are added, encoded as for other
+// queries. The OptionFullCommit, OptionIfNoneMatch and OptionRequestTimeout
+// options are honored. body may be nil, an io.Reader, a []byte or
+// json.RawMessage of raw JSON, or any other value, which is encoded as JSON.
+//
+// The client's authentication applies to the request. For error responses,
+// the error is returned along with the response, whose body has then been
+// consumed. Otherwise the caller must close the response body.
+func (c *client) DoRequest(ctx context.Context, method, path string, options map[string]interface{}, body interface{}) (*http.Response, error) {
+	c.warnPath(method, path)
+	fullCommit, err := fullCommit(false, options)
+	if err != nil {
+		return nil, err
+	}
+	inm, err := ifNoneMatch(options)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel, err := withTimeout(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	params, err := optionsToParams(options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if len(params) > 0 {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path += separator + params.Encode()
+	}
+	opts := &chttp.Options{
+		FullCommit:  fullCommit,
+		IfNoneMatch: inm,
+	}
+	switch b := body.(type) {
+	case nil:
+	case io.ReadCloser:
+		opts.Body = b
+	case io.Reader:
+		opts.Body = ioutil.NopCloser(b)
+	case []byte:
+		opts.SetBody(b)
+	case json.RawMessage:
+		opts.SetBody(b)
+	default:
+		opts.Body = chttp.EncodeBody(b)
+	}
+	resp, err := c.DoReq(ctx, method, path, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
@@ -0,0 +1,93 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestDoRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		options  map[string]interface{}
+		body     interface{}
+		reqURL   string
+		reqBody  string
+		response *http.Response
+		status   int
+		err      string
+	}{
+		{
+			name:    "invalid option",
+			options: map[string]interface{}{OptionIfNoneMatch: 1},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: option 'If-None-Match' must be string, not int",
+		},
+		{
+			name:     "get with options",
+			path:     "/_node/_local/_stats?flush=true",
+			options:  map[string]interface{}{"limit": 10},
+			reqURL:   "/_node/_local/_stats?flush=true&limit=10",
+			response: &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{}`)},
+		},
+		{
+			name:     "raw body",
+			path:     "/db/_purge",
+			body:     json.RawMessage(`{"foo":["1-x"]}`),
+			reqURL:   "/db/_purge",
+			reqBody:  `{"foo":["1-x"]}`,
+			response: &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{}`)},
+		},
+		{
+			name:     "encoded body",
+			path:     "db/_purge",
+			body:     map[string][]string{"foo": {"1-x"}},
+			reqURL:   "/db/_purge",
+			reqBody:  `{"foo":["1-x"]}`,
+			response: &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{}`)},
+		},
+		{
+			name:     "error response",
+			path:     "/missing",
+			reqURL:   "/missing",
+			response: &http.Response{StatusCode: kivik.StatusNotFound, Body: Body("")},
+			status:   kivik.StatusNotFound,
+			err:      "Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if url := req.URL.RequestURI(); url != test.reqURL {
+					t.Errorf("Unexpected URL: %s", url)
+				}
+				if req.Body != nil {
+					body, err := ioutil.ReadAll(req.Body)
+					if err != nil {
+						return nil, err
+					}
+					if strings.TrimSpace(string(body)) != test.reqBody {
+						t.Errorf("Unexpected body: %s", body)
+					}
+				}
+				resp := test.response
+				resp.Request = req
+				return resp, nil
+			})
+			resp, err := c.DoRequest(context.Background(), kivik.MethodPost, test.path, test.options, test.body)
+			if test.response != nil && resp == nil {
+				t.Error("Expected a response")
+			}
+			testy.StatusError(t, test.err, test.status, err)
+			_ = resp.Body.Close()
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package couchdb
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// pruneBatchSize is the maximum number of revisions purged per request, below
+// CouchDB's default purge.max_revisions_number of 1000.
+var pruneBatchSize = 100
+
+// leafRev is a leaf revision of a document, as returned by open_revs=all.
+type leafRev struct {
+	Rev     string `json:"_rev"`
+	Deleted bool   `json:"_deleted"`
+}
+
+// PruneRevs purges the abandoned conflict revisions of docID, such as after a
+// runaway editor has created thousands of conflicts. The winning revision is
+// kept, along with the keep most recent (by generation) of the other leaf
+// revisions, preferring live leaves over deleted ones. It returns the purged
+// revisions, in order.
+//
+// Purging a leaf also removes those of its ancestors which are not shared
+// with a remaining leaf. The history of the remaining leaves is bounded by the
+// database's _revs_limit, and unreferenced revision bodies are removed by
+// compaction. Purging is not replicated, so it should be repeated on each
+// replica. Purge requires CouchDB 2.3 or later.
+func (d *db) PruneRevs(ctx context.Context, docID string, keep int) ([]string, error) {
+	if docID == "" {
+		return nil, missingArg("docID")
+	}
+	query := url.Values{"open_revs": []string{"all"}}
+	var leaves []struct {
+		OK *leafRev `json:"ok"`
+	}
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodGet, d.path(chttp.EncodeDocID(docID), query), &chttp.Options{Accept: "application/json"}, &leaves); err != nil {
+		return nil, err
+	}
+	revs := make([]leafRev, 0, len(leaves))
+	for _, leaf := range leaves {
+		if leaf.OK != nil {
+			revs = append(revs, *leaf.OK)
+		}
+	}
+	if len(revs) == 0 {
+		return nil, errors.Status(kivik.StatusNotFound, "kivik: document not found")
+	}
+	// Order the leaves as CouchDB chooses the winner: live before deleted,
+	// then by generation, then by revision hash. The remaining leaves are
+	// then in order of preference for keeping.
+	sort.Slice(revs, func(i, j int) bool {
+		if revs[i].Deleted != revs[j].Deleted {
+			return !revs[i].Deleted
+		}
+		return revLess(revs[j].Rev, revs[i].Rev)
+	})
+	conflicts := revs[1:]
+	if keep < 0 {
+		keep = 0
+	}
+	if keep >= len(conflicts) {
+		return nil, nil
+	}
+	prune := make([]string, 0, len(conflicts)-keep)
+	for _, leaf := range conflicts[keep:] {
+		prune = append(prune, leaf.Rev)
+	}
+	sort.Slice(prune, func(i, j int) bool { return revLess(prune[i], prune[j]) })
+	var purged []string
+	for len(prune) > 0 {
+		batch := prune
+		if len(batch) > pruneBatchSize {
+			batch = batch[:pruneBatchSize]
+		}
+		prune = prune[len(batch):]
+		var result struct {
+			Purged map[string][]string `json:"purged"`
+		}
+		body := map[string][]string{docID: batch}
+		if _, err := d.Client.DoJSON(ctx, kivik.MethodPost, d.path("_purge", nil), &chttp.Options{Body: chttp.EncodeBody(body)}, &result); err != nil {
+			return purged, err
+		}
+		purged = append(purged, result.Purged[docID]...)
+	}
+	sort.Slice(purged, func(i, j int) bool { return revLess(purged[i], purged[j]) })
+	return purged, nil
+}
+
+// revLess orders revisions by generation, then by hash.
+func revLess(a, b string) bool {
+	if genA, genB := revGeneration(a), revGeneration(b); genA != genB {
+		return genA < genB
+	}
+	return revHash(a) < revHash(b)
+}
+
+// revHash returns the hash portion of rev.
+func revHash(rev string) string {
+	if i := strings.Index(rev, "-"); i >= 0 {
+		return rev[i+1:]
+	}
+	return rev
+}
@@ -0,0 +1,113 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestPruneRevs(t *testing.T) {
+	defer func(size int) { pruneBatchSize = size }(pruneBatchSize)
+	pruneBatchSize = 2
+	tests := []struct {
+		name     string
+		docID    string
+		leaves   string
+		keep     int
+		purges   []string
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no doc id",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: docID required",
+		},
+		{
+			name:   "not found",
+			docID:  "foo",
+			leaves: `[{"missing":"1-xxx"}]`,
+			status: kivik.StatusNotFound,
+			err:    "kivik: document not found",
+		},
+		{
+			name:   "no conflicts",
+			docID:  "foo",
+			leaves: `[{"ok":{"_id":"foo","_rev":"3-aaa"}}]`,
+		},
+		{
+			name:  "keep most recent",
+			docID: "foo",
+			leaves: `[
+{"ok":{"_id":"foo","_rev":"2-bbb"}},
+{"ok":{"_id":"foo","_rev":"9-zzz","_deleted":true}},
+{"ok":{"_id":"foo","_rev":"3-aaa"}},
+{"ok":{"_id":"foo","_rev":"3-ccc"}},
+{"ok":{"_id":"foo","_rev":"10-aaa"}},
+{"ok":{"_id":"foo","_rev":"4-ddd"}}
+]`,
+			keep:     1,
+			purges:   []string{`{"foo":["2-bbb","3-aaa"]}`, `{"foo":["3-ccc","9-zzz"]}`},
+			expected: []string{"2-bbb", "3-aaa", "3-ccc", "9-zzz"},
+		},
+		{
+			name:  "deleted winner",
+			docID: "foo",
+			leaves: `[
+{"ok":{"_id":"foo","_rev":"5-aaa","_deleted":true}},
+{"ok":{"_id":"foo","_rev":"5-bbb","_deleted":true}}
+]`,
+			purges:   []string{`{"foo":["5-aaa"]}`},
+			expected: []string{"5-aaa"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var purges []string
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					if openRevs := req.URL.Query().Get("open_revs"); openRevs != "all" {
+						t.Errorf("Unexpected open_revs: %s", openRevs)
+					}
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(test.leaves)}, nil
+				}
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				purges = append(purges, string(body))
+				var revs map[string][]string
+				_ = json.Unmarshal(body, &revs)
+				result, _ := json.Marshal(map[string]interface{}{"purge_seq": nil, "purged": revs})
+				return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(string(result))}, nil
+			})
+			purged, err := db.PruneRevs(context.Background(), test.docID, test.keep)
+			if d := diff.JSON(toJSONArray(test.purges), toJSONArray(purges)); d != nil {
+				t.Error(d)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, purged); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func toJSONArray(docs []string) []byte {
+	buf := []byte("[")
+	for i, doc := range docs {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, doc...)
+	}
+	return append(buf, ']')
+}
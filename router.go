@@ -0,0 +1,95 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-kivik/kivik/driver"
+)
+
+// ChangeRouter dispatches changes to handlers registered by document ID
+// prefix, document ID pattern, or document type. Its Route method may be
+// passed to Tail.
+type ChangeRouter struct {
+	// TypeField is the document field holding the type matched by
+	// HandleType. It defaults to "type".
+	TypeField string
+
+	mu     sync.RWMutex
+	routes []changeRoute
+}
+
+type changeRoute struct {
+	match func(change *driver.Change, docType string) bool
+	fn    TailFunc
+}
+
+// HandlePrefix registers fn to receive changes to documents whose IDs begin
+// with prefix.
+func (r *ChangeRouter) HandlePrefix(prefix string, fn TailFunc) {
+	r.handle(func(change *driver.Change, _ string) bool {
+		return strings.HasPrefix(change.ID, prefix)
+	}, fn)
+}
+
+// HandleRegexp registers fn to receive changes to documents whose IDs match
+// re.
+func (r *ChangeRouter) HandleRegexp(re *regexp.Regexp, fn TailFunc) {
+	r.handle(func(change *driver.Change, _ string) bool {
+		return re.MatchString(change.ID)
+	}, fn)
+}
+
+// HandleType registers fn to receive changes to documents whose type field
+// equals docType. This requires documents to be included in the feed, as Tail
+// does.
+func (r *ChangeRouter) HandleType(docType string, fn TailFunc) {
+	r.handle(func(_ *driver.Change, t string) bool {
+		return t == docType
+	}, fn)
+}
+
+func (r *ChangeRouter) handle(match func(*driver.Change, string) bool, fn TailFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, changeRoute{match: match, fn: fn})
+}
+
+// Route calls, in order of registration, each handler which matches change,
+// stopping at the first error.
+func (r *ChangeRouter) Route(change *driver.Change) error {
+	docType := r.docType(change)
+	r.mu.RLock()
+	routes := r.routes
+	r.mu.RUnlock()
+	for _, route := range routes {
+		if !route.match(change, docType) {
+			continue
+		}
+		if err := route.fn(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docType returns the value of the type field of the changed document, or an
+// empty string if it is absent, or not a string.
+func (r *ChangeRouter) docType(change *driver.Change) string {
+	if len(change.Doc) == 0 {
+		return ""
+	}
+	field := r.TypeField
+	if field == "" {
+		field = "type"
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(change.Doc, &doc); err != nil {
+		return ""
+	}
+	var docType string
+	_ = json.Unmarshal(doc[field], &docType)
+	return docType
+}
@@ -0,0 +1,59 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/flimzy/diff"
+
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestChangeRouter(t *testing.T) {
+	var calls []string
+	record := func(name string) TailFunc {
+		return func(change *driver.Change) error {
+			calls = append(calls, name+":"+change.ID)
+			return nil
+		}
+	}
+	r := &ChangeRouter{}
+	r.HandlePrefix("user:", record("prefix"))
+	r.HandleRegexp(regexp.MustCompile(`^order:\d+$`), record("regexp"))
+	r.HandleType("invoice", record("type"))
+
+	changes := []*driver.Change{
+		{ID: "user:bob"},
+		{ID: "order:123"},
+		{ID: "order:abc"},
+		{ID: "user:alice", Doc: json.RawMessage(`{"_id":"user:alice","type":"invoice"}`)},
+		{ID: "other", Doc: json.RawMessage(`{"_id":"other","type":1}`)},
+	}
+	for _, change := range changes {
+		if err := r.Route(change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"prefix:user:bob", "regexp:order:123", "prefix:user:alice", "type:user:alice"}
+	if d := diff.Interface(expected, calls); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestChangeRouterTypeField(t *testing.T) {
+	r := &ChangeRouter{TypeField: "kind"}
+	var called bool
+	r.HandleType("foo", func(_ *driver.Change) error {
+		called = true
+		return errors.New("failed")
+	})
+	err := r.Route(&driver.Change{ID: "x", Doc: json.RawMessage(`{"kind":"foo"}`)})
+	if !called {
+		t.Errorf("Handler not called")
+	}
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -11,21 +11,65 @@ import (
 	"github.com/go-kivik/kivik/errors"
 )
 
+// RowsMeta is implemented by the driver.Rows returned by AllDocs, Query, and
+// the other view queries, to distinguish the absence of offset and total_rows
+// from zero values, as kivik.Rows reports both as zero. It is reached by type
+// assertion:
+//
+//     rows, _ := db.Query(ctx, ddoc, view, opts)
+//     if meta, ok := rows.(couchdb.RowsMeta); ok && !meta.HasTotalRows() {
+//         // A reduced or grouped result
+//     }
+type RowsMeta interface {
+	driver.Rows
+	// HasOffset returns true if the response included an offset. As CouchDB
+	// sends the offset before any rows, this is valid once Next has been
+	// called.
+	HasOffset() bool
+	// HasTotalRows returns true if the response included total_rows. As
+	// CouchDB sends total_rows before any rows, this is valid once Next has
+	// been called.
+	HasTotalRows() bool
+}
+
+// AttachmentRows is implemented by the driver.Rows returned by AllDocs, Query,
+// and the other view queries, to report the attachments of the documents
+// included in the results. It is reached by type assertion, as RowsMeta is.
+type AttachmentRows interface {
+	driver.Rows
+	// Attachments returns the attachment stubs of the document of the row
+	// most recently returned by Next, sorted by filename. They are decoded
+	// only when the query sets both include_docs and attachments; otherwise,
+	// or if the row has no document with attachments, it returns nil.
+	Attachments() []*AttachmentStub
+}
+
 type rows struct {
 	offset    int64
 	totalRows int64
-	updateSeq string
-	warning   string
-	bookmark  string
-	body      io.ReadCloser
-	dec       *json.Decoder
+	// hasOffset and hasTotalRows are set when the respective fields were
+	// present in the response. Reduced and grouped views omit them.
+	hasOffset    bool
+	hasTotalRows bool
+	updateSeq    string
+	warning      string
+	bookmark     string
+	body         io.ReadCloser
+	dec          *json.Decoder
 	// closed is true after all rows have been processed
 	closed bool
 	// isFindRows is set to true if this result set is from the _find interface.
 	isFindRows bool
+	// withAttachments is set when the query requested documents with their
+	// attachments, which are then decoded into attachments for each row.
+	withAttachments bool
+	attachments     []*AttachmentStub
 }
 
-var _ driver.Rows = &rows{}
+var (
+	_ RowsMeta       = &rows{}
+	_ AttachmentRows = &rows{}
+)
 
 func newRows(r io.ReadCloser) *rows {
 	return &rows{
@@ -41,6 +85,18 @@ func (r *rows) TotalRows() int64 {
 	return r.totalRows
 }
 
+// HasOffset returns false for reduced or grouped view results, which omit the
+// offset.
+func (r *rows) HasOffset() bool {
+	return r.hasOffset
+}
+
+// HasTotalRows returns false for reduced or grouped view results, which omit
+// total_rows.
+func (r *rows) HasTotalRows() bool {
+	return r.hasTotalRows
+}
+
 func (r *rows) Warning() string {
 	return r.warning
 }
@@ -53,6 +109,11 @@ func (r *rows) UpdateSeq() string {
 	return r.updateSeq
 }
 
+// Attachments returns nil unless withAttachments is set.
+func (r *rows) Attachments() []*AttachmentStub {
+	return r.attachments
+}
+
 func (r *rows) Close() error {
 	return r.body.Close()
 }
@@ -137,8 +198,10 @@ func (r *rows) parseMeta(key string) error {
 	case "update_seq":
 		return r.readUpdateSeq()
 	case "offset":
+		r.hasOffset = true
 		return r.dec.Decode(&r.offset)
 	case "total_rows":
+		r.hasTotalRows = true
 		return r.dec.Decode(&r.totalRows)
 	case "warning":
 		return r.dec.Decode(&r.warning)
@@ -167,7 +230,47 @@ func (r *rows) nextRow(row *driver.Row) error {
 	if r.isFindRows {
 		return r.dec.Decode(&row.Doc)
 	}
-	return r.dec.Decode(row)
+	var result struct {
+		driver.Row
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	if err := r.dec.Decode(&result); err != nil {
+		return err
+	}
+	*row = result.Row
+	if result.Error != "" {
+		row.Error = rowError(result.Error, result.Reason)
+	}
+	return r.setAttachments(row.Doc)
+}
+
+// rowError converts the error and reason of a result row, such as one
+// returned for an unknown key, to an error.
+func rowError(err, reason string) error {
+	status := kivik.StatusUnknownError
+	if err == "not_found" {
+		status = kivik.StatusNotFound
+	}
+	if reason == "" {
+		reason = err
+	}
+	return errors.Status(status, reason)
+}
+
+func (r *rows) setAttachments(doc json.RawMessage) error {
+	r.attachments = nil
+	if !r.withAttachments || !bytes.Contains(doc, []byte(`"_attachments"`)) {
+		return nil
+	}
+	var result struct {
+		Attachments map[string]*AttachmentStub `json:"_attachments"`
+	}
+	if err := json.Unmarshal(doc, &result); err != nil {
+		return err
+	}
+	r.attachments = sortedStubs(result.Attachments)
+	return nil
 }
 
 // consumeDelim consumes the expected delimiter from the stream, or returns an
@@ -1,11 +1,14 @@
 package couchdb
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"strings"
 	"testing"
 
+	"github.com/flimzy/diff"
 	"github.com/flimzy/testy"
 
 	"github.com/go-kivik/kivik"
@@ -66,6 +69,9 @@ func TestRowsIterator(t *testing.T) {
 	if rows.Offset() != 6 {
 		t.Errorf("Expected Offset of 6, got %d", rows.Offset())
 	}
+	if !rows.HasTotalRows() || !rows.HasOffset() {
+		t.Errorf("Expected TotalRows and Offset to be present")
+	}
 	if err := rows.Next(&driver.Row{}); err != io.EOF {
 		t.Errorf("Calling Next() after end returned unexpected error: %s", err)
 	}
@@ -175,3 +181,147 @@ func TestFindRowsIterator(t *testing.T) {
 		t.Errorf("Unexpected bookmark: %s", rows.Bookmark())
 	}
 }
+
+func TestRowsIncludeDocs(t *testing.T) {
+	input := `{"total_rows":3,"offset":0,"rows":[
+{"id":"foo","key":"foo","value":{"rev":"2-xxx"},"doc":{"_id":"foo","_rev":"2-xxx","_attachments":{"foo.txt":{"content_type":"text/plain","revpos":2,"digest":"md5-bNNVbesNpUvKBgtMOUeYOQ==","length":13,"stub":true}}}},
+{"id":"bar","key":"bar","value":{"rev":"1-xxx"},"doc":{"_id":"bar","_rev":"1-xxx"}},
+{"key":"baz","error":"not_found"}
+]}`
+	rows := newRows(ioutil.NopCloser(strings.NewReader(input)))
+	rows.withAttachments = true
+	row := &driver.Row{}
+	if err := rows.Next(row); err != nil {
+		t.Fatal(err)
+	}
+	if d := diff.JSON([]byte(`{"_id":"foo","_rev":"2-xxx","_attachments":{"foo.txt":{"content_type":"text/plain","revpos":2,"digest":"md5-bNNVbesNpUvKBgtMOUeYOQ==","length":13,"stub":true}}}`), row.Doc); d != nil {
+		t.Errorf("Unexpected doc:\n%s", d)
+	}
+	expected := []*AttachmentStub{
+		{Filename: "foo.txt", ContentType: "text/plain", Length: 13, Digest: "md5-bNNVbesNpUvKBgtMOUeYOQ==", RevPos: 2},
+	}
+	if d := diff.Interface(expected, rows.Attachments()); d != nil {
+		t.Errorf("Unexpected attachments:\n%s", d)
+	}
+	row = &driver.Row{}
+	if err := rows.Next(row); err != nil {
+		t.Fatal(err)
+	}
+	if atts := rows.Attachments(); atts != nil {
+		t.Errorf("Unexpected attachments: %v", atts)
+	}
+	row = &driver.Row{}
+	if err := rows.Next(row); err != nil {
+		t.Fatal(err)
+	}
+	if row.Error == nil || kivik.StatusCode(row.Error) != kivik.StatusNotFound {
+		t.Errorf("Unexpected row error: %v", row.Error)
+	}
+}
+
+func TestReducedRows(t *testing.T) {
+	input := `{"rows":[
+{"key":["a"],"value":3},
+{"key":["b"],"value":0}
+]}`
+	rows := newRows(ioutil.NopCloser(strings.NewReader(input)))
+	var count int
+	for {
+		err := rows.Next(&driver.Row{})
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %s", err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 rows, got %d", count)
+	}
+	if rows.HasTotalRows() {
+		t.Errorf("Expected TotalRows to be absent")
+	}
+	if rows.HasOffset() {
+		t.Errorf("Expected Offset to be absent")
+	}
+}
+
+func TestRowsMeta(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected bool
+	}{
+		{
+			name:     "map view",
+			body:     `{"total_rows":0,"offset":0,"rows":[]}`,
+			expected: true,
+		},
+		{
+			name: "reduced view",
+			body: `{"rows":[{"key":null,"value":0}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newTestDB(&http.Response{StatusCode: kivik.StatusOK, Body: Body(test.body)}, nil)
+			rows, err := db.Query(context.Background(), "ddoc", "view", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close() // nolint: errcheck
+			_ = rows.Next(&driver.Row{})
+			meta, ok := rows.(RowsMeta)
+			if !ok {
+				t.Fatalf("%T does not implement RowsMeta", rows)
+			}
+			if meta.HasTotalRows() != test.expected || meta.HasOffset() != test.expected {
+				t.Errorf("Unexpected HasTotalRows: %t, HasOffset: %t", meta.HasTotalRows(), meta.HasOffset())
+			}
+		})
+	}
+}
+
+func TestAttachmentRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     map[string]interface{}
+		expected []*AttachmentStub
+	}{
+		{
+			name: "docs only",
+			opts: map[string]interface{}{"include_docs": true},
+		},
+		{
+			name: "docs and attachments",
+			opts: map[string]interface{}{"include_docs": true, "attachments": true},
+			expected: []*AttachmentStub{
+				{Filename: "foo.txt", ContentType: "text/plain", Digest: "md5-bNNVbesNpUvKBgtMOUeYOQ==", RevPos: 2},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"total_rows":1,"offset":0,"rows":[{"id":"foo","key":"foo","value":{"rev":"2-xxx"},"doc":{"_id":"foo","_rev":"2-xxx","_attachments":{"foo.txt":{"content_type":"text/plain","revpos":2,"digest":"md5-bNNVbesNpUvKBgtMOUeYOQ==","data":"SGVsbG8sIHdvcmxkIQ=="}}}}]}`),
+			}, nil)
+			rows, err := db.AllDocs(context.Background(), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close() // nolint: errcheck
+			if err := rows.Next(&driver.Row{}); err != nil {
+				t.Fatal(err)
+			}
+			atts, ok := rows.(AttachmentRows)
+			if !ok {
+				t.Fatalf("%T does not implement AttachmentRows", rows)
+			}
+			if d := diff.Interface(test.expected, atts.Attachments()); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// selectorArg describes the argument expected by a Mango operator.
+type selectorArg int
+
+const (
+	argAny selectorArg = iota
+	argSelector
+	argSelectorList
+	argList
+	argBool
+	argString
+	argNumber
+	argMod
+)
+
+// selectorOperators are the operators understood by CouchDB's Mango query
+// language.
+var selectorOperators = map[string]selectorArg{
+	"$and":         argSelectorList,
+	"$or":          argSelectorList,
+	"$nor":         argSelectorList,
+	"$not":         argSelector,
+	"$all":         argList,
+	"$elemMatch":   argSelector,
+	"$allMatch":    argSelector,
+	"$keyMapMatch": argSelector,
+	"$lt":          argAny,
+	"$lte":         argAny,
+	"$eq":          argAny,
+	"$ne":          argAny,
+	"$gte":         argAny,
+	"$gt":          argAny,
+	"$exists":      argBool,
+	"$type":        argString,
+	"$in":          argList,
+	"$nin":         argList,
+	"$size":        argNumber,
+	"$mod":         argMod,
+	"$regex":       argString,
+	"$beginsWith":  argString,
+	"$text":        argString,
+}
+
+// ValidateSelector checks that selector is a well-formed Mango selector,
+// using only known operators with arguments of the correct type. selector may
+// be raw JSON, as a string, []byte or json.RawMessage, or any value which
+// marshals to a JSON object. An empty selector is rejected, as it matches
+// every document.
+func ValidateSelector(selector interface{}) error {
+	var raw []byte
+	switch t := selector.(type) {
+	case []byte:
+		raw = t
+	case json.RawMessage:
+		raw = t
+	case string:
+		raw = []byte(t)
+	default:
+		var err error
+		if raw, err = json.Marshal(selector); err != nil {
+			return errors.WrapStatus(kivik.StatusBadRequest, err)
+		}
+	}
+	var sel interface{}
+	if err := json.Unmarshal(raw, &sel); err != nil {
+		return errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	obj, ok := sel.(map[string]interface{})
+	if !ok {
+		return errors.Status(kivik.StatusBadRequest, "kivik: selector must be an object")
+	}
+	if len(obj) == 0 {
+		return errors.Status(kivik.StatusBadRequest, "kivik: empty selector matches all documents")
+	}
+	return validateSelectorObject(obj)
+}
+
+func validateSelectorObject(obj map[string]interface{}) error {
+	for key, value := range obj {
+		if len(key) > 0 && key[0] == '$' {
+			if err := validateOperator(key, value); err != nil {
+				return err
+			}
+			continue
+		}
+		// A field name, whose value is either an implicit $eq, or an object
+		// of operators and sub-fields.
+		if sub, ok := value.(map[string]interface{}); ok {
+			if err := validateSelectorObject(sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateOperator(op string, value interface{}) error {
+	arg, ok := selectorOperators[op]
+	if !ok {
+		return errors.Statusf(kivik.StatusBadRequest, "kivik: unknown selector operator '%s'", op)
+	}
+	invalid := errors.Statusf(kivik.StatusBadRequest, "kivik: invalid argument for selector operator '%s'", op)
+	switch arg {
+	case argSelector:
+		sub, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid
+		}
+		return validateSelectorObject(sub)
+	case argSelectorList:
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 {
+			return invalid
+		}
+		for _, item := range list {
+			sub, ok := item.(map[string]interface{})
+			if !ok {
+				return invalid
+			}
+			if err := validateSelectorObject(sub); err != nil {
+				return err
+			}
+		}
+	case argList:
+		if _, ok := value.([]interface{}); !ok {
+			return invalid
+		}
+	case argBool:
+		if _, ok := value.(bool); !ok {
+			return invalid
+		}
+	case argString:
+		if _, ok := value.(string); !ok {
+			return invalid
+		}
+	case argNumber:
+		if _, ok := value.(float64); !ok {
+			return invalid
+		}
+	case argMod:
+		list, ok := value.([]interface{})
+		if !ok || len(list) != 2 {
+			return invalid
+		}
+		for _, item := range list {
+			if _, ok := item.(float64); !ok {
+				return invalid
+			}
+		}
+	}
+	return nil
+}
+
+// CheckSelector validates selector locally, then asks the server to plan a
+// query with it, so that operators the server does not support are reported.
+// It is intended to be called on the source database of a filtered
+// replication, before the replication is created.
+func (d *db) CheckSelector(ctx context.Context, selector interface{}) error {
+	if err := ValidateSelector(selector); err != nil {
+		return err
+	}
+	_, err := d.Explain(ctx, map[string]interface{}{"selector": selector})
+	return err
+}
@@ -0,0 +1,94 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestValidateSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector interface{}
+		status   int
+		err      string
+	}{
+		{
+			name:     "simple",
+			selector: map[string]interface{}{"type": "user"},
+		},
+		{
+			name:     "raw JSON",
+			selector: []byte(`{"$or":[{"age":{"$gt":20}},{"name":{"$regex":"^A"}}],"address":{"city":"Paris"}}`),
+		},
+		{
+			name:     "unmarshalable",
+			selector: make(chan int),
+			status:   kivik.StatusBadRequest,
+			err:      "json: unsupported type: chan int",
+		},
+		{
+			name:     "not an object",
+			selector: []string{"foo"},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: selector must be an object",
+		},
+		{
+			name:     "empty",
+			selector: map[string]interface{}{},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: empty selector matches all documents",
+		},
+		{
+			name:     "unknown operator",
+			selector: map[string]interface{}{"age": map[string]interface{}{"$gteq": 20}},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: unknown selector operator '$gteq'",
+		},
+		{
+			name:     "nested unknown operator",
+			selector: map[string]interface{}{"$and": []interface{}{map[string]interface{}{"$nott": map[string]interface{}{}}}},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: unknown selector operator '$nott'",
+		},
+		{
+			name:     "invalid $in",
+			selector: map[string]interface{}{"type": map[string]interface{}{"$in": "user"}},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: invalid argument for selector operator '$in'",
+		},
+		{
+			name:     "invalid $mod",
+			selector: map[string]interface{}{"n": map[string]interface{}{"$mod": []int{2}}},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: invalid argument for selector operator '$mod'",
+		},
+		{
+			name:     "invalid $or",
+			selector: map[string]interface{}{"$or": []interface{}{}},
+			status:   kivik.StatusBadRequest,
+			err:      "kivik: invalid argument for selector operator '$or'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateSelector(test.selector)
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestCheckSelector(t *testing.T) {
+	db := newTestDB(&http.Response{
+		StatusCode:    kivik.StatusBadRequest,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		ContentLength: -1,
+		Body:          Body(`{"error":"invalid_operator","reason":"Invalid operator: $beginsWith"}`),
+	}, nil)
+	err := db.CheckSelector(context.Background(), map[string]interface{}{"name": map[string]interface{}{"$beginsWith": "A"}})
+	testy.StatusError(t, "Bad Request: Invalid operator: $beginsWith", kivik.StatusBadRequest, err)
+}
@@ -0,0 +1,99 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// Sequence is an update sequence, as found in changes feeds and database
+// info. CouchDB 1.x sequences are integers, while later versions use opaque
+// strings, of which only the numeric prefix is meaningful to clients. The
+// zero value is the empty sequence.
+type Sequence struct {
+	value string
+}
+
+var _ json.Marshaler = Sequence{}
+var _ json.Unmarshaler = &Sequence{}
+
+// ParseSequence returns the Sequence represented by s, as it would appear in a
+// since query parameter, such as "42", "now", or "42-g1AAAA...".
+func ParseSequence(s string) Sequence {
+	return Sequence{value: s}
+}
+
+// String returns the sequence in the form used in query parameters.
+func (s Sequence) String() string {
+	return s.value
+}
+
+// IsZero returns true for the empty sequence.
+func (s Sequence) IsZero() bool {
+	return s.value == ""
+}
+
+// Number returns the numeric part of the sequence: the whole of a 1.x
+// sequence, or the prefix of a later one. ok is false if there is none, as
+// for "now".
+func (s Sequence) Number() (n int64, ok bool) {
+	prefix := strings.SplitN(s.value, "-", 2)[0]
+	n, err := strconv.ParseInt(prefix, 10, 64)
+	return n, err == nil
+}
+
+// Compare compares the numeric parts of two sequences, returning -1, 0 or 1,
+// and whether the comparison was possible. For CouchDB 2.x and later, the
+// numeric part is a sum over the database's shards, so a larger value
+// suggests, but does not guarantee, a later sequence, and equal values do not
+// imply equal sequences.
+func (s Sequence) Compare(other Sequence) (int, bool) {
+	a, aok := s.Number()
+	b, bok := other.Number()
+	if !aok || !bok {
+		return 0, false
+	}
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	}
+	return 0, true
+}
+
+// MarshalJSON encodes integer sequences as JSON numbers, as CouchDB 1.x does,
+// and others as strings.
+func (s Sequence) MarshalJSON() ([]byte, error) {
+	if s.value == "" {
+		return []byte("null"), nil
+	}
+	if _, err := strconv.ParseInt(s.value, 10, 64); err == nil {
+		return []byte(s.value), nil
+	}
+	return json.Marshal(s.value)
+}
+
+// UnmarshalJSON accepts a sequence encoded as a JSON number, string or null.
+func (s *Sequence) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return errors.WrapStatus(kivik.StatusBadResponse, err)
+	}
+	switch v := value.(type) {
+	case nil:
+		s.value = ""
+	case string:
+		s.value = v
+	case json.Number:
+		s.value = v.String()
+	default:
+		return errors.Statusf(kivik.StatusBadResponse, "kivik: invalid sequence: %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestSequenceJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		value   string
+		encoded string
+		status  int
+		err     string
+	}{
+		{name: "1.x integer", input: `42`, value: "42", encoded: `42`},
+		{name: "2.x string", input: `"42-g1AAAA"`, value: "42-g1AAAA", encoded: `"42-g1AAAA"`},
+		{name: "quoted integer", input: `"42"`, value: "42", encoded: `42`},
+		{name: "null", input: `null`, value: "", encoded: `null`},
+		{name: "invalid type", input: `[1]`, status: kivik.StatusBadResponse, err: "kivik: invalid sequence: [1]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var seq Sequence
+			err := json.Unmarshal([]byte(test.input), &seq)
+			testy.StatusError(t, test.err, test.status, err)
+			if seq.String() != test.value {
+				t.Errorf("Unexpected value: %s", seq)
+			}
+			encoded, err := json.Marshal(seq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(encoded) != test.encoded {
+				t.Errorf("Unexpected encoding: %s", encoded)
+			}
+		})
+	}
+}
+
+func TestSequenceCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+		ok       bool
+	}{
+		{a: "1", b: "2", expected: -1, ok: true},
+		{a: "10-xxx", b: "9-yyy", expected: 1, ok: true},
+		{a: "10", b: "10-yyy", expected: 0, ok: true},
+		{a: "now", b: "10"},
+		{a: "", b: "10"},
+	}
+	for _, test := range tests {
+		result, ok := ParseSequence(test.a).Compare(ParseSequence(test.b))
+		if result != test.expected || ok != test.ok {
+			t.Errorf("Compare(%q, %q) = %d, %t; expected %d, %t", test.a, test.b, result, ok, test.expected, test.ok)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package couchdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+)
+
+// ShardCopies reports the winning revision of a document in each copy of the
+// shard which holds it.
+type ShardCopies struct {
+	DocID string
+	// Range is the shard's range, such as "00000000-1fffffff".
+	Range string
+	// Revs maps the name of each node holding a copy of the shard to the
+	// document's winning revision in that copy, or an empty string if the copy
+	// lacks the document.
+	Revs map[string]string
+	// Errors maps the name of each node whose copy could not be checked to
+	// the reason.
+	Errors map[string]error
+}
+
+// Diverged returns true if the copies which could be checked do not all have
+// the same winning revision.
+func (s *ShardCopies) Diverged() bool {
+	first := true
+	var rev string
+	for _, r := range s.Revs {
+		if first {
+			rev, first = r, false
+			continue
+		}
+		if r != rev {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckShardCopies compares the copies of each of the sample of documents
+// docIDs from dbName held by each node of a CouchDB 2.x cluster, and returns
+// the reports for those documents whose copies have diverged, or could not
+// all be checked. Divergence is expected briefly after each write, while
+// internal replication catches up; persistent divergence indicates a problem.
+//
+// The shards holding each document are found with /{db}/_shards/{docid}, and
+// each copy is read through the node-local interface of the node holding it.
+// nodes maps node names, as reported by _shards, such as
+// "couchdb@node1.example.com", to clients connected to the node-local
+// interface (by default on port 5986) of that node, with admin credentials.
+func (c *client) CheckShardCopies(ctx context.Context, dbName string, docIDs []string, nodes map[string]*chttp.Client) ([]*ShardCopies, error) {
+	if dbName == "" {
+		return nil, missingArg("dbName")
+	}
+	if err := c.checkDB(dbName); err != nil {
+		return nil, err
+	}
+	d := &db{client: c, dbName: dbName}
+	// shardNames caches the name of each node's copy of each range.
+	shardNames := make(map[string]map[string]string)
+	var reports []*ShardCopies
+	for _, docID := range docIDs {
+		var shard struct {
+			Range string   `json:"range"`
+			Nodes []string `json:"nodes"`
+		}
+		if _, err := c.DoJSON(ctx, kivik.MethodGet, d.path("_shards/"+chttp.EncodeDocID(docID), nil), nil, &shard); err != nil {
+			return nil, err
+		}
+		report := &ShardCopies{
+			DocID: docID,
+			Range: shard.Range,
+			Revs:  make(map[string]string, len(shard.Nodes)),
+		}
+		for _, node := range shard.Nodes {
+			rev, err := checkShardCopy(ctx, nodes[node], node, dbName, shard.Range, docID, shardNames)
+			if err != nil {
+				if report.Errors == nil {
+					report.Errors = make(map[string]error)
+				}
+				report.Errors[node] = err
+				continue
+			}
+			report.Revs[node] = rev
+		}
+		if report.Diverged() || len(report.Errors) > 0 {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// checkShardCopy returns the winning revision of docID in node's copy of the
+// shard of dbName covering shardRange.
+func checkShardCopy(ctx context.Context, nodeClient *chttp.Client, node, dbName, shardRange, docID string, shardNames map[string]map[string]string) (string, error) {
+	if nodeClient == nil {
+		return "", fmt.Errorf("kivik: no client for node %s", node)
+	}
+	names, ok := shardNames[node]
+	if !ok {
+		var err error
+		if names, err = nodeShards(ctx, nodeClient, dbName); err != nil {
+			return "", err
+		}
+		shardNames[node] = names
+	}
+	name, ok := names[shardRange]
+	if !ok {
+		return "", fmt.Errorf("kivik: shard %s of %s not found on node %s", shardRange, dbName, node)
+	}
+	shard := &db{client: &client{Client: nodeClient}, dbName: url.PathEscape(name)}
+	return shard.currentRev(ctx, docID)
+}
+
+// nodeShards returns the names of the node's shards of dbName, by range. On
+// the node-local interface, shards are named shards/{range}/{dbName}.{suffix}.
+func nodeShards(ctx context.Context, nodeClient *chttp.Client, dbName string) (map[string]string, error) {
+	var allDBs []string
+	if _, err := nodeClient.DoJSON(ctx, kivik.MethodGet, "/_all_dbs", nil, &allDBs); err != nil {
+		return nil, err
+	}
+	shards := make(map[string]string)
+	for _, name := range allDBs {
+		if !strings.HasPrefix(name, "shards/") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(name, "shards/"), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		dot := strings.LastIndex(parts[1], ".")
+		if dot < 0 || parts[1][:dot] != dbName {
+			continue
+		}
+		shards[parts[0]] = name
+	}
+	return shards, nil
+}
@@ -0,0 +1,87 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+)
+
+func TestCheckShardCopies(t *testing.T) {
+	jsonResponse := func(body string) *http.Response {
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       Body(body),
+		}
+	}
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/db/_shards/foo":
+			return jsonResponse(`{"range":"00000000-7fffffff","nodes":["n1","n2","n3"]}`), nil
+		case "/db/_shards/bar":
+			return jsonResponse(`{"range":"00000000-7fffffff","nodes":["n1","n2"]}`), nil
+		}
+		t.Errorf("Unexpected request: %s %s", req.Method, req.URL.Path)
+		return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+	})
+	node := func(revs map[string]string) *chttp.Client {
+		return newCustomClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/_all_dbs" {
+				return jsonResponse(`["_users","shards/00000000-7fffffff/db.123","shards/00000000-7fffffff/dbx.123","shards/80000000-ffffffff/db.123"]`), nil
+			}
+			if req.Method != kivik.MethodHead {
+				t.Errorf("Unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			switch req.URL.EscapedPath() {
+			case "/shards%2F00000000-7fffffff%2Fdb.123/foo":
+				return &http.Response{StatusCode: kivik.StatusOK, Header: http.Header{"ETag": {`"` + revs["foo"] + `"`}}, Body: Body("")}, nil
+			case "/shards%2F00000000-7fffffff%2Fdb.123/bar":
+				return &http.Response{StatusCode: kivik.StatusOK, Header: http.Header{"ETag": {`"` + revs["bar"] + `"`}}, Body: Body("")}, nil
+			}
+			t.Errorf("Unexpected path: %s", req.URL.EscapedPath())
+			return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+		}).Client
+	}
+	nodes := map[string]*chttp.Client{
+		"n1": node(map[string]string{"foo": "2-a", "bar": "1-b"}),
+		"n2": node(map[string]string{"foo": "1-a", "bar": "1-b"}),
+	}
+	reports, err := c.CheckShardCopies(context.Background(), "db", []string{"foo", "bar"}, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+	report := reports[0]
+	if d := diff.Interface(map[string]string{"n1": "2-a", "n2": "1-a"}, report.Revs); d != nil {
+		t.Error(d)
+	}
+	if report.DocID != "foo" || report.Range != "00000000-7fffffff" || !report.Diverged() {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	testy.Error(t, "kivik: no client for node n3", report.Errors["n3"])
+}
+
+func TestShardCopiesDiverged(t *testing.T) {
+	tests := []struct {
+		revs     map[string]string
+		expected bool
+	}{
+		{revs: map[string]string{}},
+		{revs: map[string]string{"n1": "1-a"}},
+		{revs: map[string]string{"n1": "1-a", "n2": "1-a"}},
+		{revs: map[string]string{"n1": "1-a", "n2": ""}, expected: true},
+	}
+	for _, test := range tests {
+		if result := (&ShardCopies{Revs: test.revs}).Diverged(); result != test.expected {
+			t.Errorf("Diverged(%v) = %t, expected %t", test.revs, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// DeletePreserving deletes the document docID at rev, as Delete does, but
+// keeps the named fields of the deleted revision in the tombstone, so that
+// they remain available for auditing. If no fields are named, every field
+// except the special underscore-prefixed fields is kept. The new rev is
+// returned.
+//
+// Tombstones may be read with Get, by passing OptionTombstone, and are
+// included in changes feeds requested with include_docs=true.
+func (d *db) DeletePreserving(ctx context.Context, docID, rev string, fields ...string) (string, error) {
+	if err := validateDocID(docID); err != nil {
+		return "", err
+	}
+	if err := validateRev(rev); err != nil {
+		return "", err
+	}
+	body, _, err := d.GetRev(ctx, docID, map[string]interface{}{"rev": rev})
+	if err != nil {
+		return "", err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", errors.WrapStatus(kivik.StatusBadResponse, err)
+	}
+	tomb := make(map[string]interface{}, len(fields)+2)
+	if len(fields) == 0 {
+		for field, value := range doc {
+			if !strings.HasPrefix(field, "_") {
+				tomb[field] = value
+			}
+		}
+	}
+	for _, field := range fields {
+		if value, ok := doc[field]; ok {
+			tomb[field] = value
+		}
+	}
+	tomb["_rev"] = rev
+	tomb["_deleted"] = true
+	return d.Put(ctx, docID, tomb, nil)
+}
+
+// DeletedError is returned by Get for a document which has been deleted, to
+// distinguish it from one which never existed.
+type DeletedError struct {
+	*chttp.HTTPError
+	// Rev is the revision of the tombstone, if OptionDeletedRev was set.
+	Rev string
+}
+
+// deletedError converts err to a *DeletedError if it reports that docID has
+// been deleted, looking up the tombstone's revision if lookupRev is true.
+func (d *db) deletedError(ctx context.Context, docID string, err error, lookupRev bool) error {
+	httpErr, ok := err.(*chttp.HTTPError)
+	if !ok || httpErr.Code != kivik.StatusNotFound || httpErr.Reason != "deleted" {
+		return err
+	}
+	deleted := &DeletedError{HTTPError: httpErr}
+	if lookupRev {
+		tomb, e := d.getTombstone(ctx, docID)
+		if e != nil {
+			return e
+		}
+		_ = tomb.Body.Close()
+		deleted.Rev = tomb.Rev
+	}
+	return deleted
+}
+
+// getTombstone returns the most recent deleted leaf revision of docID.
+func (d *db) getTombstone(ctx context.Context, docID string) (*driver.Document, error) {
+	query := url.Values{"open_revs": []string{"all"}}
+	var leaves []struct {
+		OK json.RawMessage `json:"ok"`
+	}
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodGet, d.path(chttp.EncodeDocID(docID), query), &chttp.Options{Accept: "application/json"}, &leaves); err != nil {
+		return nil, err
+	}
+	var body []byte
+	var rev string
+	for _, leaf := range leaves {
+		if leaf.OK == nil {
+			continue
+		}
+		var meta struct {
+			Rev     string `json:"_rev"`
+			Deleted bool   `json:"_deleted"`
+		}
+		if err := json.Unmarshal(leaf.OK, &meta); err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		if meta.Deleted && (rev == "" || revGeneration(meta.Rev) > revGeneration(rev)) {
+			body, rev = leaf.OK, meta.Rev
+		}
+	}
+	if rev == "" {
+		return nil, errors.Status(kivik.StatusNotFound, "kivik: no tombstone found")
+	}
+	return &driver.Document{
+		Rev:           rev,
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// revGeneration returns the generation number of rev, or 0 if it is malformed.
+func revGeneration(rev string) int {
+	gen, _ := strconv.Atoi(strings.SplitN(rev, "-", 2)[0])
+	return gen
+}
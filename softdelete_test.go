@@ -0,0 +1,228 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestDeletePreserving(t *testing.T) {
+	tests := []struct {
+		name     string
+		rev      string
+		fields   []string
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name:   "no rev",
+			status: kivik.StatusBadRequest,
+			err:    "kivik: rev required",
+		},
+		{
+			name:     "all fields",
+			rev:      "1-xxx",
+			expected: `{"_rev":"1-xxx","_deleted":true,"owner":"bob","size":3}`,
+		},
+		{
+			name:     "selected fields",
+			rev:      "1-xxx",
+			fields:   []string{"owner", "missing"},
+			expected: `{"_rev":"1-xxx","_deleted":true,"owner":"bob"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tomb []byte
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet {
+					if rev := req.URL.Query().Get("rev"); rev != "1-xxx" {
+						return nil, errors.Errorf("Unexpected rev: %s", rev)
+					}
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Header:     http.Header{"ETag": {`"1-xxx"`}},
+						Body:       Body(`{"_id":"foo","_rev":"1-xxx","_attachments":{},"owner":"bob","size":3}`),
+					}, nil
+				}
+				var err error
+				if tomb, err = ioutil.ReadAll(req.Body); err != nil {
+					return nil, err
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"ok":true,"id":"foo","rev":"2-xxx"}`),
+				}, nil
+			})
+			rev, err := db.DeletePreserving(context.Background(), "foo", test.rev, test.fields...)
+			testy.StatusError(t, test.err, test.status, err)
+			if rev != "2-xxx" {
+				t.Errorf("Unexpected rev: %s", rev)
+			}
+			if d := diff.JSON([]byte(test.expected), tomb); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestGetTombstone(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  map[string]interface{}
+		db       *db
+		expected string
+		rev      string
+		status   int
+		err      string
+	}{
+		{
+			name:    "invalid option",
+			options: map[string]interface{}{OptionTombstone: "yes"},
+			db:      &db{},
+			status:  kivik.StatusBadRequest,
+			err:     "kivik: option 'tombstone' must be bool, not string",
+		},
+		{
+			name:    "not requested",
+			options: map[string]interface{}{},
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusNotFound,
+				Request:    &http.Request{Method: kivik.MethodGet},
+				Body:       Body(`{"error":"not_found","reason":"deleted"}`),
+			}, nil),
+			status: kivik.StatusNotFound,
+			err:    "Not Found",
+		},
+		{
+			name:    "tombstone",
+			options: map[string]interface{}{OptionTombstone: true},
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if _, ok := req.URL.Query()["tombstone"]; ok {
+					return nil, errors.New("tombstone option sent to server")
+				}
+				if req.URL.Query().Get("open_revs") != "all" {
+					return &http.Response{
+						StatusCode: kivik.StatusNotFound,
+						Request:    req,
+						Body:       Body(`{"error":"not_found","reason":"deleted"}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body: Body(`[{"ok":{"_id":"foo","_rev":"2-aaa","_deleted":true}},
+{"missing":"1-zzz"},
+{"ok":{"_id":"foo","_rev":"3-bbb","_deleted":true,"owner":"bob"}}]`),
+				}, nil
+			}),
+			expected: `{"_id":"foo","_rev":"3-bbb","_deleted":true,"owner":"bob"}`,
+			rev:      "3-bbb",
+		},
+		{
+			name:    "no deleted leaf",
+			options: map[string]interface{}{OptionTombstone: true},
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Query().Get("open_revs") != "all" {
+					return &http.Response{
+						StatusCode: kivik.StatusNotFound,
+						Request:    req,
+						Body:       Body(`{"error":"not_found","reason":"deleted"}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`[{"missing":"1-zzz"}]`),
+				}, nil
+			}),
+			status: kivik.StatusNotFound,
+			err:    "kivik: no tombstone found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := test.db.Get(context.Background(), "foo", test.options)
+			testy.StatusError(t, test.err, test.status, err)
+			if doc.Rev != test.rev {
+				t.Errorf("Unexpected rev: %s", doc.Rev)
+			}
+			body, err := ioutil.ReadAll(doc.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.JSON([]byte(test.expected), body); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestGetDeleted(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		reason  string
+		deleted bool
+		rev     string
+	}{
+		{
+			name:   "missing",
+			reason: "missing",
+		},
+		{
+			name:    "deleted",
+			reason:  "deleted",
+			deleted: true,
+		},
+		{
+			name:    "deleted rev",
+			options: map[string]interface{}{OptionDeletedRev: true},
+			reason:  "deleted",
+			deleted: true,
+			rev:     "3-bbb",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if _, ok := req.URL.Query()[OptionDeletedRev]; ok {
+					return nil, errors.New("deleted_rev option sent to server")
+				}
+				if req.URL.Query().Get("open_revs") != "all" {
+					return &http.Response{
+						StatusCode:    kivik.StatusNotFound,
+						Request:       req,
+						Header:        http.Header{"Content-Type": {"application/json"}},
+						ContentLength: -1,
+						Body:          Body(`{"error":"not_found","reason":"` + test.reason + `"}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`[{"ok":{"_id":"foo","_rev":"3-bbb","_deleted":true}}]`),
+				}, nil
+			})
+			_, err := db.Get(context.Background(), "foo", test.options)
+			if kivik.StatusCode(err) != kivik.StatusNotFound {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			deleted, ok := err.(*DeletedError)
+			if ok != test.deleted {
+				t.Fatalf("Unexpected error type %T", err)
+			}
+			if ok && deleted.Rev != test.rev {
+				t.Errorf("Unexpected rev: %s", deleted.Rev)
+			}
+			if kind := ErrorKindOf(err); (kind == KindDeleted) != test.deleted {
+				t.Errorf("Unexpected kind: %s", kind)
+			}
+		})
+	}
+}
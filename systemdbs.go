@@ -0,0 +1,81 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+// SystemDBs are the system databases which a CouchDB 2.x or 3.x cluster needs
+// to operate. CouchDB 3 does not create them automatically.
+var SystemDBs = []string{"_users", "_replicator", "_global_changes"}
+
+// SystemDBStatus reports the state of a single system database.
+type SystemDBStatus struct {
+	Name   string
+	Exists bool
+	// Stats is nil if the database does not exist.
+	Stats *driver.DBStats
+}
+
+// SystemDBStats fetches the stats for each of SystemDBs, reporting which of
+// them exist. Where supported (CouchDB 2.2 and later), a single _dbs_info
+// request is made; otherwise each database is queried in turn.
+func (c *client) SystemDBStats(ctx context.Context) ([]SystemDBStatus, error) {
+	status, err := c.dbsInfo(ctx, SystemDBs)
+	if kivik.StatusCode(err) != kivik.StatusNotFound && kivik.StatusCode(err) != kivik.StatusMethodNotAllowed {
+		return status, err
+	}
+	status = make([]SystemDBStatus, len(SystemDBs))
+	for i, name := range SystemDBs {
+		d := &db{client: c, dbName: name}
+		stats, err := d.Stats(ctx)
+		switch {
+		case kivik.StatusCode(err) == kivik.StatusNotFound:
+			status[i] = SystemDBStatus{Name: name}
+		case err != nil:
+			return nil, err
+		default:
+			status[i] = SystemDBStatus{Name: name, Exists: true, Stats: stats}
+		}
+	}
+	return status, nil
+}
+
+// dbsInfo fetches the stats for dbNames with a single _dbs_info request.
+func (c *client) dbsInfo(ctx context.Context, dbNames []string) ([]SystemDBStatus, error) {
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(map[string]interface{}{"keys": dbNames}),
+	}
+	var result []struct {
+		Key   string   `json:"key"`
+		Info  *dbStats `json:"info"`
+		Error string   `json:"error"`
+	}
+	if _, err := c.DoJSON(ctx, kivik.MethodPost, "/_dbs_info", opts, &result); err != nil {
+		return nil, err
+	}
+	status := make([]SystemDBStatus, len(result))
+	for i, row := range result {
+		status[i] = SystemDBStatus{Name: row.Key}
+		if row.Info != nil && row.Error == "" {
+			status[i].Exists = true
+			status[i].Stats = row.Info.driverStats()
+		}
+	}
+	return status, nil
+}
+
+// EnsureSystemDBs creates any of SystemDBs which do not already exist. It is
+// safe to call repeatedly, and concurrently with other clients doing the same.
+func (c *client) EnsureSystemDBs(ctx context.Context) error {
+	for _, name := range SystemDBs {
+		err := c.CreateDB(ctx, name, nil)
+		if err != nil && kivik.StatusCode(err) != kivik.StatusPreconditionFailed {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestSystemDBStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   *client
+		expected []SystemDBStatus
+		status   int
+		err      string
+	}{
+		{
+			name:   "network error",
+			client: newTestClient(nil, errors.New("net error")),
+			status: kivik.StatusNetworkError,
+			err:    `Post "?http://example.com/_dbs_info"?: net error`,
+		},
+		{
+			name: "dbs_info",
+			client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/_dbs_info" {
+					return nil, errors.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body: Body(`[{"key":"_users","info":{"db_name":"_users","update_seq":"1-xxx","sizes":{"file":100,"external":20,"active":50},"doc_count":1}},
+{"key":"_replicator","error":"not_found"},
+{"key":"_global_changes","error":"not_found"}]`),
+				}, nil
+			}),
+			expected: []SystemDBStatus{
+				{Name: "_users", Exists: true, Stats: &driver.DBStats{Name: "_users", UpdateSeq: "1-xxx", DocCount: 1, DiskSize: 100, ExternalSize: 20, ActiveSize: 50}},
+				{Name: "_replicator"},
+				{Name: "_global_changes"},
+			},
+		},
+		{
+			name: "fallback",
+			client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+				switch req.URL.Path {
+				case "/_users":
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Body:       Body(`{"db_name":"_users","update_seq":31,"doc_count":3,"disk_size":127080,"data_size":6028}`),
+					}, nil
+				case "/_replicator":
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Body:       Body(`{"db_name":"_replicator","update_seq":1}`),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusNotFound,
+					Request:    req,
+					Body:       Body(""),
+				}, nil
+			}),
+			expected: []SystemDBStatus{
+				{Name: "_users", Exists: true, Stats: &driver.DBStats{Name: "_users", UpdateSeq: "31", DocCount: 3, DiskSize: 127080, ActiveSize: 6028}},
+				{Name: "_replicator", Exists: true, Stats: &driver.DBStats{Name: "_replicator", UpdateSeq: "1"}},
+				{Name: "_global_changes"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.client.SystemDBStats(context.Background())
+			testy.StatusErrorRE(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestEnsureSystemDBs(t *testing.T) {
+	tests := []struct {
+		name   string
+		client *client
+		status int
+		err    string
+	}{
+		{
+			name:   "network error",
+			client: newTestClient(nil, errors.New("net error")),
+			status: kivik.StatusNetworkError,
+			err:    `Put "?http://example.com/_users"?: net error`,
+		},
+		{
+			name: "some exist",
+			client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/_users" {
+					return &http.Response{
+						StatusCode: kivik.StatusPreconditionFailed,
+						Request:    req,
+						Body:       Body(""),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`{"ok":true}`),
+				}, nil
+			}),
+		},
+		{
+			name: "unauthorized",
+			client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: kivik.StatusUnauthorized,
+					Request:    req,
+					Body:       Body(""),
+				}, nil
+			}),
+			status: kivik.StatusUnauthorized,
+			err:    "Unauthorized",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.client.EnsureSystemDBs(context.Background())
+			testy.StatusErrorRE(t, test.err, test.status, err)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+// tailRetryDelay is how long Tail waits before reconnecting to a feed which
+// has ended or failed.
+var tailRetryDelay = time.Second
+
+// TailFunc is called by Tail for each change.
+type TailFunc func(change *driver.Change) error
+
+// Tail follows the continuous changes feed of dbName, with documents
+// included, calling fn for each change made after Tail is called. If the
+// connection is lost, or the server ends the feed, Tail reconnects, resuming
+// from the last change seen. It returns when ctx is cancelled, when fn returns
+// an error, when the client is closed, or when the server rejects the request,
+// such as when the database does not exist.
+func (c *client) Tail(ctx context.Context, dbName string, fn TailFunc) error {
+	if dbName == "" {
+		return missingArg("dbName")
+	}
+	if err := c.checkDB(dbName); err != nil {
+		return err
+	}
+	d := &db{client: c, dbName: dbName}
+	return d.follow(ctx, "now", fn)
+}
+
+// follow reads the changes feed from since, reconnecting as described for
+// Tail.
+func (d *db) follow(ctx context.Context, since string, fn TailFunc) error {
+	metrics := feedMetrics(ctx)
+	for connected := false; ; connected = true {
+		if connected {
+			metrics.observeReconnect()
+		}
+		var err error
+		since, err = d.tail(ctx, since, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if herr, ok := err.(handlerError); ok {
+				return herr.error
+			}
+			if err == errClosed {
+				return err
+			}
+			if status := kivik.StatusCode(err); status >= 400 && status < 500 {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.clock().After(tailRetryDelay):
+		}
+	}
+}
+
+// handlerError wraps an error returned by a TailFunc, to distinguish it from
+// feed errors.
+type handlerError struct {
+	error
+}
+
+// tail reads a single connection's worth of changes, returning the sequence
+// from which to resume.
+func (d *db) tail(ctx context.Context, since string, fn TailFunc) (string, error) {
+	changes, err := d.Changes(ctx, map[string]interface{}{
+		"since":        since,
+		"include_docs": true,
+	})
+	if err != nil {
+		return since, err
+	}
+	defer changes.Close() // nolint: errcheck
+	metrics := feedMetrics(ctx)
+	for {
+		change := &driver.Change{}
+		if err := changes.Next(change); err != nil {
+			if err == io.EOF {
+				return since, nil
+			}
+			return since, err
+		}
+		metrics.observeChange(d.clock())
+		if err := fn(change); err != nil {
+			return since, handlerError{err}
+		}
+		if seq := string(change.Seq); seq != "" {
+			since = seq
+		}
+	}
+}
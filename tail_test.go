@@ -0,0 +1,134 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestTail(t *testing.T) {
+	defer func(delay time.Duration) { tailRetryDelay = delay }(tailRetryDelay)
+	tailRetryDelay = time.Millisecond
+
+	var sinces []string
+	responses := []string{
+		`{"seq":"1-xxx","id":"foo","changes":[{"rev":"1-aaa"}],"doc":{"_id":"foo"}}
+{"seq":"2-xxx","id":"bar","changes":[{"rev":"1-bbb"}],"doc":{"_id":"bar"}}
+`,
+		`{"seq":"3-xxx","id":"baz","changes":[{"rev":"1-ccc"}],"doc":{"_id":"baz"}}
+`,
+	}
+	c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		if query.Get("include_docs") != "true" {
+			t.Errorf("include_docs not set")
+		}
+		sinces = append(sinces, query.Get("since"))
+		if len(sinces) == 2 {
+			return nil, errors.New("connection lost")
+		}
+		body := responses[0]
+		if len(sinces) > 2 {
+			body = responses[1]
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body:       Body(body),
+		}, nil
+	})
+	stop := errors.New("stop")
+	var ids []string
+	metrics := &FeedMetrics{}
+	ctx := WithFeedMetrics(context.Background(), metrics)
+	err := c.Tail(ctx, "db", func(change *driver.Change) error {
+		ids = append(ids, change.ID)
+		if change.ID == "baz" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if d := diff.Interface([]string{"foo", "bar", "baz"}, ids); d != nil {
+		t.Error(d)
+	}
+	if d := diff.Interface([]string{"now", "2-xxx", "2-xxx"}, sinces); d != nil {
+		t.Error(d)
+	}
+	if metrics.Changes() != 3 {
+		t.Errorf("Expected 3 changes, got %d", metrics.Changes())
+	}
+	if metrics.Reconnects() != 2 {
+		t.Errorf("Expected 2 reconnects, got %d", metrics.Reconnects())
+	}
+	if age := metrics.LastChangeAge(); age <= 0 || age > time.Minute {
+		t.Errorf("Unexpected last change age: %s", age)
+	}
+}
+
+func TestTailErrors(t *testing.T) {
+	t.Run("no db", func(t *testing.T) {
+		err := (&client{}).Tail(context.Background(), "", nil)
+		testy.StatusError(t, "kivik: dbName required", kivik.StatusBadRequest, err)
+	})
+	t.Run("not found", func(t *testing.T) {
+		c := newTestClient(&http.Response{
+			StatusCode: kivik.StatusNotFound,
+			Body:       Body(""),
+		}, nil)
+		err := c.Tail(context.Background(), "db", nil)
+		testy.StatusError(t, "Not Found", kivik.StatusNotFound, err)
+	})
+	t.Run("client closed", func(t *testing.T) {
+		defer func(delay time.Duration) { tailRetryDelay = delay }(tailRetryDelay)
+		tailRetryDelay = time.Millisecond
+		c := newTestClient(&http.Response{
+			StatusCode: kivik.StatusOK,
+			Body:       Body(`{"seq":"1-xxx","id":"foo","changes":[{"rev":"1-aaa"}]}`),
+		}, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err := c.Tail(ctx, "db", func(*driver.Change) error {
+			return c.Close(context.Background())
+		})
+		testy.StatusError(t, "kivik: client closed", kivik.StatusUnknownError, err)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		c := newTestClient(nil, errors.New("net error"))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := c.Tail(ctx, "db", nil); err != context.DeadlineExceeded {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestTailMetricsClock(t *testing.T) {
+	c := newTestClient(&http.Response{
+		StatusCode: kivik.StatusOK,
+		Body:       Body(`{"seq":"1-xxx","id":"foo","changes":[{"rev":"1-aaa"}]}`),
+	}, nil)
+	clock := &fakeClock{now: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := c.Authenticate(context.Background(), SetClock(clock)); err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	metrics := &FeedMetrics{}
+	ctx := WithFeedMetrics(context.Background(), metrics)
+	if err := c.Tail(ctx, "db", func(*driver.Change) error { return stop }); err != stop {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	<-clock.After(5 * time.Second)
+	if age := metrics.LastChangeAge(); age != 5*time.Second {
+		t.Errorf("Unexpected last change age: %s", age)
+	}
+}
@@ -0,0 +1,56 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+var tempViewNotImplemented = errors.Status(kivik.StatusNotImplemented, "kivik: _temp_view not supported by CouchDB 2.0.0 and later")
+
+// TempView queries a temporary view, defined by the JavaScript map function
+// and optional reduce function, without saving a design document. The index
+// is built from scratch for each query, so temporary views are suitable only
+// for ad-hoc exploration of small databases. opts are the usual view query
+// options. Temporary views were removed in CouchDB 2.0.
+func (d *db) TempView(ctx context.Context, mapFn, reduceFn string, opts map[string]interface{}) (driver.Rows, error) {
+	if d.client.Compat == CompatCouch20 {
+		return nil, tempViewNotImplemented
+	}
+	if mapFn == "" {
+		return nil, missingArg("map")
+	}
+	ctx, cancel, err := withTimeout(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateQueryOptions(opts); err != nil {
+		cancel()
+		return nil, err
+	}
+	options, err := optionsToParams(opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	view := map[string]string{
+		"language": "javascript",
+		"map":      mapFn,
+	}
+	if reduceFn != "" {
+		view["reduce"] = reduceFn
+	}
+	resp, err := d.Client.DoReq(ctx, kivik.MethodPost, d.path("_temp_view", options), &chttp.Options{Body: chttp.EncodeBody(view)})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err = chttp.ResponseError(resp); err != nil {
+		cancel()
+		return nil, err
+	}
+	return newRows(&cancelBody{ReadCloser: resp.Body, cancel: cancel}), nil
+}
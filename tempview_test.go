@@ -0,0 +1,102 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestTempView(t *testing.T) {
+	tests := []struct {
+		name     string
+		compat   CompatMode
+		mapFn    string
+		reduceFn string
+		opts     map[string]interface{}
+		body     string
+		query    string
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:   "2.x",
+			compat: CompatCouch20,
+			mapFn:  "function(doc){emit(doc._id)}",
+			status: kivik.StatusNotImplemented,
+			err:    "kivik: _temp_view not supported by CouchDB 2.0.0 and later",
+		},
+		{
+			name:   "no map",
+			compat: CompatCouch16,
+			status: kivik.StatusBadRequest,
+			err:    "kivik: map required",
+		},
+		{
+			name:     "map only",
+			compat:   CompatCouch16,
+			mapFn:    "function(doc){emit(doc._id)}",
+			opts:     map[string]interface{}{"limit": 2},
+			body:     `{"language":"javascript","map":"function(doc){emit(doc._id)}"}`,
+			query:    "limit=2",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "map and reduce",
+			mapFn:    "function(doc){emit(doc._id)}",
+			reduceFn: "_count",
+			body:     `{"language":"javascript","map":"function(doc){emit(doc._id)}","reduce":"_count"}`,
+			expected: []string{"a", "b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method != kivik.MethodPost || req.URL.Path != "/testdb/_temp_view" {
+					return nil, errors.New("unexpected request: " + req.Method + " " + req.URL.Path)
+				}
+				if req.URL.RawQuery != test.query {
+					t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+				}
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				if d := diff.JSON([]byte(test.body), body); d != nil {
+					t.Error(d)
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"total_rows":2,"offset":0,"rows":[{"id":"a","key":"a","value":null},{"id":"b","key":"b","value":null}]}`),
+				}, nil
+			})
+			db.client.Compat = test.compat
+			rows, err := db.TempView(context.Background(), test.mapFn, test.reduceFn, test.opts)
+			testy.StatusError(t, test.err, test.status, err)
+			defer rows.Close() // nolint: errcheck
+			var ids []string
+			for {
+				var row driver.Row
+				if err := rows.Next(&row); err != nil {
+					if err != io.EOF {
+						t.Fatal(err)
+					}
+					break
+				}
+				ids = append(ids, row.ID)
+			}
+			if d := diff.Interface(test.expected, ids); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
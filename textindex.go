@@ -0,0 +1,68 @@
+package couchdb
+
+import (
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// TextIndex defines a full-text index, as supported by Cloudant, and by
+// CouchDB with Clouseau. Passing one to CreateIndex, in place of the
+// definition of a JSON index, creates a text index, which Find uses for
+// queries with the $text operator, and for selectors which no JSON index can
+// serve.
+type TextIndex struct {
+	// Fields lists the fields to index. If empty, every field of every
+	// document is indexed.
+	Fields []TextField `json:"fields,omitempty"`
+	// DefaultAnalyzer is the analyzer used for the indexed fields. It
+	// defaults to "keyword".
+	DefaultAnalyzer string `json:"default_analyzer,omitempty"`
+	// DefaultField, if set, configures the default field, which is searched
+	// by the $text operator.
+	DefaultField *TextDefaultField `json:"default_field,omitempty"`
+	// IndexArrayLengths, if set to false, disables the indexing of the
+	// lengths of array fields, which is otherwise done to support $size.
+	IndexArrayLengths *bool `json:"index_array_lengths,omitempty"`
+	// PartialFilterSelector, if set, limits the index to the documents which
+	// match it.
+	PartialFilterSelector interface{} `json:"partial_filter_selector,omitempty"`
+}
+
+// TextField is a field indexed by a TextIndex.
+type TextField struct {
+	Name string `json:"name"`
+	// Type is one of "string", "number" or "boolean".
+	Type string `json:"type"`
+}
+
+// TextDefaultField configures the default field of a TextIndex.
+type TextDefaultField struct {
+	Enabled  bool   `json:"enabled"`
+	Analyzer string `json:"analyzer,omitempty"`
+}
+
+// textIndex returns the TextIndex index, if it is one.
+func textIndex(index interface{}) (*TextIndex, bool) {
+	switch t := index.(type) {
+	case TextIndex:
+		return &t, true
+	case *TextIndex:
+		return t, t != nil
+	}
+	return nil, false
+}
+
+// validate checks the types of the indexed fields.
+func (i *TextIndex) validate() error {
+	for _, field := range i.Fields {
+		if field.Name == "" {
+			return errors.Status(kivik.StatusBadRequest, "kivik: text index field name required")
+		}
+		switch field.Type {
+		case "string", "number", "boolean":
+		default:
+			return errors.Statusf(kivik.StatusBadRequest, "kivik: invalid type '%s' for text index field '%s'", field.Type, field.Name)
+		}
+	}
+	return nil
+}
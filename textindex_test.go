@@ -0,0 +1,133 @@
+package couchdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestCreateTextIndex(t *testing.T) {
+	noLengths := false
+	tests := []struct {
+		name     string
+		index    interface{}
+		expected string
+		status   int
+		err      string
+	}{
+		{
+			name: "text index",
+			index: &TextIndex{
+				Fields:            []TextField{{Name: "title", Type: "string"}, {Name: "year", Type: "number"}},
+				DefaultAnalyzer:   "english",
+				DefaultField:      &TextDefaultField{Enabled: true, Analyzer: "standard"},
+				IndexArrayLengths: &noLengths,
+			},
+			expected: `{"ddoc":"foo","name":"bar","type":"text","index":{
+				"fields":[{"name":"title","type":"string"},{"name":"year","type":"number"}],
+				"default_analyzer":"english",
+				"default_field":{"enabled":true,"analyzer":"standard"},
+				"index_array_lengths":false
+			}}`,
+		},
+		{
+			name:     "all fields",
+			index:    TextIndex{},
+			expected: `{"ddoc":"foo","name":"bar","type":"text","index":{}}`,
+		},
+		{
+			name:     "json index",
+			index:    map[string]interface{}{"fields": []string{"title"}},
+			expected: `{"ddoc":"foo","name":"bar","index":{"fields":["title"]}}`,
+		},
+		{
+			name:   "invalid field type",
+			index:  &TextIndex{Fields: []TextField{{Name: "title", Type: "text"}}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: invalid type 'text' for text index field 'title'",
+		},
+		{
+			name:   "missing field name",
+			index:  &TextIndex{Fields: []TextField{{Type: "string"}}},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: text index field name required",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body []byte
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				var err error
+				if body, err = ioutil.ReadAll(req.Body); err != nil {
+					return nil, err
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"result":"created"}`)}, nil
+			})
+			err := db.CreateIndex(context.Background(), "foo", "bar", test.index)
+			if test.expected != "" {
+				if d := diff.JSON([]byte(test.expected), body); d != nil {
+					t.Error(d)
+				}
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestDeleteTextIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		indexes  map[string]bool
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:     "json index",
+			indexes:  map[string]bool{"/testdb/_index/foo/json/bar": true},
+			expected: []string{"/testdb/_index/foo/json/bar"},
+		},
+		{
+			name:     "text index",
+			indexes:  map[string]bool{"/testdb/_index/foo/text/bar": true},
+			expected: []string{"/testdb/_index/foo/json/bar", "/testdb/_index/foo/text/bar"},
+		},
+		{
+			name:     "not found",
+			expected: []string{"/testdb/_index/foo/json/bar", "/testdb/_index/foo/text/bar"},
+			status:   kivik.StatusNotFound,
+			err:      "Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var paths []string
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				paths = append(paths, req.URL.Path)
+				if !test.indexes[req.URL.Path] {
+					return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+				}
+				return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"ok":true}`)}, nil
+			})
+			err := db.DeleteIndex(context.Background(), "foo", "bar")
+			if d := diff.Interface(test.expected, paths); d != nil {
+				t.Error(d)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestValidateTextSelector(t *testing.T) {
+	if err := ValidateSelector(`{"$text":"bond"}`); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	err := ValidateSelector(`{"$text":["bond"]}`)
+	testy.StatusError(t, "kivik: invalid argument for selector operator '$text'", kivik.StatusBadRequest, err)
+}
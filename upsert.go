@@ -0,0 +1,52 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// Upsert stores doc as docID, creating it or replacing the current revision,
+// whatever that is. Any _rev field in doc is ignored. If the document changes
+// between looking up its current rev and storing doc, the update is retried
+// once. The new rev is returned.
+func (d *db) Upsert(ctx context.Context, docID string, doc interface{}) (string, error) {
+	if err := validateDocID(docID); err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return "", errors.WrapStatus(kivik.StatusBadRequest, err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return "", errors.Status(kivik.StatusBadRequest, "kivik: document must be a JSON object")
+	}
+	var rev string
+	for attempt := 0; attempt < 2; attempt++ {
+		if rev, err = d.currentRev(ctx, docID); err != nil {
+			return "", err
+		}
+		delete(fields, "_rev")
+		if rev != "" {
+			fields["_rev"] = rev
+		}
+		rev, err = d.Put(ctx, docID, fields, nil)
+		if kivik.StatusCode(err) != kivik.StatusConflict {
+			break
+		}
+	}
+	return rev, err
+}
+
+// currentRev returns the current rev of docID, or an empty string if it does
+// not exist or is deleted.
+func (d *db) currentRev(ctx context.Context, docID string) (string, error) {
+	_, rev, err := d.GetMeta(ctx, docID, nil)
+	if kivik.StatusCode(err) == kivik.StatusNotFound {
+		return "", nil
+	}
+	return rev, err
+}
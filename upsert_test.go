@@ -0,0 +1,103 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      interface{}
+		revs     []string // Successive HEAD results; "" for not found
+		puts     int      // Number of PUTs which conflict
+		expected []string
+		status   int
+		err      string
+	}{
+		{
+			name:   "not an object",
+			doc:    []int{1},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: document must be a JSON object",
+		},
+		{
+			name:     "create",
+			doc:      map[string]string{"foo": "bar", "_rev": "9-stale"},
+			revs:     []string{""},
+			expected: []string{"HEAD", `PUT {"foo":"bar"}`},
+		},
+		{
+			name:     "replace",
+			doc:      map[string]string{"foo": "bar"},
+			revs:     []string{"1-xxx"},
+			expected: []string{"HEAD", `PUT {"_rev":"1-xxx","foo":"bar"}`},
+		},
+		{
+			name:     "race",
+			doc:      map[string]string{"foo": "bar"},
+			revs:     []string{"", "1-xxx"},
+			puts:     1,
+			expected: []string{"HEAD", `PUT {"foo":"bar"}`, "HEAD", `PUT {"_rev":"1-xxx","foo":"bar"}`},
+		},
+		{
+			name:     "repeated race",
+			doc:      map[string]string{"foo": "bar"},
+			revs:     []string{"1-xxx", "2-xxx"},
+			puts:     2,
+			expected: []string{"HEAD", `PUT {"_rev":"1-xxx","foo":"bar"}`, "HEAD", `PUT {"_rev":"2-xxx","foo":"bar"}`},
+			status:   kivik.StatusConflict,
+			err:      "Conflict",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+			revs, puts := test.revs, test.puts
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodHead {
+					requests = append(requests, req.Method)
+					rev := revs[0]
+					revs = revs[1:]
+					if rev == "" {
+						return &http.Response{StatusCode: kivik.StatusNotFound, Request: req, Body: Body("")}, nil
+					}
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Header:     http.Header{"ETag": {`"` + rev + `"`}},
+						Body:       Body(""),
+					}, nil
+				}
+				var doc map[string]interface{}
+				if err := json.NewDecoder(req.Body).Decode(&doc); err != nil {
+					return nil, err
+				}
+				body, _ := json.Marshal(doc)
+				requests = append(requests, req.Method+" "+string(body))
+				if puts > 0 {
+					puts--
+					return &http.Response{StatusCode: kivik.StatusConflict, Request: req, Body: Body("")}, nil
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusCreated,
+					Body:       Body(`{"ok":true,"id":"foo","rev":"5-new"}`),
+				}, nil
+			})
+			rev, err := db.Upsert(context.Background(), "foo", test.doc)
+			if d := diff.Interface(test.expected, requests); d != nil {
+				t.Error(d)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+			if rev != "5-new" {
+				t.Errorf("Unexpected rev: %s", rev)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package couchdb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// userPrefix is the prefix of the IDs of user documents.
+const userPrefix = "org.couchdb.user:"
+
+// User is a user of the server, stored in the _users database.
+type User struct {
+	Name string
+	// Password, if set, replaces the user's password. If it is not set when
+	// updating an existing user, the current password is kept.
+	Password string
+	Roles    []string
+	// Rev is the current revision of the user document, which is required
+	// to update an existing user.
+	Rev string
+}
+
+// userDoc is a user document in the _users database.
+type userDoc struct {
+	ID       string   `json:"_id"`
+	Rev      string   `json:"_rev,omitempty"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Roles    []string `json:"roles"`
+	Password string   `json:"password,omitempty"`
+
+	PasswordScheme string `json:"password_scheme,omitempty"`
+	Iterations     int    `json:"iterations,omitempty"`
+	Salt           string `json:"salt,omitempty"`
+	DerivedKey     string `json:"derived_key,omitempty"`
+	PasswordSHA    string `json:"password_sha,omitempty"`
+}
+
+// PutUser creates or updates user in the _users database, returning the new
+// revision of the user document. The password is sent to be hashed by the
+// server, unless the server is older than CouchDB 1.2, which stores passwords
+// as given, or the client has been configured with SetPasswordHashing. In
+// those cases, the password is hashed before it is sent: in the password_sha
+// scheme for servers older than CouchDB 1.3, and otherwise in the pbkdf2
+// scheme. Finding the version of the server takes an additional request.
+func (c *client) PutUser(ctx context.Context, user *User) (string, error) {
+	if user == nil || user.Name == "" {
+		return "", missingArg("name")
+	}
+	doc := &userDoc{
+		ID:    userPrefix + user.Name,
+		Rev:   user.Rev,
+		Name:  user.Name,
+		Type:  "user",
+		Roles: user.Roles,
+	}
+	if doc.Roles == nil {
+		doc.Roles = []string{}
+	}
+	d := &db{client: c, dbName: "_users"}
+	switch {
+	case user.Password != "":
+		if err := c.setPassword(ctx, doc, user.Password); err != nil {
+			return "", err
+		}
+	case user.Rev != "":
+		if err := d.keepPassword(ctx, doc); err != nil {
+			return "", err
+		}
+	}
+	return d.Put(ctx, doc.ID, doc, nil)
+}
+
+// setPassword sets the password of doc, hashed if required, as described for
+// PutUser.
+func (c *client) setPassword(ctx context.Context, doc *userDoc, password string) error {
+	version, err := c.Version(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case versionBefore(version.Version, 1, 2),
+		c.passwordIterations > 0 && versionBefore(version.Version, 1, 3):
+		return doc.hashPasswordSHA(password)
+	case c.passwordIterations > 0:
+		return doc.hashPassword(password, c.passwordIterations)
+	}
+	doc.Password = password
+	return nil
+}
+
+// keepPassword copies the password fields of revision doc.Rev of the user
+// document to doc, which would otherwise replace them.
+func (d *db) keepPassword(ctx context.Context, doc *userDoc) error {
+	current := &userDoc{}
+	path := d.path(chttp.EncodeDocID(doc.ID), url.Values{"rev": {doc.Rev}})
+	if _, err := d.Client.DoJSON(ctx, kivik.MethodGet, path, nil, current); err != nil {
+		return err
+	}
+	doc.PasswordScheme = current.PasswordScheme
+	doc.Iterations = current.Iterations
+	doc.Salt = current.Salt
+	doc.DerivedKey = current.DerivedKey
+	doc.PasswordSHA = current.PasswordSHA
+	return nil
+}
+
+// versionBefore returns true if version, as reported by the server, is older
+// than major.minor. Versions which can't be parsed are assumed to be newer.
+func versionBefore(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	vMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	vMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return vMajor < major || vMajor == major && vMinor < minor
+}
+
+// hashPassword sets the PBKDF2 fields of doc for password, as CouchDB 1.3 and
+// later would upon receiving it, with a random salt.
+func (doc *userDoc) hashPassword(password string, iterations int) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	doc.PasswordScheme = "pbkdf2"
+	doc.Iterations = iterations
+	doc.Salt = hex.EncodeToString(salt)
+	// CouchDB uses the hex-encoded salt as given, rather than decoding it.
+	doc.DerivedKey = hex.EncodeToString(pbkdf2([]byte(password), []byte(doc.Salt), iterations, sha1.Size, sha1.New))
+	return nil
+}
+
+// hashPasswordSHA sets the password_sha fields of doc for password, as CouchDB
+// 1.2 would upon receiving it, with a random salt. Older servers require them,
+// as they do not hash passwords themselves.
+func (doc *userDoc) hashPasswordSHA(password string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	doc.Salt = hex.EncodeToString(salt)
+	sum := sha1.Sum([]byte(password + doc.Salt))
+	doc.PasswordSHA = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// pbkdf2 derives a key of keyLen bytes from password and salt, as described
+// by RFC 2898.
+func pbkdf2(password, salt []byte, iterations, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	var key []byte
+	var counter [4]byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		_, _ = prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], block)
+		_, _ = prf.Write(counter[:])
+		u := prf.Sum(nil)
+		t := make([]byte, size)
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			_, _ = prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
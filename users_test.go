@@ -0,0 +1,172 @@
+package couchdb
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// Test vectors from RFC 6070
+	tests := []struct {
+		password, salt string
+		iterations     int
+		keyLen         int
+		expected       string
+	}{
+		{"password", "salt", 1, 20, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"password", "salt", 2, 20, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{"password", "salt", 4096, 20, "4b007901b765489abead49d926f721d065a429c1"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+	}
+	for _, test := range tests {
+		key := pbkdf2([]byte(test.password), []byte(test.salt), test.iterations, test.keyLen, sha1.New)
+		if result := hex.EncodeToString(key); result != test.expected {
+			t.Errorf("pbkdf2(%q, %q, %d) = %s, expected %s", test.password, test.salt, test.iterations, result, test.expected)
+		}
+	}
+}
+
+func TestPutUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       *User
+		iterations int
+		expected   string
+		status     int
+		err        string
+	}{
+		{
+			name:   "no name",
+			user:   &User{Password: "abc123"},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: name required",
+		},
+		{
+			name:     "server-side hashing",
+			user:     &User{Name: "bob", Password: "abc123", Roles: []string{"staff"}},
+			expected: `{"_id":"org.couchdb.user:bob","name":"bob","type":"user","roles":["staff"],"password":"abc123"}`,
+		},
+		{
+			name:     "new user without password",
+			user:     &User{Name: "bob"},
+			expected: `{"_id":"org.couchdb.user:bob","name":"bob","type":"user","roles":[]}`,
+		},
+		{
+			name:     "update without password",
+			user:     &User{Name: "bob", Rev: "1-xxx"},
+			expected: `{"_id":"org.couchdb.user:bob","_rev":"1-xxx","name":"bob","type":"user","roles":[],"password_scheme":"pbkdf2","iterations":10,"salt":"abc","derived_key":"def"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body []byte
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.Method == kivik.MethodGet && req.URL.Path == "/" {
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"couchdb":"Welcome","version":"2.3.1"}`)}, nil
+				}
+				if req.URL.Path != "/_users/org.couchdb.user:bob" {
+					t.Errorf("Unexpected path: %s", req.URL.Path)
+				}
+				if req.Method == kivik.MethodGet {
+					if rev := req.URL.Query().Get("rev"); rev != "1-xxx" {
+						t.Errorf("Unexpected rev: %s", rev)
+					}
+					return &http.Response{
+						StatusCode: kivik.StatusOK,
+						Body:       Body(`{"_id":"org.couchdb.user:bob","_rev":"1-xxx","name":"bob","type":"user","roles":["staff"],"password_scheme":"pbkdf2","iterations":10,"salt":"abc","derived_key":"def"}`),
+					}, nil
+				}
+				var err error
+				if body, err = ioutil.ReadAll(req.Body); err != nil {
+					return nil, err
+				}
+				return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{"ok":true,"id":"org.couchdb.user:bob","rev":"2-yyy"}`)}, nil
+			})
+			rev, err := c.PutUser(context.Background(), test.user)
+			testy.StatusError(t, test.err, test.status, err)
+			if rev != "2-yyy" {
+				t.Errorf("Unexpected rev: %s", rev)
+			}
+			if d := diff.JSON([]byte(test.expected), body); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestPutUserHashed(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    string
+		iterations int
+		scheme     string
+	}{
+		{
+			name:       "pbkdf2",
+			version:    "2.3.1",
+			iterations: 10,
+			scheme:     "pbkdf2",
+		},
+		{
+			name:       "pbkdf2 unsupported",
+			version:    "1.2.0",
+			iterations: 10,
+			scheme:     "password_sha",
+		},
+		{
+			name:    "no server-side hashing",
+			version: "1.1.1",
+			scheme:  "password_sha",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var doc userDoc
+			c := newCustomClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/" {
+					return &http.Response{StatusCode: kivik.StatusOK, Body: Body(`{"couchdb":"Welcome","version":"` + test.version + `"}`)}, nil
+				}
+				if err := json.NewDecoder(req.Body).Decode(&doc); err != nil {
+					return nil, err
+				}
+				return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{"ok":true,"id":"org.couchdb.user:bob","rev":"1-xxx"}`)}, nil
+			})
+			if err := c.Authenticate(context.Background(), SetPasswordHashing(test.iterations)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.PutUser(context.Background(), &User{Name: "bob", Password: "abc123"}); err != nil {
+				t.Fatal(err)
+			}
+			if doc.Password != "" {
+				t.Errorf("Plaintext password sent")
+			}
+			if len(doc.Salt) != 32 {
+				t.Errorf("Unexpected salt: %s", doc.Salt)
+			}
+			if test.scheme == "password_sha" {
+				sum := sha1.Sum([]byte("abc123" + doc.Salt))
+				if doc.PasswordScheme != "" || doc.DerivedKey != "" || doc.PasswordSHA != hex.EncodeToString(sum[:]) {
+					t.Errorf("Unexpected password_sha fields: %s, %s, %s", doc.PasswordScheme, doc.DerivedKey, doc.PasswordSHA)
+				}
+				return
+			}
+			if doc.PasswordScheme != "pbkdf2" || doc.Iterations != test.iterations || doc.PasswordSHA != "" {
+				t.Errorf("Unexpected hash parameters: %s, %d, %s", doc.PasswordScheme, doc.Iterations, doc.PasswordSHA)
+			}
+			expected := hex.EncodeToString(pbkdf2([]byte("abc123"), []byte(doc.Salt), test.iterations, sha1.Size, sha1.New))
+			if doc.DerivedKey != expected {
+				t.Errorf("Unexpected derived key: %s", doc.DerivedKey)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package couchdb
 
 import (
 	"encoding/json"
+	"os"
 
 	"github.com/go-kivik/kivik"
 	"github.com/go-kivik/kivik/errors"
@@ -25,3 +26,24 @@ func deJSONify(i interface{}) (interface{}, error) {
 	err := json.Unmarshal(data, &x)
 	return x, errors.WrapStatus(kivik.StatusBadRequest, err)
 }
+
+// writeFileAtomic replaces the file at path with data, via a temporary file,
+// so that a crash never leaves it partially written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
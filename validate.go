@@ -0,0 +1,70 @@
+package couchdb
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// validateDocID returns an error if docID is empty, or begins with an
+// underscore without being a design or local document ID.
+func validateDocID(docID string) error {
+	if docID == "" {
+		return missingArg("docID")
+	}
+	if docID[0] != '_' {
+		return nil
+	}
+	for _, prefix := range []string{"_design/", "_local/"} {
+		if strings.HasPrefix(docID, prefix) && len(docID) > len(prefix) {
+			return nil
+		}
+	}
+	return errors.Statusf(kivik.StatusBadRequest, "kivik: invalid document ID '%s': only design and local document IDs may begin with '_'", docID)
+}
+
+var revRE = regexp.MustCompile(`^[0-9]+-.+$`)
+
+// validateRev returns an error if rev is not of the form N-xxx.
+func validateRev(rev string) error {
+	if rev == "" {
+		return missingArg("rev")
+	}
+	if !revRE.MatchString(rev) {
+		return errors.Statusf(kivik.StatusBadRequest, "kivik: invalid rev '%s'", rev)
+	}
+	return nil
+}
+
+// validateQueryOptions rejects combinations of view query options which
+// CouchDB would refuse.
+func validateQueryOptions(opts map[string]interface{}) error {
+	if isFalse(opts["reduce"]) {
+		for _, key := range []string{"group", "group_level"} {
+			if _, ok := opts[key]; ok && !isFalse(opts[key]) {
+				return errors.Statusf(kivik.StatusBadRequest, "kivik: option '%s' requires reduce", key)
+			}
+		}
+	}
+	if _, ok := opts["keys"]; ok {
+		for _, key := range []string{"key", "startkey", "start_key", "endkey", "end_key"} {
+			if _, ok := opts[key]; ok {
+				return errors.Statusf(kivik.StatusBadRequest, "kivik: option 'keys' is incompatible with '%s'", key)
+			}
+		}
+	}
+	return nil
+}
+
+// isFalse returns true if v is false, or "false".
+func isFalse(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return !t
+	case string:
+		return t == "false"
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package couchdb
+
+import (
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestValidateDocID(t *testing.T) {
+	tests := []struct {
+		id     string
+		status int
+		err    string
+	}{
+		{id: "", status: kivik.StatusBadRequest, err: "kivik: docID required"},
+		{id: "foo"},
+		{id: "_design/foo"},
+		{id: "_local/foo"},
+		{id: "foo_bar"},
+		{id: "_foo", status: kivik.StatusBadRequest, err: "kivik: invalid document ID '_foo': only design and local document IDs may begin with '_'"},
+		{id: "_design/", status: kivik.StatusBadRequest, err: "kivik: invalid document ID '_design/': only design and local document IDs may begin with '_'"},
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			err := validateDocID(test.id)
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestValidateRev(t *testing.T) {
+	tests := []struct {
+		rev    string
+		status int
+		err    string
+	}{
+		{rev: "", status: kivik.StatusBadRequest, err: "kivik: rev required"},
+		{rev: "1-xxx"},
+		{rev: "12-4c6114c65e295552ab1019e2b046b10e"},
+		{rev: "xxx", status: kivik.StatusBadRequest, err: "kivik: invalid rev 'xxx'"},
+		{rev: "1-", status: kivik.StatusBadRequest, err: "kivik: invalid rev '1-'"},
+		{rev: "a-xxx", status: kivik.StatusBadRequest, err: "kivik: invalid rev 'a-xxx'"},
+	}
+	for _, test := range tests {
+		t.Run(test.rev, func(t *testing.T) {
+			err := validateRev(test.rev)
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
+
+func TestValidateQueryOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   map[string]interface{}
+		status int
+		err    string
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "group with reduce",
+			opts: map[string]interface{}{"group": true, "reduce": true},
+		},
+		{
+			name:   "group without reduce",
+			opts:   map[string]interface{}{"group": true, "reduce": false},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'group' requires reduce",
+		},
+		{
+			name:   "group_level without reduce",
+			opts:   map[string]interface{}{"group_level": 2, "reduce": "false"},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'group_level' requires reduce",
+		},
+		{
+			name: "group false without reduce",
+			opts: map[string]interface{}{"group": false, "reduce": false},
+		},
+		{
+			name:   "keys and key",
+			opts:   map[string]interface{}{"keys": []string{"a"}, "key": `"a"`},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'keys' is incompatible with 'key'",
+		},
+		{
+			name:   "keys and startkey",
+			opts:   map[string]interface{}{"keys": []string{"a"}, "startkey": `"a"`},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'keys' is incompatible with 'startkey'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateQueryOptions(test.opts)
+			testy.StatusError(t, test.err, test.status, err)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package couchdb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+)
+
+// Headers used by CouchDB's proxy authentication handler.
+const (
+	ProxyUserHeader  = "X-Auth-CouchDB-UserName"
+	ProxyRolesHeader = "X-Auth-CouchDB-Roles"
+	ProxyTokenHeader = "X-Auth-CouchDB-Token"
+)
+
+// ProxyAuthHeader returns the proxy authentication headers which identify a
+// request as coming from user, with roles. If secret is not empty, the token
+// derived from it is included, as required when the server's
+// couch_httpd_auth/proxy_use_secret option is set.
+func ProxyAuthHeader(secret, user string, roles []string) http.Header {
+	header := http.Header{}
+	header.Set(ProxyUserHeader, user)
+	header.Set(ProxyRolesHeader, strings.Join(roles, ","))
+	if secret != "" {
+		mac := hmac.New(sha1.New, []byte(secret))
+		_, _ = mac.Write([]byte(user))
+		header.Set(ProxyTokenHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	return header
+}
+
+// ValidationCase is a single write attempted by TestValidation.
+type ValidationCase struct {
+	// Name describes the case in the results.
+	Name string
+	// User and Roles are the user context in which the write is attempted.
+	User  string
+	Roles []string
+	// DocID and Doc are the document to write. Include _rev in Doc to test
+	// an update.
+	DocID string
+	Doc   interface{}
+}
+
+// ValidationResult reports the outcome of a ValidationCase.
+type ValidationResult struct {
+	Name    string
+	Allowed bool
+	// Status is the HTTP status of the write; 401 or 403 when rejected by
+	// validate_doc_update.
+	Status int
+	// Reason is the message returned with a rejection.
+	Reason string
+}
+
+// TestValidation attempts each of cases as a write to the database, in the
+// user context of the case, and reports which were accepted or rejected by
+// the database's validate_doc_update functions. The user context is
+// established with proxy authentication, so the server must have
+// {chttpd_auth, proxy_authentication_handler} among its authentication
+// handlers, ahead of any handler which the client's own credentials would
+// satisfy, and secret must match its couch_httpd_auth/secret, if
+// proxy_use_secret is enabled.
+//
+// Documents created by accepted writes are deleted again, with the client's
+// own credentials, so that the cases may be run repeatedly. Accepted updates
+// of existing documents are not undone, so TestValidation is best run against
+// a scratch database. An error is returned only for failures other than
+// rejections, such as network errors or conflicts.
+func (d *db) TestValidation(ctx context.Context, secret string, cases []ValidationCase) ([]ValidationResult, error) {
+	results := make([]ValidationResult, 0, len(cases))
+	for _, vc := range cases {
+		result, err := d.tryValidation(ctx, secret, vc)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+func (d *db) tryValidation(ctx context.Context, secret string, vc ValidationCase) (*ValidationResult, error) {
+	if err := validateDocID(vc.DocID); err != nil {
+		return nil, err
+	}
+	opts := &chttp.Options{
+		Body:   chttp.EncodeBody(vc.Doc),
+		Header: ProxyAuthHeader(secret, vc.User, vc.Roles),
+	}
+	var written struct {
+		Rev string `json:"rev"`
+	}
+	resp, err := d.Client.DoJSON(ctx, kivik.MethodPut, d.path(chttp.EncodeDocID(vc.DocID), nil), opts, &written)
+	result := &ValidationResult{Name: vc.Name}
+	switch status := kivik.StatusCode(err); status {
+	case kivik.StatusUnauthorized, kivik.StatusForbidden:
+		result.Status = status
+		if httpErr, ok := err.(*chttp.HTTPError); ok {
+			result.Reason = httpErr.Reason
+		}
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Allowed = true
+	result.Status = resp.StatusCode
+	if revGeneration(written.Rev) == 1 {
+		if _, err := d.Delete(ctx, vc.DocID, written.Rev, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
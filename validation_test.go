@@ -0,0 +1,102 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestProxyAuthHeader(t *testing.T) {
+	header := ProxyAuthHeader("secret", "bob", []string{"a", "b"})
+	expected := http.Header{}
+	expected.Set(ProxyUserHeader, "bob")
+	expected.Set(ProxyRolesHeader, "a,b")
+	// hex(HMAC-SHA1("secret", "bob"))
+	expected.Set(ProxyTokenHeader, "dcd244bed8f9dffffa806d4c9523d744d236df13")
+	if d := diff.Interface(expected, header); d != nil {
+		t.Error(d)
+	}
+	if token := ProxyAuthHeader("", "bob", nil).Get(ProxyTokenHeader); token != "" {
+		t.Errorf("Unexpected token without secret: %s", token)
+	}
+}
+
+func TestTestValidation(t *testing.T) {
+	var requests []string
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		user := req.Header.Get(ProxyUserHeader)
+		requests = append(requests, req.Method+" "+req.URL.Path+" "+user+" "+req.Header.Get(ProxyRolesHeader))
+		if req.Method == kivik.MethodDelete {
+			if rev := req.URL.Query().Get("rev"); rev != "1-new" {
+				return nil, errors.Errorf("Unexpected rev: %s", rev)
+			}
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Header:     http.Header{"ETag": {`"2-del"`}},
+				Body:       Body(`{"ok":true,"id":"new","rev":"2-del"}`),
+			}, nil
+		}
+		switch user {
+		case "admin":
+			if req.URL.Path == "/testdb/existing" {
+				return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{"ok":true,"id":"existing","rev":"2-upd"}`)}, nil
+			}
+			return &http.Response{StatusCode: kivik.StatusCreated, Body: Body(`{"ok":true,"id":"new","rev":"1-new"}`)}, nil
+		case "anon":
+			return &http.Response{
+				StatusCode:    kivik.StatusUnauthorized,
+				Request:       req,
+				Header:        http.Header{"Content-Type": {"application/json"}},
+				ContentLength: -1,
+				Body:          Body(`{"error":"unauthorized","reason":"log in first"}`),
+			}, nil
+		case "conflict":
+			return &http.Response{StatusCode: kivik.StatusConflict, Request: req, Body: Body("")}, nil
+		}
+		return &http.Response{
+			StatusCode:    kivik.StatusForbidden,
+			Request:       req,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			ContentLength: -1,
+			Body:          Body(`{"error":"forbidden","reason":"admins only"}`),
+		}, nil
+	})
+	cases := []ValidationCase{
+		{Name: "admin create", User: "admin", Roles: []string{"_admin"}, DocID: "new", Doc: map[string]string{}},
+		{Name: "admin update", User: "admin", Roles: []string{"_admin"}, DocID: "existing", Doc: map[string]string{"_rev": "1-old"}},
+		{Name: "user create", User: "bob", Roles: []string{"users"}, DocID: "new", Doc: map[string]string{}},
+		{Name: "anonymous", User: "anon", DocID: "new", Doc: map[string]string{}},
+	}
+	results, err := db.TestValidation(context.Background(), "", cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ValidationResult{
+		{Name: "admin create", Allowed: true, Status: kivik.StatusCreated},
+		{Name: "admin update", Allowed: true, Status: kivik.StatusCreated},
+		{Name: "user create", Status: kivik.StatusForbidden, Reason: "admins only"},
+		{Name: "anonymous", Status: kivik.StatusUnauthorized, Reason: "log in first"},
+	}
+	if d := diff.Interface(expected, results); d != nil {
+		t.Error(d)
+	}
+	expectedRequests := []string{
+		"PUT /testdb/new admin _admin",
+		"DELETE /testdb/new  ",
+		"PUT /testdb/existing admin _admin",
+		"PUT /testdb/new bob users",
+		"PUT /testdb/new anon ",
+	}
+	if d := diff.Interface(expectedRequests, requests); d != nil {
+		t.Error(d)
+	}
+
+	_, err = db.TestValidation(context.Background(), "", []ValidationCase{{User: "conflict", DocID: "new", Doc: map[string]string{}}})
+	testy.StatusError(t, "Conflict", kivik.StatusConflict, err)
+}
@@ -0,0 +1,211 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/tleyden/couchdb/chttp"
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+// timeoutPageSize is the number of rows requested per page after a query
+// times out. It is halved after each further timeout.
+var timeoutPageSize = 1000
+
+// timeoutError returns true if err is a server-side timeout of a view query.
+func timeoutError(err error) bool {
+	httpErr, ok := err.(*chttp.HTTPError)
+	if !ok || httpErr.Code != kivik.StatusInternalServerError {
+		return false
+	}
+	return httpErr.Name == "timeout" || httpErr.Name == "os_process_error"
+}
+
+// retryingQuery performs a query as described for OptionRetryTimeouts.
+func (d *db) retryingQuery(ctx context.Context, path string, opts map[string]interface{}) (driver.Rows, error) {
+	base := copyOptions(opts)
+	rows, timeoutErr := d.rowsQuery(ctx, path, opts)
+	if !timeoutError(timeoutErr) {
+		return rows, timeoutErr
+	}
+	if _, ok := base["keys"]; ok {
+		return nil, timeoutErr
+	}
+	remaining, err := queryLimit(base)
+	if err != nil {
+		return nil, err
+	}
+	// A page of no rows can't be any quicker than the query that timed out.
+	if remaining == 0 {
+		return nil, timeoutErr
+	}
+	delete(base, "limit")
+	p := &pagedRows{
+		ctx:       ctx,
+		d:         d,
+		path:      path,
+		opts:      base,
+		pageSize:  timeoutPageSize,
+		remaining: remaining,
+	}
+	if err = p.nextPage(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func copyOptions(opts map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(opts))
+	for k, v := range opts {
+		c[k] = v
+	}
+	return c
+}
+
+// pagedRows stitches together the pages fetched after a timeout.
+type pagedRows struct {
+	ctx  context.Context
+	d    *db
+	path string
+	// opts are the original query options, without limit.
+	opts     map[string]interface{}
+	pageSize int
+	// remaining is the number of rows still to be returned, or -1 for no
+	// limit.
+	remaining int
+
+	first   driver.Rows
+	current driver.Rows
+	// pageLimit is the limit of the current page, and pageRows the number
+	// of rows read from it.
+	pageLimit int
+	pageRows  int
+	// lastKey and lastID identify the last row received, from which the next
+	// page continues. lastRun is the number of consecutive rows received with
+	// that key and ID, as a view may emit the same key more than once for a
+	// document, and all of them must be skipped.
+	lastKey json.RawMessage
+	lastID  string
+	lastRun int
+	done    bool
+}
+
+var (
+	_ RowsMeta       = &pagedRows{}
+	_ AttachmentRows = &pagedRows{}
+)
+
+// nextPage fetches the next page, continuing from the last row received, and
+// halving the page size each time the request times out.
+func (p *pagedRows) nextPage() error {
+	for {
+		limit := p.pageSize
+		if p.remaining >= 0 && p.remaining < limit {
+			limit = p.remaining
+		}
+		opts := copyOptions(p.opts)
+		opts["limit"] = limit
+		if p.lastKey != nil {
+			for _, key := range []string{"start_key", "start_key_doc_id", "startkey_docid", "skip"} {
+				delete(opts, key)
+			}
+			opts["startkey"] = p.lastKey
+			if p.lastID != "" {
+				opts["startkey_docid"] = p.lastID
+			}
+			// Skip the rows already received with the last key and ID.
+			opts["skip"] = p.lastRun
+		}
+		rows, err := p.d.rowsQuery(p.ctx, p.path, opts)
+		if timeoutError(err) && p.pageSize > 1 {
+			p.pageSize /= 2
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if p.current != nil {
+			_ = p.current.Close()
+		}
+		if p.first == nil {
+			p.first = rows
+		}
+		p.current = rows
+		p.pageLimit = limit
+		p.pageRows = 0
+		return nil
+	}
+}
+
+func (p *pagedRows) Next(row *driver.Row) error {
+	for {
+		if p.done || p.remaining == 0 {
+			return io.EOF
+		}
+		err := p.current.Next(row)
+		if err == nil {
+			p.pageRows++
+			if p.remaining > 0 {
+				p.remaining--
+			}
+			if p.lastRun > 0 && row.ID == p.lastID && bytes.Equal(row.Key, p.lastKey) {
+				p.lastRun++
+				return nil
+			}
+			p.lastKey = append(p.lastKey[:0:0], row.Key...)
+			p.lastID = row.ID
+			p.lastRun = 1
+			return nil
+		}
+		if err != io.EOF {
+			return err
+		}
+		// A short page was the last one, and no more are needed once the
+		// limit is reached.
+		if p.pageRows < p.pageLimit || p.remaining == 0 {
+			p.done = true
+			return io.EOF
+		}
+		if err := p.nextPage(); err != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the current page; earlier pages are closed as they are
+// replaced.
+func (p *pagedRows) Close() error {
+	return p.current.Close()
+}
+
+func (p *pagedRows) UpdateSeq() string {
+	return p.first.UpdateSeq()
+}
+
+func (p *pagedRows) Offset() int64 {
+	return p.first.Offset()
+}
+
+func (p *pagedRows) TotalRows() int64 {
+	return p.first.TotalRows()
+}
+
+func (p *pagedRows) HasOffset() bool {
+	meta, ok := p.first.(RowsMeta)
+	return ok && meta.HasOffset()
+}
+
+func (p *pagedRows) HasTotalRows() bool {
+	meta, ok := p.first.(RowsMeta)
+	return ok && meta.HasTotalRows()
+}
+
+func (p *pagedRows) Attachments() []*AttachmentStub {
+	if atts, ok := p.current.(AttachmentRows); ok {
+		return atts.Attachments()
+	}
+	return nil
+}
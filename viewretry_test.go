@@ -0,0 +1,173 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestRetryTimeouts(t *testing.T) {
+	defer func(size int) { timeoutPageSize = size }(timeoutPageSize)
+	timeoutPageSize = 4
+	type viewRow struct{ id, key string }
+	keys := []string{"a", "b", "c", "d", "e"}
+	unique := make([]viewRow, len(keys))
+	for i, key := range keys {
+		unique[i] = viewRow{id: key, key: key}
+	}
+	timeout := func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode:    kivik.StatusInternalServerError,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			ContentLength: -1,
+			Request:       req,
+			Body:          Body(`{"error":"timeout","reason":"The request could not be processed in a reasonable amount of time."}`),
+		}
+	}
+	tests := []struct {
+		name     string
+		opts     map[string]interface{}
+		rows     []viewRow
+		expected []string
+		requests []string
+		status   int
+		err      string
+	}{
+		{
+			name:     "unlimited",
+			opts:     map[string]interface{}{OptionRetryTimeouts: true},
+			expected: keys,
+			requests: []string{
+				"",
+				"limit=4",
+				"limit=2",
+				`limit=2&skip=1&startkey="b"&startkey_docid=b`,
+				`limit=2&skip=1&startkey="d"&startkey_docid=d`,
+			},
+		},
+		{
+			name:     "limited",
+			opts:     map[string]interface{}{OptionRetryTimeouts: true, "limit": 3},
+			expected: []string{"a", "b", "c"},
+			requests: []string{
+				"limit=3",
+				"limit=3",
+				"limit=2",
+				`limit=1&skip=1&startkey="b"&startkey_docid=b`,
+			},
+		},
+		{
+			name:     "limit reached at end of page",
+			opts:     map[string]interface{}{OptionRetryTimeouts: true, "limit": 4},
+			expected: []string{"a", "b", "c", "d"},
+			requests: []string{
+				"limit=4",
+				"limit=4",
+				"limit=2",
+				`limit=2&skip=1&startkey="b"&startkey_docid=b`,
+			},
+		},
+		{
+			name:     "zero limit",
+			opts:     map[string]interface{}{OptionRetryTimeouts: true, "limit": 0},
+			requests: []string{"limit=0"},
+			status:   kivik.StatusInternalServerError,
+			err:      "Internal Server Error: The request could not be processed in a reasonable amount of time.",
+		},
+		{
+			name:     "key emitted repeatedly by one document",
+			opts:     map[string]interface{}{OptionRetryTimeouts: true},
+			rows:     []viewRow{{"a", "a"}, {"x", "b"}, {"x", "b"}, {"x", "b"}, {"c", "c"}},
+			expected: []string{"a", "x", "x", "x", "c"},
+			requests: []string{
+				"",
+				"limit=4",
+				"limit=2",
+				`limit=2&skip=1&startkey="b"&startkey_docid=x`,
+				`limit=2&skip=3&startkey="b"&startkey_docid=x`,
+			},
+		},
+		{
+			name:   "invalid option",
+			opts:   map[string]interface{}{OptionRetryTimeouts: "yes"},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: option 'retry_timeouts' must be bool, not string",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			view := test.rows
+			if view == nil {
+				view = unique
+			}
+			var requests []string
+			db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+				query, _ := url.QueryUnescape(req.URL.RawQuery)
+				requests = append(requests, query)
+				q := req.URL.Query()
+				limit, _ := strconv.Atoi(q.Get("limit"))
+				if limit == 0 || limit > 2 {
+					return timeout(req), nil
+				}
+				start := 0
+				if sk := q.Get("startkey"); sk != "" {
+					var key string
+					_ = json.Unmarshal([]byte(sk), &key)
+					docID := q.Get("startkey_docid")
+					for start < len(view) && (view[start].key < key || view[start].key == key && view[start].id < docID) {
+						start++
+					}
+				}
+				if skip, _ := strconv.Atoi(q.Get("skip")); skip > 0 {
+					start += skip
+				}
+				var rows []string
+				for i := start; i < len(view) && len(rows) < limit; i++ {
+					rows = append(rows, fmt.Sprintf(`{"id":"%s","key":"%s","value":null}`, view[i].id, view[i].key))
+				}
+				return &http.Response{
+					StatusCode: kivik.StatusOK,
+					Body:       Body(`{"total_rows":5,"offset":0,"rows":[` + strings.Join(rows, ",") + `]}`),
+				}, nil
+			})
+			rows, err := db.AllDocs(context.Background(), test.opts)
+			if d := diff.Interface(test.requests, requests); err != nil && d != nil {
+				t.Error(d)
+			}
+			testy.StatusError(t, test.err, test.status, err)
+			defer rows.Close() // nolint: errcheck
+			var result []string
+			for {
+				var row driver.Row
+				if err := rows.Next(&row); err != nil {
+					if err != io.EOF {
+						t.Fatal(err)
+					}
+					break
+				}
+				result = append(result, row.ID)
+			}
+			if d := diff.Interface(test.expected, result); d != nil {
+				t.Error(d)
+			}
+			if d := diff.Interface(test.requests, requests); d != nil {
+				t.Error(d)
+			}
+			if rows.TotalRows() != 5 {
+				t.Errorf("Unexpected total rows: %d", rows.TotalRows())
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// GroupRow is a single (key, reduced value) pair of a grouped view result.
+type GroupRow struct {
+	Key   json.RawMessage
+	Value interface{}
+}
+
+// GroupBy queries a reduce view with the requested group_level, optionally
+// limited to the key range [startKey, endKey], and returns the reduced rows.
+// Each value is decoded into a new value of the same type as proto, so
+// passing int64(0) results in int64 values, and passing a struct results in
+// struct values. A nil startKey or endKey leaves that end of the range open.
+func (d *db) GroupBy(ctx context.Context, ddoc, view string, groupLevel int, startKey, endKey, proto interface{}, options map[string]interface{}) ([]GroupRow, error) {
+	if proto == nil {
+		return nil, missingArg("proto")
+	}
+	opts, err := KeyRange{Start: startKey, End: endKey}.Options()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range options {
+		opts[k] = v
+	}
+	opts["reduce"] = true
+	opts["group_level"] = groupLevel
+	rows, err := d.Query(ctx, ddoc, view, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	valueType := reflect.TypeOf(proto)
+	var result []GroupRow
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				return result, nil
+			}
+			return nil, err
+		}
+		value := reflect.New(valueType)
+		if err := json.Unmarshal(row.Value, value.Interface()); err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		result = append(result, GroupRow{
+			Key:   row.Key,
+			Value: value.Elem().Interface(),
+		})
+	}
+}
+
+// ViewIterator iterates over view results, decoding the key, value, and
+// document of each row into caller-supplied destinations as it advances.
+type ViewIterator struct {
+	rows            driver.Rows
+	key, value, doc interface{}
+	id              string
+	err             error
+}
+
+// NewViewIterator returns a ViewIterator over rows, as returned by Query or
+// AllDocs. key, value, and doc must each be a non-nil pointer, or nil to skip
+// decoding that part of the row. Each destination is reset to its zero value
+// before every row is decoded into it.
+func NewViewIterator(rows driver.Rows, key, value, doc interface{}) (*ViewIterator, error) {
+	if rows == nil {
+		return nil, missingArg("rows")
+	}
+	for name, dest := range map[string]interface{}{"key": key, "value": value, "doc": doc} {
+		if dest == nil {
+			continue
+		}
+		if v := reflect.ValueOf(dest); v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil, errors.Statusf(kivik.StatusBadRequest, "kivik: %s destination must be a non-nil pointer, not %T", name, dest)
+		}
+	}
+	return &ViewIterator{
+		rows:  rows,
+		key:   key,
+		value: value,
+		doc:   doc,
+	}, nil
+}
+
+// Next advances to the next row, decoding it into the destinations. It
+// returns false when there are no more rows, or an error occurred, in which
+// case Err reports the error.
+func (i *ViewIterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+	var row driver.Row
+	if err := i.rows.Next(&row); err != nil {
+		if err != io.EOF {
+			i.err = err
+		}
+		return false
+	}
+	if row.Error != nil {
+		i.err = row.Error
+		return false
+	}
+	i.id = row.ID
+	for _, part := range []struct {
+		dest interface{}
+		data json.RawMessage
+	}{
+		{i.key, row.Key},
+		{i.value, row.Value},
+		{i.doc, row.Doc},
+	} {
+		if part.dest == nil {
+			continue
+		}
+		v := reflect.ValueOf(part.dest).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		if len(part.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(part.data, part.dest); err != nil {
+			i.err = errors.WrapStatus(kivik.StatusBadResponse, err)
+			return false
+		}
+	}
+	return true
+}
+
+// ID returns the document ID of the current row.
+func (i *ViewIterator) ID() string {
+	return i.id
+}
+
+// Err returns the error, if any, that stopped iteration.
+func (i *ViewIterator) Err() error {
+	return i.err
+}
+
+// Close closes the underlying rows.
+func (i *ViewIterator) Close() error {
+	return i.rows.Close()
+}
+
+// Each calls fn for every remaining row, after decoding the row into the
+// destinations, then closes the iterator. Iteration stops at the first
+// error returned by fn, which is returned.
+func (i *ViewIterator) Each(fn func(id string) error) error {
+	defer i.Close() // nolint: errcheck
+	for i.Next() {
+		if err := fn(i.id); err != nil {
+			return err
+		}
+	}
+	return i.err
+}
+
+// HighKey is the empty-object key sentinel, which collates after every other
+// JSON value. Use it as the last element of a composite endkey to match all
+// keys sharing a prefix:
+//
+//    startkey=["foo"]&endkey=["foo",{}]
+var HighKey = struct{}{}
+
+// HighStringSuffix is a high Unicode character which may be appended to a
+// string endkey to match all string keys with a given prefix.
+const HighStringSuffix = "\ufff0"
+
+// KeyRange describes a range of view keys.
+type KeyRange struct {
+	// Start and End are the first and last keys of the range. A nil value
+	// leaves that end of the range open.
+	Start, End interface{}
+	// StartDocID and EndDocID limit the range by document ID among rows with
+	// equal Start or End keys, for paging through duplicate keys. They are
+	// sent as plain strings, rather than JSON-encoded like keys.
+	StartDocID, EndDocID string
+	// ExcludeEnd sets inclusive_end=false, excluding the final key of the
+	// query from the results. This is End, or Start when Descending is set.
+	ExcludeEnd bool
+	// Descending reverses the order of results. Start and End are swapped in
+	// the generated query, so they always describe the range in ascending
+	// collation order.
+	Descending bool
+}
+
+// PrefixRange returns a KeyRange matching all composite (array) keys which
+// begin with the elements of prefix.
+func PrefixRange(prefix ...interface{}) KeyRange {
+	start := make([]interface{}, len(prefix))
+	copy(start, prefix)
+	end := make([]interface{}, len(prefix), len(prefix)+1)
+	copy(end, prefix)
+	return KeyRange{
+		Start: start,
+		End:   append(end, HighKey),
+	}
+}
+
+// StringPrefixRange returns a KeyRange matching all string keys which begin
+// with prefix.
+func StringPrefixRange(prefix string) KeyRange {
+	return KeyRange{
+		Start: prefix,
+		End:   prefix + HighStringSuffix,
+	}
+}
+
+// Options returns the query options describing the range, suitable to pass
+// to Query or AllDocs.
+func (r KeyRange) Options() (map[string]interface{}, error) {
+	start, end := r.Start, r.End
+	startDocID, endDocID := r.StartDocID, r.EndDocID
+	opts := map[string]interface{}{}
+	if r.Descending {
+		start, end = end, start
+		startDocID, endDocID = endDocID, startDocID
+		opts["descending"] = true
+	}
+	if startDocID != "" {
+		opts["startkey_docid"] = startDocID
+	}
+	if endDocID != "" {
+		opts["endkey_docid"] = endDocID
+	}
+	for key, value := range map[string]interface{}{"startkey": start, "endkey": end} {
+		if value == nil {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadRequest, err)
+		}
+		opts[key] = string(encoded)
+	}
+	if r.ExcludeEnd {
+		opts["inclusive_end"] = false
+	}
+	return opts, nil
+}
@@ -0,0 +1,276 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/errors"
+)
+
+func TestGroupBy(t *testing.T) {
+	type stats struct {
+		Sum   int64 `json:"sum"`
+		Count int64 `json:"count"`
+	}
+	tests := []struct {
+		name             string
+		db               *db
+		level            int
+		startKey, endKey interface{}
+		proto            interface{}
+		expected         []GroupRow
+		status           int
+		err              string
+	}{
+		{
+			name:   "no proto",
+			db:     &db{},
+			status: kivik.StatusBadRequest,
+			err:    "kivik: proto required",
+		},
+		{
+			name: "query params",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				q := req.URL.Query()
+				if q.Get("group_level") != "1" {
+					return nil, errors.Errorf("Unexpected group_level: %s", q.Get("group_level"))
+				}
+				if q.Get("reduce") != "true" {
+					return nil, errors.Errorf("Unexpected reduce: %s", q.Get("reduce"))
+				}
+				if q.Get("startkey") != `["a"]` {
+					return nil, errors.Errorf("Unexpected startkey: %s", q.Get("startkey"))
+				}
+				if _, ok := q["endkey"]; ok {
+					return nil, errors.New("Unexpected endkey")
+				}
+				return nil, errors.New("success")
+			}),
+			level:    1,
+			startKey: []string{"a"},
+			proto:    int64(0),
+			status:   kivik.StatusNetworkError,
+			err:      "success",
+		},
+		{
+			name: "int values",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"rows":[{"key":["a"],"value":3},{"key":["b"],"value":5}]}`),
+			}, nil),
+			level: 1,
+			proto: int64(0),
+			expected: []GroupRow{
+				{Key: json.RawMessage(`["a"]`), Value: int64(3)},
+				{Key: json.RawMessage(`["b"]`), Value: int64(5)},
+			},
+		},
+		{
+			name: "struct values",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"rows":[{"key":["a",1],"value":{"sum":10,"count":2}}]}`),
+			}, nil),
+			level: 2,
+			proto: stats{},
+			expected: []GroupRow{
+				{Key: json.RawMessage(`["a",1]`), Value: stats{Sum: 10, Count: 2}},
+			},
+		},
+		{
+			name: "wrong value type",
+			db: newTestDB(&http.Response{
+				StatusCode: kivik.StatusOK,
+				Body:       Body(`{"rows":[{"key":["a"],"value":"x"}]}`),
+			}, nil),
+			level:  1,
+			proto:  int64(0),
+			status: kivik.StatusBadResponse,
+			err:    "json: cannot unmarshal string into Go value of type int64",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.db.GroupBy(context.Background(), "ddoc", "view", test.level, test.startKey, test.endKey, test.proto, nil)
+			testy.StatusErrorRE(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, result); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestViewIterator(t *testing.T) {
+	t.Run("invalid destination", func(t *testing.T) {
+		var key string
+		_, err := NewViewIterator(newRows(Body("")), key, nil, nil)
+		testy.StatusError(t, "kivik: key destination must be a non-nil pointer, not string", kivik.StatusBadRequest, err)
+	})
+	t.Run("success", func(t *testing.T) {
+		type doc struct {
+			ID   string `json:"_id"`
+			Name string `json:"name"`
+		}
+		rows := newRows(Body(`{"total_rows":2,"offset":0,"rows":[
+{"id":"a","key":["x",1],"value":1,"doc":{"_id":"a","name":"Alice"}},
+{"id":"b","key":["y",2],"value":2,"doc":{"_id":"b"}}
+]}`))
+		var key []interface{}
+		var value int
+		var d doc
+		iter, err := NewViewIterator(rows, &key, &value, &d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		type result struct {
+			ID    string
+			Key   []interface{}
+			Value int
+			Doc   doc
+		}
+		var results []result
+		if err := iter.Each(func(id string) error {
+			results = append(results, result{ID: id, Key: key, Value: value, Doc: d})
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		expected := []result{
+			{ID: "a", Key: []interface{}{"x", float64(1)}, Value: 1, Doc: doc{ID: "a", Name: "Alice"}},
+			{ID: "b", Key: []interface{}{"y", float64(2)}, Value: 2, Doc: doc{ID: "b"}},
+		}
+		if d := diff.Interface(expected, results); d != nil {
+			t.Error(d)
+		}
+	})
+	t.Run("decode error", func(t *testing.T) {
+		rows := newRows(Body(`{"rows":[{"id":"a","key":"x","value":"one"}]}`))
+		var value int
+		iter, err := NewViewIterator(rows, nil, &value, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iter.Next() {
+			t.Fatal("Expected Next to fail")
+		}
+		testy.StatusError(t, "json: cannot unmarshal string into Go value of type int", kivik.StatusBadResponse, iter.Err())
+	})
+}
+
+func TestKeyRangeOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		kr       KeyRange
+		expected map[string]interface{}
+		status   int
+		err      string
+	}{
+		{
+			name:     "open",
+			expected: map[string]interface{}{},
+		},
+		{
+			name: "composite prefix",
+			kr:   PrefixRange("foo", 1),
+			expected: map[string]interface{}{
+				"startkey": `["foo",1]`,
+				"endkey":   `["foo",1,{}]`,
+			},
+		},
+		{
+			name: "string prefix",
+			kr:   StringPrefixRange("foo"),
+			expected: map[string]interface{}{
+				"startkey": `"foo"`,
+				"endkey":   `"foo` + HighStringSuffix + `"`,
+			},
+		},
+		{
+			name: "descending, exclusive",
+			kr: KeyRange{
+				Start:      "a",
+				End:        "z",
+				ExcludeEnd: true,
+				Descending: true,
+			},
+			expected: map[string]interface{}{
+				"startkey":      `"z"`,
+				"endkey":        `"a"`,
+				"descending":    true,
+				"inclusive_end": false,
+			},
+		},
+		{
+			name: "doc IDs",
+			kr: KeyRange{
+				Start:      "a",
+				StartDocID: "doc 1",
+				End:        "a",
+				EndDocID:   "doc 9",
+			},
+			expected: map[string]interface{}{
+				"startkey":       `"a"`,
+				"endkey":         `"a"`,
+				"startkey_docid": "doc 1",
+				"endkey_docid":   "doc 9",
+			},
+		},
+		{
+			name: "doc IDs, descending",
+			kr: KeyRange{
+				Start:      "a",
+				StartDocID: "doc 1",
+				End:        "b",
+				EndDocID:   "doc 9",
+				Descending: true,
+			},
+			expected: map[string]interface{}{
+				"startkey":       `"b"`,
+				"endkey":         `"a"`,
+				"startkey_docid": "doc 9",
+				"endkey_docid":   "doc 1",
+				"descending":     true,
+			},
+		},
+		{
+			name:   "unencodable key",
+			kr:     KeyRange{Start: make(chan int)},
+			status: kivik.StatusBadRequest,
+			err:    "json: unsupported type: chan int",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := test.kr.Options()
+			testy.StatusError(t, test.err, test.status, err)
+			if d := diff.Interface(test.expected, opts); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestQueryDocIDRange(t *testing.T) {
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		q := req.URL.Query()
+		if v := q.Get("startkey_docid"); v != "doc 1" {
+			return nil, errors.Errorf("Unexpected startkey_docid: %s", v)
+		}
+		if v := q.Get("startkey"); v != `"a"` {
+			return nil, errors.Errorf("Unexpected startkey: %s", v)
+		}
+		return nil, errors.New("success")
+	})
+	opts, err := KeyRange{Start: "a", StartDocID: "doc 1"}.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Query(context.Background(), "ddoc", "view", opts)
+	testy.ErrorRE(t, "success", err)
+}
@@ -0,0 +1,120 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+	"github.com/go-kivik/kivik/errors"
+)
+
+// ViewWarmup reports the result of warming the index of a single design
+// document with WarmViews.
+type ViewWarmup struct {
+	DDoc string
+	// View is the view which was queried to build the index.
+	View string
+	// Duration is how long the query took, which is how long the index took
+	// to bring up to date.
+	Duration time.Duration
+	// Err is the error, if any, returned by the query.
+	Err error
+}
+
+// WarmViews brings the view index of every design document in the database up
+// to date, so that subsequent queries are fast, for instance after deploying
+// new design documents. As the views of a design document share an index, only
+// the first view of each, by name, is queried, with limit=0 and update=true,
+// by up to parallelism queries at a time, which defaults to 4. Design
+// documents are reported in order of name.
+//
+// An error is returned only if the design documents could not be listed;
+// errors querying individual views are reported in their ViewWarmup.
+func (d *db) WarmViews(ctx context.Context, parallelism int) ([]ViewWarmup, error) {
+	if parallelism < 1 {
+		parallelism = 4
+	}
+	warmups, err := d.listViews(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clock := d.clock()
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range warmups {
+		wg.Add(1)
+		go func(w *ViewWarmup) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				w.Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			start := clock.Now()
+			w.Err = d.warmView(ctx, w.DDoc, w.View)
+			w.Duration = clock.Now().Sub(start)
+		}(&warmups[i])
+	}
+	wg.Wait()
+	return warmups, nil
+}
+
+// warmView queries a view without returning any rows.
+func (d *db) warmView(ctx context.Context, ddoc, view string) error {
+	rows, err := d.Query(ctx, ddoc, view, map[string]interface{}{
+		"limit":  0,
+		"update": true,
+	})
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// listViews returns a ViewWarmup for the first view of each design document
+// which has any, in the order of _all_docs.
+func (d *db) listViews(ctx context.Context) ([]ViewWarmup, error) {
+	rows, err := d.AllDocs(ctx, map[string]interface{}{
+		"startkey":     `"_design/"`,
+		"endkey":       `"_design0"`,
+		"include_docs": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	var warmups []ViewWarmup
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		var ddoc struct {
+			Views map[string]json.RawMessage `json:"views"`
+		}
+		if err := json.Unmarshal(row.Doc, &ddoc); err != nil {
+			return nil, errors.WrapStatus(kivik.StatusBadResponse, err)
+		}
+		if len(ddoc.Views) == 0 {
+			continue
+		}
+		views := make([]string, 0, len(ddoc.Views))
+		for view := range ddoc.Views {
+			views = append(views, view)
+		}
+		sort.Strings(views)
+		warmups = append(warmups, ViewWarmup{DDoc: strings.TrimPrefix(row.ID, "_design/"), View: views[0]})
+	}
+	return warmups, nil
+}
@@ -0,0 +1,75 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kivik/kivik"
+)
+
+func TestWarmViews(t *testing.T) {
+	var active, maxActive int32
+	release := make(chan struct{})
+	var once sync.Once
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/testdb/_all_docs" {
+			if q := req.URL.Query(); q.Get("startkey") != `"_design/"` || q.Get("include_docs") != "true" {
+				t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+			}
+			return &http.Response{
+				StatusCode: kivik.StatusOK,
+				Body: Body(`{"total_rows":3,"offset":0,"rows":[
+{"id":"_design/a","key":"_design/a","value":{"rev":"1-a"},"doc":{"_id":"_design/a","views":{"y":{"map":"function(doc){}"},"x":{"map":"function(doc){}"}}}},
+{"id":"_design/b","key":"_design/b","value":{"rev":"1-b"},"doc":{"_id":"_design/b","views":{"broken":{"map":"function(doc){"}}}},
+{"id":"_design/c","key":"_design/c","value":{"rev":"1-c"},"doc":{"_id":"_design/c","language":"javascript"}}
+]}`),
+			}, nil
+		}
+		if q := req.URL.Query(); q.Get("limit") != "0" || q.Get("update") != "true" {
+			t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+		}
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		if n == 2 {
+			once.Do(func() { close(release) })
+		}
+		<-release
+		if req.URL.Path == "/testdb/_design/b/_view/broken" {
+			return &http.Response{StatusCode: kivik.StatusInternalServerError, Request: req, Body: Body("")}, nil
+		}
+		return &http.Response{
+			StatusCode: kivik.StatusOK,
+			Body:       Body(`{"total_rows":10,"offset":0,"rows":[]}`),
+		}, nil
+	})
+	warmups, err := db.WarmViews(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, w := range warmups {
+		names = append(names, w.DDoc+"/"+w.View)
+		if w.View == "broken" {
+			if kivik.StatusCode(w.Err) != kivik.StatusInternalServerError {
+				t.Errorf("Unexpected error for %s/%s: %v", w.DDoc, w.View, w.Err)
+			}
+		} else if w.Err != nil {
+			t.Errorf("Unexpected error for %s/%s: %s", w.DDoc, w.View, w.Err)
+		}
+	}
+	if len(names) != 2 || names[0] != "a/x" || names[1] != "b/broken" {
+		t.Errorf("Unexpected views: %v", names)
+	}
+	if maxActive != 2 {
+		t.Errorf("Expected 2 concurrent queries, got %d", maxActive)
+	}
+}
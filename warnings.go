@@ -0,0 +1,98 @@
+package couchdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kivik/kivik"
+)
+
+// Warning describes the use of an option or endpoint which the server, as
+// detected by its compatibility mode, has deprecated. The request is made
+// regardless.
+type Warning struct {
+	// Option is the deprecated option, endpoint or setting.
+	Option string
+
+	// Replacement is what should be used instead, if anything.
+	Replacement string
+
+	// Message is a human-readable description of the warning.
+	Message string
+}
+
+func (w *Warning) String() string {
+	return w.Message
+}
+
+// WarningFunc is called with each Warning raised by a client.
+type WarningFunc func(*Warning)
+
+// warningAuth is an Authenticator which sets a client's WarningFunc.
+type warningAuth WarningFunc
+
+var _ Authenticator = warningAuth(nil)
+
+func (fn warningAuth) auth(_ context.Context, c *client) error {
+	c.warningFunc = WarningFunc(fn)
+	return nil
+}
+
+// SetWarningFunc returns an authenticator which causes fn to be called
+// whenever the client is used with an option deprecated by the server's
+// version, such as the stale view option, on CouchDB 2.x. Requests made with
+// DoRequest also raise warnings for the _missing_revs endpoint and the
+// delayed_commits setting, which the driver does not otherwise use. Passing
+// nil disables warnings.
+//
+// Example:
+//
+//     client.Authenticate(couchdb.SetWarningFunc(func(w *couchdb.Warning) {
+//         log.Printf("couchdb: %s", w)
+//     }))
+func SetWarningFunc(fn WarningFunc) Authenticator {
+	return warningAuth(fn)
+}
+
+// warn passes w to the client's WarningFunc, if any.
+func (c *client) warn(w *Warning) {
+	if c == nil || c.warningFunc == nil {
+		return
+	}
+	c.warningFunc(w)
+}
+
+// warnQueryOptions warns of deprecated view query options.
+func (c *client) warnQueryOptions(opts map[string]interface{}) {
+	if c == nil || c.Compat != CompatCouch20 {
+		return
+	}
+	if _, ok := opts["stale"]; ok {
+		c.warn(&Warning{
+			Option:      "stale",
+			Replacement: "stable, update",
+			Message:     "option 'stale' is deprecated; use 'stable' and 'update' instead",
+		})
+	}
+}
+
+// warnPath warns of requests to deprecated endpoints or settings.
+func (c *client) warnPath(method, path string) {
+	if c == nil || c.Compat != CompatCouch20 {
+		return
+	}
+	path = strings.SplitN(path, "?", 2)[0]
+	switch {
+	case strings.HasSuffix(path, "/_missing_revs"):
+		c.warn(&Warning{
+			Option:      "_missing_revs",
+			Replacement: "_revs_diff",
+			Message:     "endpoint '_missing_revs' is deprecated; use '_revs_diff' instead",
+		})
+	case method != kivik.MethodGet && strings.Contains(path, "/_config/couchdb/delayed_commits"):
+		c.warn(&Warning{
+			Option:  "delayed_commits",
+			Message: "setting 'couchdb/delayed_commits' is deprecated, and will be removed",
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+)
+
+func TestWarnings(t *testing.T) {
+	type tst struct {
+		compat   CompatMode
+		call     func(*client) error
+		expected []*Warning
+	}
+	tests := map[string]tst{
+		"stale on 2.x": {
+			compat: CompatCouch20,
+			call: func(c *client) error {
+				d := &db{client: c, dbName: "db"}
+				rows, err := d.Query(context.Background(), "ddoc", "view", map[string]interface{}{"stale": "ok"})
+				if err == nil {
+					_ = rows.Close()
+				}
+				return err
+			},
+			expected: []*Warning{{
+				Option:      "stale",
+				Replacement: "stable, update",
+				Message:     "option 'stale' is deprecated; use 'stable' and 'update' instead",
+			}},
+		},
+		"stale on 1.x": {
+			compat: CompatCouch16,
+			call: func(c *client) error {
+				d := &db{client: c, dbName: "db"}
+				rows, err := d.Query(context.Background(), "ddoc", "view", map[string]interface{}{"stale": "ok"})
+				if err == nil {
+					_ = rows.Close()
+				}
+				return err
+			},
+		},
+		"missing revs": {
+			compat: CompatCouch20,
+			call: func(c *client) error {
+				resp, err := c.DoRequest(context.Background(), "POST", "/db/_missing_revs", nil, map[string][]string{"foo": {"1-x"}})
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				return err
+			},
+			expected: []*Warning{{
+				Option:      "_missing_revs",
+				Replacement: "_revs_diff",
+				Message:     "endpoint '_missing_revs' is deprecated; use '_revs_diff' instead",
+			}},
+		},
+		"delayed commits": {
+			compat: CompatCouch20,
+			call: func(c *client) error {
+				resp, err := c.DoRequest(context.Background(), "PUT", "/_node/_local/_config/couchdb/delayed_commits", nil, `"true"`)
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				return err
+			},
+			expected: []*Warning{{
+				Option:  "delayed_commits",
+				Message: "setting 'couchdb/delayed_commits' is deprecated, and will be removed",
+			}},
+		},
+		"reading delayed commits": {
+			compat: CompatCouch20,
+			call: func(c *client) error {
+				resp, err := c.DoRequest(context.Background(), "GET", "/_node/_local/_config/couchdb/delayed_commits", nil, nil)
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				return err
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(&http.Response{StatusCode: 200, Body: Body(`{"rows":[]}`)}, nil)
+			c.Compat = test.compat
+			var warnings []*Warning
+			if err := SetWarningFunc(func(w *Warning) {
+				warnings = append(warnings, w)
+			}).auth(context.Background(), c); err != nil {
+				t.Fatal(err)
+			}
+			if err := test.call(c); err != nil {
+				t.Fatal(err)
+			}
+			if d := diff.Interface(test.expected, warnings); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
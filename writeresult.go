@@ -0,0 +1,21 @@
+package couchdb
+
+import "github.com/go-kivik/kivik"
+
+// WriteResult reports the durability of a write, when passed with
+// OptionWriteResult.
+type WriteResult struct {
+	// Accepted is true if the server responded 202 Accepted rather than 201
+	// Created: the write was stored, but by fewer copies than the write
+	// quorum, such as while nodes of a cluster are down, so it may be lost if
+	// those copies are. For BulkDocs, it is true if any batch was accepted.
+	Accepted bool
+}
+
+// record records the outcome of a write from the status code of its response.
+// wr may be nil.
+func (wr *WriteResult) record(status int) {
+	if wr != nil && status == kivik.StatusAccepted {
+		wr.Accepted = true
+	}
+}
@@ -0,0 +1,86 @@
+package couchdb
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik"
+	"github.com/go-kivik/kivik/driver"
+)
+
+func TestWriteResult(t *testing.T) {
+	writes := map[string]func(*db, map[string]interface{}) error{
+		"Put": func(d *db, opts map[string]interface{}) error {
+			_, err := d.Put(context.Background(), "foo", map[string]string{}, opts)
+			return err
+		},
+		"CreateDoc": func(d *db, opts map[string]interface{}) error {
+			_, _, err := d.CreateDoc(context.Background(), map[string]string{}, opts)
+			return err
+		},
+		"Delete": func(d *db, opts map[string]interface{}) error {
+			_, err := d.Delete(context.Background(), "foo", "1-xxx", opts)
+			return err
+		},
+		"Copy": func(d *db, opts map[string]interface{}) error {
+			_, err := d.Copy(context.Background(), "bar", "foo", opts)
+			return err
+		},
+		"BulkDocs": func(d *db, opts map[string]interface{}) error {
+			results, err := d.BulkDocs(context.Background(), []interface{}{map[string]string{}}, opts)
+			if err != nil {
+				return err
+			}
+			return results.Close()
+		},
+		"PutAttachment": func(d *db, opts map[string]interface{}) error {
+			att := &driver.Attachment{Filename: "foo.txt", ContentType: "text/plain", Content: Body("x")}
+			_, err := d.PutAttachment(context.Background(), "foo", "1-xxx", att, opts)
+			return err
+		},
+		"DeleteAttachment": func(d *db, opts map[string]interface{}) error {
+			_, err := d.DeleteAttachment(context.Background(), "foo", "1-xxx", "foo.txt", opts)
+			return err
+		},
+	}
+	for name, write := range writes {
+		for _, status := range []int{kivik.StatusCreated, kivik.StatusAccepted} {
+			t.Run(name+"/"+http.StatusText(status), func(t *testing.T) {
+				d := newCustomDB(func(req *http.Request) (*http.Response, error) {
+					if req.Body != nil {
+						_, _ = io.Copy(ioutil.Discard, req.Body)
+					}
+					if _, ok := req.URL.Query()[OptionWriteResult]; ok {
+						t.Errorf("%s sent as a query parameter", OptionWriteResult)
+					}
+					body := `{"ok":true,"id":"foo","rev":"2-xxx"}`
+					if strings.HasSuffix(req.URL.Path, "/_bulk_docs") {
+						body = "[" + body + "]"
+					}
+					return &http.Response{
+						StatusCode: status,
+						Header:     http.Header{"ETag": {`"2-xxx"`}},
+						Body:       Body(body),
+					}, nil
+				})
+				result := new(WriteResult)
+				if err := write(d, map[string]interface{}{OptionWriteResult: result}); err != nil {
+					t.Fatal(err)
+				}
+				if expected := status == kivik.StatusAccepted; result.Accepted != expected {
+					t.Errorf("Expected Accepted %t, got %t", expected, result.Accepted)
+				}
+			})
+		}
+	}
+	t.Run("invalid type", func(t *testing.T) {
+		err := writes["Put"](newTestDB(nil, nil), map[string]interface{}{OptionWriteResult: WriteResult{}})
+		testy.StatusError(t, "kivik: option 'write_result' must be *WriteResult, not couchdb.WriteResult", kivik.StatusBadRequest, err)
+	})
+}